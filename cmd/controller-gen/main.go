@@ -16,10 +16,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -29,9 +32,14 @@ import (
 	"sigs.k8s.io/controller-tools/pkg/genall"
 	"sigs.k8s.io/controller-tools/pkg/genall/help"
 	prettyhelp "sigs.k8s.io/controller-tools/pkg/genall/help/pretty"
+	"sigs.k8s.io/controller-tools/pkg/genall/help/reference"
+	"sigs.k8s.io/controller-tools/pkg/lsp"
 	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/model"
+	"sigs.k8s.io/controller-tools/pkg/plugin"
 	"sigs.k8s.io/controller-tools/pkg/rbac"
 	"sigs.k8s.io/controller-tools/pkg/schemapatcher"
+	"sigs.k8s.io/controller-tools/pkg/typescript"
 	"sigs.k8s.io/controller-tools/pkg/version"
 	"sigs.k8s.io/controller-tools/pkg/webhook"
 	"sigs.k8s.io/controller-tools/pkg/xrd"
@@ -52,9 +60,12 @@ var (
 		"crd":         crd.Generator{},
 		"xrd":         xrd.Generator{},
 		"rbac":        rbac.Generator{},
+		"rbacinfer":   rbac.InferGenerator{},
 		"object":      deepcopy.Generator{},
 		"webhook":     webhook.Generator{},
 		"schemapatch": schemapatcher.Generator{},
+		"typescript":  typescript.Generator{},
+		"model":       model.Generator{},
 	}
 
 	// allOutputRules defines the list of all known output rules, giving
@@ -63,10 +74,15 @@ var (
 	// - output:<generator>:<form> (per-generator output)
 	// - output:<form> (default output)
 	allOutputRules = map[string]genall.OutputRule{
-		"dir":       genall.OutputToDirectory(""),
-		"none":      genall.OutputToNothing,
-		"stdout":    genall.OutputToStdout,
-		"artifacts": genall.OutputArtifacts{},
+		"dir":        genall.OutputToDirectory(""),
+		"none":       genall.OutputToNothing,
+		"stdout":     genall.OutputToStdout,
+		"artifacts":  genall.OutputArtifacts{},
+		"verify":     genall.VerifyOutput{},
+		"bundle":     genall.OutputBundle{},
+		"archive":    genall.OutputArchive{},
+		"jsonstream": genall.OutputJSONStream{},
+		"helm":       genall.OutputHelmChart{},
 	}
 
 	// optionsRegistry contains all the marker definitions used to process command line options
@@ -87,16 +103,8 @@ func init() {
 		}
 
 		// make per-generation output rule markers
-		for ruleName, rule := range allOutputRules {
-			ruleMarker := markers.Must(markers.MakeDefinition(fmt.Sprintf("output:%s:%s", genName, ruleName), markers.DescribesPackage, rule))
-			if err := optionsRegistry.Register(ruleMarker); err != nil {
-				panic(err)
-			}
-			if helpGiver, hasHelp := rule.(genall.HasHelp); hasHelp {
-				if help := helpGiver.Help(); help != nil {
-					optionsRegistry.AddHelp(ruleMarker, help)
-				}
-			}
+		if err := registerOutputRules(genName); err != nil {
+			panic(err)
 		}
 	}
 
@@ -119,15 +127,91 @@ func init() {
 	}
 }
 
+// registerOutputRules registers the per-generator output rule markers for the
+// generator with the given name.
+func registerOutputRules(genName string) error {
+	for ruleName, rule := range allOutputRules {
+		ruleMarker, err := markers.MakeDefinition(fmt.Sprintf("output:%s:%s", genName, ruleName), markers.DescribesPackage, rule)
+		if err != nil {
+			return err
+		}
+		if err := optionsRegistry.Register(ruleMarker); err != nil {
+			return err
+		}
+		if helpGiver, hasHelp := rule.(genall.HasHelp); hasHelp {
+			if help := helpGiver.Help(); help != nil {
+				optionsRegistry.AddHelp(ruleMarker, help)
+			}
+		}
+	}
+	return nil
+}
+
+// registerPlugins finds the plugins named by any options that aren't
+// otherwise known, and registers them as generators.  Options that don't
+// name a plugin are left for option parsing to complain about.
+func registerPlugins(rawOpts []string) error {
+	for _, rawOpt := range rawOpts {
+		if !strings.HasPrefix(rawOpt, "+") {
+			rawOpt = "+" + rawOpt
+		}
+		if optionsRegistry.Lookup(rawOpt, markers.DescribesPackage) != nil {
+			continue
+		}
+
+		name := rawOpt[1:]
+		if end := strings.IndexAny(name, ":="); end >= 0 {
+			name = name[:end]
+		}
+		p, err := plugin.Find(name)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			continue
+		}
+		if err := plugin.Register(optionsRegistry, p); err != nil {
+			return err
+		}
+		if err := registerOutputRules(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // noUsageError suppresses usage printing when it occurs
 // (since cobra doesn't provide a good way to avoid printing
 // out usage in only certain situations).
 type noUsageError struct{ error }
 
+// errReported indicates that generators failed, and that their errors have
+// already been written out as a structured report.
+var errReported = errors.New("not all generators ran successfully")
+
+// optionsFromConfigFile reads options from the given config file, using the
+// given profile (if any).
+func optionsFromConfigFile(path, profile string) ([]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := genall.OptionsFromConfig(contents, profile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return opts, nil
+}
+
 func main() {
 	helpLevel := 0
 	whichLevel := 0
 	showVersion := false
+	configFile := ""
+	profile := ""
+	watch := false
+	diagnosticsFormat := ""
+	referenceFormat := ""
 
 	cmd := &cobra.Command{
 		Use:   "controller-gen",
@@ -143,12 +227,60 @@ func main() {
 	# Generate OpenAPI v3 schemas for API packages and merge them into existing CRD manifests
 	controller-gen schemapatch:manifests=./manifests output:dir=./manifests paths=./pkg/apis/... 
 
+	# Check that RBAC markers allow everything controllers do with their controller-runtime clients
+	controller-gen rbacinfer paths=./controllers/...
+
+	# Generate TypeScript type definitions for API kinds, one module per group-version
+	controller-gen typescript paths=./apis/... output:typescript:dir=./ui/src/apis
+
+	# Write the parsed API model (group-versions, kinds, fields and markers) as JSON, for custom tooling
+	controller-gen model paths=./apis/... output:model:stdout
+
 	# Run all the generators for a given project
 	controller-gen paths=./apis/...
 
+	# Skip generators whose inputs haven't changed since the last run
+	controller-gen crd object paths=./apis/... cache:dir=.cache/controller-gen
+
+	# Bundle all CRDs, RBAC and webhook configuration into a single multi-document YAML file
+	controller-gen crd rbac:roleName=<role name> webhook paths=./apis/... output:bundle:path=./dist/install.yaml
+
+	# Write CRDs, RBAC and webhook configuration into a Helm chart, templatizing names and namespaces
+	controller-gen crd rbac:roleName=manager-role webhook paths=./apis/... output:helm:dir=./charts/my-operator
+
+	# Check that checked-in CRDs and deepcopy code are up to date, without writing anything
+	controller-gen crd object paths=./apis/... output:crd:verify:dir=./config/crd/bases output:object:verify
+
+	# Remove CRD manifests left behind by kinds that have since been renamed or deleted
+	controller-gen crd paths=./apis/... output:crd:dir=./config/crd/bases prune
+
+	# Regenerate CRDs and deepcopy code whenever API types change, until interrupted
+	controller-gen crd object paths=./apis/... --watch
+
+	# Report misspelled or misplaced markers, like +kubebuilder:valdiation:Minimum=1
+	controller-gen crd object paths=./apis/... markers:check
+
+	# Report errors and warnings as SARIF, for annotating pull requests in CI
+	controller-gen crd paths=./apis/... --diagnostics-format=sarif 2> controller-gen.sarif
+
+	# Run the generators listed in a config file, using its "release" profile
+	controller-gen --config controller-gen.yaml --profile release
+
+	# Run the controller-gen-policy plugin from the PATH, alongside the CRD generator
+	controller-gen crd policy:bundle=default paths=./apis/... output:policy:dir=./config/policy
+
 	# Explain the markers for generating CRDs, and their arguments
 	controller-gen crd -ww
+
+	# Write a Markdown reference for the markers of every generator
+	controller-gen --markers-reference=markdown > markers.md
+
+	# Run a language server for markers, for editors to start and talk to over stdio
+	controller-gen lsp
 `,
+		// options are positional arguments, so anything that isn't a
+		// subcommand is fine
+		Args: cobra.ArbitraryArgs,
 		RunE: func(c *cobra.Command, rawOpts []string) error {
 			// print version if asked for it
 			if showVersion {
@@ -161,10 +293,28 @@ func main() {
 				return c.Usage()
 			}
 
+			// options from the config file come first, so that ones on the command line can add to them
+			if configFile != "" {
+				configOpts, err := optionsFromConfigFile(configFile, profile)
+				if err != nil {
+					return err
+				}
+				rawOpts = append(configOpts, rawOpts...)
+			} else if profile != "" {
+				return fmt.Errorf("--profile requires --config")
+			}
+
+			if err := registerPlugins(rawOpts); err != nil {
+				return err
+			}
+
 			// print the marker docs if we asked for them, then bail
 			if whichLevel > 0 {
 				return printMarkerDocs(c, rawOpts, whichLevel)
 			}
+			if referenceFormat != "" {
+				return printMarkerReference(c, rawOpts, referenceFormat)
+			}
 
 			// otherwise, set up the runtime for actually running the generators
 			rt, err := genall.FromOptions(optionsRegistry, rawOpts)
@@ -174,24 +324,67 @@ func main() {
 			if len(rt.Generators) == 0 {
 				return fmt.Errorf("no generators specified")
 			}
+			rt.DiagnosticsFormat, err = genall.ParseDiagnosticsFormat(diagnosticsFormat)
+			if err != nil {
+				return err
+			}
+
+			if watch && rt.DiagnosticsFormat != genall.DiagnosticsText {
+				// each run writes a whole report, so the output of
+				// several runs couldn't be parsed
+				return fmt.Errorf("--watch can only be used with --diagnostics-format=text")
+			}
+			if watch {
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer stop()
+				return rt.Watch(ctx)
+			}
 
 			if hadErrs := rt.Run(); hadErrs {
+				if rt.DiagnosticsFormat != genall.DiagnosticsText {
+					return errReported
+				}
 				// don't obscure the actual error with a bunch of usage
 				return noUsageError{fmt.Errorf("not all generators ran successfully")}
 			}
 			return nil
 		},
-		SilenceUsage: true, // silence the usage, then print it out ourselves if it wasn't suppressed
+		SilenceUsage:  true, // silence the usage, then print it out ourselves if it wasn't suppressed
+		SilenceErrors: true, // likewise for errors, which shouldn't be printed after a report
 	}
 	cmd.Flags().CountVarP(&whichLevel, "which-markers", "w", "print out all markers available with the requested generators\n(up to -www for the most detailed output, or -wwww for json output)")
 	cmd.Flags().CountVarP(&helpLevel, "detailed-help", "h", "print out more detailed help\n(up to -hhh for the most detailed output, or -hhhh for json output)")
 	cmd.Flags().BoolVar(&showVersion, "version", false, "show version")
+	cmd.Flags().StringVar(&configFile, "config", "", "read generators, output rules, paths and other options from the given YAML file")
+	cmd.Flags().StringVar(&profile, "profile", "", "use the given profile from the config file")
+	cmd.Flags().BoolVar(&watch, "watch", false, "keep running, regenerating whenever Go files in the root packages change")
+	cmd.Flags().StringVar(&diagnosticsFormat, "diagnostics-format", "text", "report errors and warnings as text, or as a json or sarif report on stderr")
+	cmd.Flags().StringVar(&referenceFormat, "markers-reference", "", "print a reference for the markers of the requested generators (or of all generators, if none are requested)\nas markdown or html")
 	cmd.Flags().Bool("help", false, "print out usage and a summary of options")
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.AddCommand(&cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server for markers over stdin and stdout.",
+		Long: `Run a language server for markers over stdin and stdout.
+
+The server speaks the Language Server Protocol, providing completion, hover
+documentation, diagnostics and go-to-definition for the markers of every
+generator in Go files, so that it can be used from any editor with LSP support.`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			server := &lsp.Server{Registry: genall.AllGeneratorMarkers(optionsRegistry)}
+			return server.Serve(os.Stdin, os.Stdout)
+		},
+	})
 	oldUsage := cmd.UsageFunc()
 	cmd.SetUsageFunc(func(c *cobra.Command) error {
 		if err := oldUsage(c); err != nil {
 			return err
 		}
+		if c != cmd {
+			// subcommands don't take options
+			return nil
+		}
 		if helpLevel == 0 {
 			helpLevel = summaryHelp
 		}
@@ -200,6 +393,12 @@ func main() {
 	})
 
 	if err := cmd.Execute(); err != nil {
+		if errors.Is(err, errReported) {
+			// the report's already been written, and anything else we
+			// print would stop it from being parsed
+			os.Exit(1)
+		}
+		fmt.Fprintln(cmd.ErrOrStderr(), "Error:", err)
 		if _, noUsage := err.(noUsageError); !noUsage {
 			// print the usage unless we suppressed it
 			if err := cmd.Usage(); err != nil {
@@ -224,6 +423,30 @@ func printMarkerDocs(c *cobra.Command, rawOptions []string, whichLevel int) erro
 	return helpForLevels(c.OutOrStdout(), c.OutOrStderr(), whichLevel, reg, help.SortByCategory)
 }
 
+// printMarkerReference prints a complete reference for the markers of the
+// generators specified in the rawOptions (or all of them, if none are), in
+// the given format.
+func printMarkerReference(c *cobra.Command, rawOptions []string, format string) error {
+	reg, err := genall.RegistryFromOptions(optionsRegistry, rawOptions)
+	if err != nil {
+		return err
+	}
+	if len(reg.AllDefinitions()) == 0 {
+		reg = genall.AllGeneratorMarkers(optionsRegistry)
+	}
+
+	title := fmt.Sprintf("controller-gen %s marker reference", version.Version())
+	categories := help.ByCategory(reg, help.SortByCategory)
+	switch format {
+	case "markdown":
+		return reference.WriteMarkdown(c.OutOrStdout(), title, categories)
+	case "html":
+		return reference.WriteHTML(c.OutOrStdout(), title, categories)
+	default:
+		return fmt.Errorf("unknown marker reference format %q (must be markdown or html)", format)
+	}
+}
+
 func helpForLevels(mainOut io.Writer, errOut io.Writer, whichLevel int, reg *markers.Registry, sorter help.SortGroup) error {
 	helpInfo := help.ByCategory(reg, sorter)
 	switch whichLevel {
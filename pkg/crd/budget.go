@@ -0,0 +1,363 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// SizeStats describes the size of a generated CustomResourceDefinition, and
+// any descriptions that were removed to fit it into a size budget.
+type SizeStats struct {
+	// Group is the API group of the defined resource.
+	Group string `json:"group"`
+	// Kind is the kind of the defined resource.
+	Kind string `json:"kind"`
+	// OriginalSize is the size of the CRD before fitting it into the budget, in bytes.
+	OriginalSize int `json:"originalSize"`
+	// Size is the final size of the CRD, in bytes.
+	Size int `json:"size"`
+	// Trimmed lists the subtrees of the schema whose descriptions were removed.
+	Trimmed []TrimmedSubtree `json:"trimmed,omitempty"`
+}
+
+// TrimmedSubtree is a part of a CRD schema whose descriptions were removed.
+type TrimmedSubtree struct {
+	// Version is the version of the resource whose schema was trimmed.
+	Version string `json:"version"`
+	// Path is the path to the subtree in the schema, like `.spec.template`.
+	Path string `json:"path"`
+	// Type is the Go type that the subtree was generated from.
+	Type string `json:"type"`
+	// Saved is the (approximate) number of bytes saved.
+	Saved int `json:"saved"`
+}
+
+// CRDSize returns the size of the given CRD, as serialized to JSON (which is
+// how it's stored in the last-applied-configuration annotation by kubectl).
+func CRDSize(crd *apiext.CustomResourceDefinition) (int, error) {
+	raw, err := json.Marshal(crd)
+	if err != nil {
+		return 0, err
+	}
+	return len(raw), nil
+}
+
+// typedSubtree is a part of a version schema that was generated from a named type.
+type typedSubtree struct {
+	version  string
+	path     []string
+	typ      TypeIdent
+	upstream bool
+	// descBytes is the size of the descriptions in the subtree, excluding its root.
+	descBytes int
+}
+
+// pathString formats the subtree's path like a JSONPath.
+func (s typedSubtree) pathString() string {
+	var out strings.Builder
+	for _, segment := range s.path {
+		switch segment {
+		case itemsSegment:
+			out.WriteString("[*]")
+		case additionalPropertiesSegment:
+			out.WriteString(".*")
+		default:
+			out.WriteString("." + segment)
+		}
+	}
+	return out.String()
+}
+
+const (
+	// itemsSegment is the path segment for array items.
+	itemsSegment = "[*]"
+	// additionalPropertiesSegment is the path segment for map values.
+	additionalPropertiesSegment = "*"
+)
+
+// FitCRDToBudget removes descriptions from the schemata of the given CRD, one
+// subtree at a time, until it's at most budget bytes in size (see CRDSize).
+//
+// Subtrees generated from types outside of the given root packages (like
+// embedded PodTemplateSpecs) go first, then those from types in the roots,
+// deepest first, and then largest first.  The root of each subtree keeps its
+// own description, since that's the field that refers to the type.
+func (p *Parser) FitCRDToBudget(crd *apiext.CustomResourceDefinition, roots []*loader.Package, budget int) (SizeStats, error) {
+	return fitCRDToBudget(crd, budget, func() []typedSubtree {
+		return p.budgetSubtrees(crd, roots)
+	})
+}
+
+// FitCRDToBudget is Parser.FitCRDToBudget, but only locks the parser while
+// finding the subtrees to trim, rather than while trimming them.  The CRD
+// must not be one of the parser's own.
+func (s *SharedParser) FitCRDToBudget(crd *apiext.CustomResourceDefinition, roots []*loader.Package, budget int) (SizeStats, error) {
+	return fitCRDToBudget(crd, budget, func() []typedSubtree {
+		s.Lock()
+		defer s.Unlock()
+		return s.Parser.budgetSubtrees(crd, roots)
+	})
+}
+
+// fitCRDToBudget implements FitCRDToBudget, finding the subtrees that can be
+// trimmed with the given function (only if the CRD is over budget).
+func fitCRDToBudget(crd *apiext.CustomResourceDefinition, budget int, subtreesFor func() []typedSubtree) (SizeStats, error) {
+	stats := SizeStats{
+		Group: crd.Spec.Group,
+		Kind:  crd.Spec.Names.Kind,
+	}
+	size, err := CRDSize(crd)
+	if err != nil {
+		return stats, err
+	}
+	stats.OriginalSize = size
+	stats.Size = size
+	if size <= budget {
+		return stats, nil
+	}
+
+	subtrees := subtreesFor()
+	sort.SliceStable(subtrees, func(i, j int) bool {
+		a, b := subtrees[i], subtrees[j]
+		if a.upstream != b.upstream {
+			return a.upstream
+		}
+		if len(a.path) != len(b.path) {
+			return len(a.path) > len(b.path)
+		}
+		if a.descBytes != b.descBytes {
+			return a.descBytes > b.descBytes
+		}
+		if a.pathString() != b.pathString() {
+			return a.pathString() < b.pathString()
+		}
+		return a.version < b.version
+	})
+
+	for _, subtree := range subtrees {
+		if size <= budget {
+			break
+		}
+		var saved int
+		for i, ver := range crd.Spec.Versions {
+			if ver.Name != subtree.version {
+				continue
+			}
+			editSubtree(crd.Spec.Versions[i].Schema.OpenAPIV3Schema, subtree.path, func(sub *apiext.JSONSchemaProps) {
+				saved = descriptionBytes(sub) - rootDescriptionBytes(sub)
+				rootDesc := sub.Description
+				TruncateDescription(sub, 0)
+				sub.Description = rootDesc
+			})
+		}
+		if saved == 0 {
+			// already trimmed as part of a deeper subtree
+			continue
+		}
+		stats.Trimmed = append(stats.Trimmed, TrimmedSubtree{
+			Version: subtree.version,
+			Path:    subtree.pathString(),
+			Type:    loader.NonVendorPath(subtree.typ.Package.PkgPath) + "." + subtree.typ.Name,
+			Saved:   saved,
+		})
+
+		size -= saved
+		if size <= budget {
+			// double-check our estimate
+			if size, err = CRDSize(crd); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	if stats.Size, err = CRDSize(crd); err != nil {
+		return stats, err
+	}
+	if stats.Size > budget {
+		return stats, fmt.Errorf("CRD for %s.%s is %d bytes even after removing descriptions, which is over the budget of %d bytes", stats.Kind, stats.Group, stats.Size, budget)
+	}
+	return stats, nil
+}
+
+// budgetSubtrees finds the subtrees of the given CRD's schemata that come
+// from named types, and how many bytes of descriptions each has.
+func (p *Parser) budgetSubtrees(crd *apiext.CustomResourceDefinition, roots []*loader.Package) []typedSubtree {
+	rootSet := make(map[*loader.Package]struct{}, len(roots))
+	for _, root := range roots {
+		rootSet[root] = struct{}{}
+	}
+
+	var subtrees []typedSubtree
+	for i, ver := range crd.Spec.Versions {
+		if ver.Schema == nil || ver.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		typ, found := p.kindForVersion(schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}, ver.Name)
+		if !found {
+			continue
+		}
+		for _, subtree := range p.typedSubtrees(ver.Name, typ) {
+			_, isRoot := rootSet[subtree.typ.Package]
+			subtree.upstream = !isRoot
+			editSubtree(crd.Spec.Versions[i].Schema.OpenAPIV3Schema, subtree.path, func(sub *apiext.JSONSchemaProps) {
+				subtree.descBytes = descriptionBytes(sub) - rootDescriptionBytes(sub)
+			})
+			if subtree.descBytes > 0 {
+				subtrees = append(subtrees, subtree)
+			}
+		}
+	}
+	return subtrees
+}
+
+// kindForVersion finds the type for the given kind in the given version.
+func (p *Parser) kindForVersion(groupKind schema.GroupKind, version string) (TypeIdent, bool) {
+	for pkg, gv := range p.GroupVersions {
+		if gv.Group != groupKind.Group || gv.Version != version {
+			continue
+		}
+		typ := TypeIdent{Package: pkg, Name: groupKind.Kind}
+		if _, known := p.Types[typ]; known {
+			return typ, true
+		}
+	}
+	return TypeIdent{}, false
+}
+
+// typedSubtrees finds all the subtrees of the (flattened) schema for the given
+// type that come from other named types, by following the references in the
+// unflattened schemata.
+func (p *Parser) typedSubtrees(version string, typ TypeIdent) []typedSubtree {
+	var res []typedSubtree
+	seenPaths := make(map[string]struct{})
+	// inProgress guards against recursive types
+	inProgress := map[TypeIdent]struct{}{typ: {}}
+
+	var walk func(props *apiext.JSONSchemaProps, pkg *loader.Package, path []string)
+	walk = func(props *apiext.JSONSchemaProps, pkg *loader.Package, path []string) {
+		if props.Ref != nil {
+			ident, err := identFromRef(*props.Ref, pkg)
+			if err != nil {
+				return
+			}
+			if _, recursive := inProgress[ident]; recursive {
+				return
+			}
+			p.NeedSchemaFor(ident)
+			refSchema, known := p.Schemata[ident]
+			if !known {
+				return
+			}
+
+			subtree := typedSubtree{version: version, path: append([]string(nil), path...), typ: ident}
+			if _, seen := seenPaths[subtree.pathString()]; !seen && len(path) > 0 {
+				seenPaths[subtree.pathString()] = struct{}{}
+				res = append(res, subtree)
+			}
+
+			inProgress[ident] = struct{}{}
+			walk(&refSchema, ident.Package, path)
+			delete(inProgress, ident)
+			return
+		}
+
+		for name, prop := range props.Properties {
+			prop := prop
+			walk(&prop, pkg, append(path[:len(path):len(path)], name))
+		}
+		for i := range props.AllOf {
+			walk(&props.AllOf[i], pkg, path)
+		}
+		if props.Items != nil && props.Items.Schema != nil {
+			walk(props.Items.Schema, pkg, append(path[:len(path):len(path)], itemsSegment))
+		}
+		if props.AdditionalProperties != nil && props.AdditionalProperties.Schema != nil {
+			walk(props.AdditionalProperties.Schema, pkg, append(path[:len(path):len(path)], additionalPropertiesSegment))
+		}
+	}
+
+	p.NeedSchemaFor(typ)
+	rootSchema := p.Schemata[typ]
+	walk(&rootSchema, typ.Package, nil)
+	return res
+}
+
+// editSubtree calls the given function with the part of the given schema at
+// the given path (if it exists), saving any modifications.
+func editSubtree(props *apiext.JSONSchemaProps, path []string, edit func(*apiext.JSONSchemaProps)) {
+	if len(path) == 0 {
+		edit(props)
+		return
+	}
+	switch path[0] {
+	case itemsSegment:
+		if props.Items != nil && props.Items.Schema != nil {
+			editSubtree(props.Items.Schema, path[1:], edit)
+		}
+	case additionalPropertiesSegment:
+		if props.AdditionalProperties != nil && props.AdditionalProperties.Schema != nil {
+			editSubtree(props.AdditionalProperties.Schema, path[1:], edit)
+		}
+	default:
+		prop, exists := props.Properties[path[0]]
+		if !exists {
+			return
+		}
+		editSubtree(&prop, path[1:], edit)
+		props.Properties[path[0]] = prop
+	}
+}
+
+// descriptionBytes returns the number of bytes that the descriptions in the
+// given schema take up when serialized.
+func descriptionBytes(props *apiext.JSONSchemaProps) int {
+	total := 0
+	EditSchema(props, descBytesVisitor{total: &total})
+	return total
+}
+
+// rootDescriptionBytes returns the number of bytes that the description of
+// just the root of the given schema takes up when serialized.
+func rootDescriptionBytes(props *apiext.JSONSchemaProps) int {
+	if props.Description == "" {
+		return 0
+	}
+	raw, _ := json.Marshal(props.Description)
+	// `"description":<raw>,`
+	return len(`"description":,`) + len(raw)
+}
+
+// descBytesVisitor totals up the serialized size of descriptions.
+type descBytesVisitor struct {
+	total *int
+}
+
+func (v descBytesVisitor) Visit(props *apiext.JSONSchemaProps) SchemaVisitor {
+	if props != nil {
+		*v.total += rootDescriptionBytes(props)
+	}
+	return v
+}
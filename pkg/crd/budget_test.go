@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func Test_Budget_Subtrees(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	props := &apiext.JSONSchemaProps{
+		Description: "root",
+		Properties: map[string]apiext.JSONSchemaProps{
+			"containers": {
+				Description: "containers",
+				Items: &apiext.JSONSchemaPropsOrArray{Schema: &apiext.JSONSchemaProps{
+					Description: "container",
+					AdditionalProperties: &apiext.JSONSchemaPropsOrBool{Schema: &apiext.JSONSchemaProps{
+						Description: "value",
+					}},
+				}},
+			},
+		},
+	}
+	subtree := typedSubtree{path: []string{"containers", itemsSegment}}
+	g.Expect(subtree.pathString()).To(gomega.Equal(".containers[*]"))
+	g.Expect(typedSubtree{path: []string{"labels", additionalPropertiesSegment}}.pathString()).To(gomega.Equal(".labels.*"))
+
+	var saved int
+	editSubtree(props, subtree.path, func(sub *apiext.JSONSchemaProps) {
+		saved = descriptionBytes(sub) - rootDescriptionBytes(sub)
+		rootDesc := sub.Description
+		TruncateDescription(sub, 0)
+		sub.Description = rootDesc
+	})
+	g.Expect(saved).To(gomega.Equal(len(`"description":"value",`)))
+	g.Expect(props.Description).To(gomega.Equal("root"))
+	g.Expect(props.Properties["containers"].Description).To(gomega.Equal("containers"))
+	g.Expect(props.Properties["containers"].Items.Schema.Description).To(gomega.Equal("container"))
+	g.Expect(props.Properties["containers"].Items.Schema.AdditionalProperties.Schema.Description).To(gomega.BeEmpty())
+
+	// missing paths are left alone
+	editSubtree(props, []string{"missing"}, func(*apiext.JSONSchemaProps) {
+		t.Error("edit called for a missing path")
+	})
+}
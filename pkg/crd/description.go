@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// DescriptionRules configures how Go documentation is turned into schema
+// descriptions.  The zero value uses the documentation as-is.
+type DescriptionRules struct {
+	// Separator, if set, drops the first line consisting only of this string
+	// (e.g. `---`), and everything after it, so that implementation notes
+	// can follow it without showing up in the schema.
+	Separator string
+
+	// StripTODOs drops any lines starting with TODO or FIXME.
+	StripTODOs bool
+
+	// RewriteLinks rewrites Go doc links into plain text: code links like
+	// `[Foo]` or `[pkg.Foo]` lose their brackets, while links with a
+	// matching `[text]: URL` definition become `text (URL)`, and the
+	// definitions themselves are dropped.
+	RewriteLinks bool
+}
+
+var (
+	// todoLine matches lines starting with TODO or FIXME, like `TODO(someone): fix this`.
+	todoLine = regexp.MustCompile(`^(TODO|FIXME)\b`)
+	// linkDefinition matches doc link definitions, like `[text]: https://example.com`.
+	linkDefinition = regexp.MustCompile(`^\[([^\]]+)\]:\s*(\S+)$`)
+	// docLink matches potential doc links, like `[text]`.
+	docLink = regexp.MustCompile(`\[([^\]\[]+)\]`)
+	// codeLink matches the contents of doc links to Go identifiers, like `[*pkg.Foo]`.
+	codeLink = regexp.MustCompile(`^\*?([\w.-]+/)*([A-Za-z_]\w*\.)?[A-Za-z_]\w*(\.[A-Za-z_]\w*)?$`)
+)
+
+// Describe produces a description from the given lines of Go documentation
+// (see markers.FieldInfo.DocLines), applying the rules.
+func (r DescriptionRules) Describe(docLines []string) string {
+	var lines []string
+	links := make(map[string]string)
+	for _, line := range docLines {
+		trimmed := strings.TrimSpace(line)
+		if r.Separator != "" && trimmed == r.Separator {
+			break
+		}
+		if r.StripTODOs && todoLine.MatchString(trimmed) {
+			continue
+		}
+		if r.RewriteLinks {
+			if def := linkDefinition.FindStringSubmatch(trimmed); def != nil {
+				links[def[1]] = def[2]
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	// trim trailing blank lines left behind by anything we dropped
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if r.RewriteLinks {
+		for i, line := range lines {
+			lines[i] = rewriteDocLinks(line, links)
+		}
+	}
+
+	return markers.JoinDocLines(lines)
+}
+
+// rewriteDocLinks rewrites any doc links in the given line into plain text,
+// using the given link definitions.
+func rewriteDocLinks(line string, links map[string]string) string {
+	var out strings.Builder
+	last := 0
+	for _, match := range docLink.FindAllStringSubmatchIndex(line, -1) {
+		start, end := match[0], match[1]
+		text := line[match[2]:match[3]]
+
+		// like godoc, links must be surrounded by spaces or punctuation,
+		// so that we don't rewrite things like `items[name]`
+		if (start > 0 && isWordByte(line[start-1])) || (end < len(line) && (isWordByte(line[end]) || line[end] == '(')) {
+			continue
+		}
+
+		var replacement string
+		if url, isDefined := links[text]; isDefined {
+			replacement = text + " (" + url + ")"
+		} else if codeLink.MatchString(text) {
+			replacement = text
+		} else {
+			continue
+		}
+
+		out.WriteString(line[last:start])
+		out.WriteString(replacement)
+		last = end
+	}
+	out.WriteString(line[last:])
+	return out.String()
+}
+
+// isWordByte checks if the given byte is part of a word (as opposed to
+// space or punctuation).
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
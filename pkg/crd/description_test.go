@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+var descriptionTestDoc = []string{
+	"Widget is a [pkg.Thing] that does stuff, see the [widget docs].",
+	"TODO(someone): make it do more stuff.",
+	"It uses items[0] and [a-z]+ patterns,",
+	"FIXME: this is wrong",
+	"and refers to [*k8s.io/api/core/v1.Pod] too.",
+	"",
+	"[widget docs]: https://example.com/widgets",
+	"",
+	"---",
+	"Implementation notes.",
+}
+
+func Test_Description_Rules(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules DescriptionRules
+		desc  string
+	}{
+		{
+			name:  "no rules",
+			rules: DescriptionRules{},
+			desc:  markers.JoinDocLines(descriptionTestDoc),
+		},
+		{
+			name:  "separator",
+			rules: DescriptionRules{Separator: "---"},
+			desc: "Widget is a [pkg.Thing] that does stuff, see the [widget docs]. TODO(someone): make it do more stuff. " +
+				"It uses items[0] and [a-z]+ patterns, FIXME: this is wrong and refers to [*k8s.io/api/core/v1.Pod] too. \n " +
+				"[widget docs]: https://example.com/widgets",
+		},
+		{
+			name:  "all rules",
+			rules: DescriptionRules{Separator: "---", StripTODOs: true, RewriteLinks: true},
+			desc: "Widget is a pkg.Thing that does stuff, see the widget docs (https://example.com/widgets). " +
+				"It uses items[0] and [a-z]+ patterns, and refers to *k8s.io/api/core/v1.Pod too.",
+		},
+	}
+
+	for _, c := range cases {
+		g := gomega.NewWithT(t)
+		g.Expect(c.rules.Describe(descriptionTestDoc)).To(gomega.Equal(c.desc), c.name)
+	}
+}
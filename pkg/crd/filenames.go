@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// FileNameData is the information available to file name templates
+// when naming generated CustomResourceDefinitions (and similar objects).
+type FileNameData struct {
+	// Group is the API group of the defined resource.
+	Group string
+	// Kind is the kind of the defined resource.
+	Kind string
+	// Plural is the plural resource name of the defined resource.
+	Plural string
+	// Singular is the singular resource name of the defined resource.
+	Singular string
+	// CRDVersion is the API version of the definition object itself (e.g. v1).
+	CRDVersion string
+}
+
+// FileNamer names the files that generated objects are written to, either
+// using a template or the generator's default naming, and makes sure that
+// no two objects are written to the same file.
+type FileNamer struct {
+	template *template.Template
+	written  map[string]struct{}
+}
+
+// NewFileNamer constructs a FileNamer from the given file name template (see
+// FileNameData for the available fields).  An empty template indicates that
+// the generator's default names should be used.
+func NewFileNamer(fileNameTemplate string) (*FileNamer, error) {
+	namer := &FileNamer{
+		written: make(map[string]struct{}),
+	}
+	if fileNameTemplate == "" {
+		return namer, nil
+	}
+
+	tmpl, err := template.New("fileName").Option("missingkey=error").Parse(fileNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file name template %q: %w", fileNameTemplate, err)
+	}
+	namer.template = tmpl
+	return namer, nil
+}
+
+// FileName returns the name of the file that the object described by the
+// given data should be written to, falling back to the given default name if
+// no template was specified.
+func (n *FileNamer) FileName(data FileNameData, defaultName string) (string, error) {
+	fileName := defaultName
+	if n.template != nil {
+		var out bytes.Buffer
+		if err := n.template.Execute(&out, data); err != nil {
+			return "", fmt.Errorf("unable to render file name for %s.%s: %w", data.Kind, data.Group, err)
+		}
+		fileName = path.Clean(out.String())
+		if fileName == "." || path.IsAbs(fileName) || fileName == ".." || strings.HasPrefix(fileName, "../") {
+			return "", fmt.Errorf("file name template rendered invalid path %q for %s.%s, must be relative to the output directory", out.String(), data.Kind, data.Group)
+		}
+	}
+
+	if _, exists := n.written[fileName]; exists {
+		return "", fmt.Errorf("multiple objects would be written to %q, make sure the file name template is unique for each object", fileName)
+	}
+	n.written[fileName] = struct{}{}
+	return fileName, nil
+}
+
+// FileNames returns all the file names handed out by this FileNamer so far, sorted.
+func (n *FileNamer) FileNames() []string {
+	names := make([]string, 0, len(n.written))
+	for name := range n.written {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/types"
+	"math"
 	"sort"
 	"strings"
 
@@ -65,6 +66,24 @@ type Generator struct {
 	// closest sentence boundary if it exceeds n characters.
 	MaxDescLen *int `marker:",optional"`
 
+	// DescriptionSeparator cuts descriptions off at the first line of Go
+	// documentation consisting only of this string (quote it on the command
+	// line, e.g. descriptionSeparator="---"), so that implementation notes can
+	// follow it without ending up in the schema.
+	DescriptionSeparator string `marker:",optional"`
+
+	// StripDescriptionTODOs removes lines starting with TODO or FIXME from descriptions.
+	//
+	// Left unspecified, the default is false.
+	StripDescriptionTODOs *bool `marker:",optional"`
+
+	// RewriteDescriptionLinks rewrites Go doc links in descriptions into plain
+	// text: `[pkg.Foo]` becomes `pkg.Foo`, and `[text]` with a matching
+	// `[text]: URL` definition becomes `text (URL)`.
+	//
+	// Left unspecified, the default is false.
+	RewriteDescriptionLinks *bool `marker:",optional"`
+
 	// CRDVersions specifies the target API versions of the CRD type itself to
 	// generate. Defaults to v1.
 	//
@@ -85,11 +104,46 @@ type Generator struct {
 
 	// Year specifies the year to substitute for " YEAR" in the header file.
 	Year string `marker:",optional"`
+
+	// FileNameTemplate specifies a Go template for the path of each generated file,
+	// relative to the output directory.  Since templates contain braces, quote the
+	// value on the command line, e.g. fileNameTemplate="{{.Group}}/{{.Plural}}.yaml".
+	//
+	// The template may refer to .Group, .Kind, .Plural, .Singular and .CRDVersion.
+	// Left unspecified, files are named "<group>_<plural>.yaml", with a
+	// ".<crdVersion>" suffix for every CRD version after the first.
+	FileNameTemplate string `marker:",optional"`
+
+	// Kustomization indicates that a kustomization.yaml listing every generated
+	// file should be written as well.
+	//
+	// Left unspecified, the default is false.
+	Kustomization *bool `marker:",optional"`
+
+	// SizeBudget specifies the maximum size, in bytes, of each generated CRD when
+	// serialized as JSON (which is how kubectl stores it in the
+	// last-applied-configuration annotation, limited to 262144 bytes).
+	//
+	// Descriptions are removed from CRDs over budget one subtree at a time until
+	// they fit, starting with the deepest (and then largest) subtrees generated
+	// from types outside of the input packages, like an embedded PodTemplateSpec.
+	// Unlike maxDescLen, CRDs under budget keep all their descriptions.
+	SizeBudget *int `marker:",optional"`
+
+	// SizeReport specifies a file (relative to the output) to write size
+	// statistics for each generated CRD to, including which subtrees had
+	// their descriptions removed to fit the size budget.
+	SizeReport string `marker:",optional"`
 }
 
 func (Generator) CheckFilter() loader.NodeFilter {
 	return filterTypesForCRDs
 }
+
+func (Generator) CacheScope() genall.CacheScope {
+	return genall.CachePerRun
+}
+
 func (Generator) RegisterMarkers(into *markers.Registry) error {
 	return crdmarkers.Register(into)
 }
@@ -101,31 +155,24 @@ func transformRemoveCRDStatus(obj map[string]interface{}) error {
 }
 
 func (g Generator) Generate(ctx *genall.GenerationContext) error {
-	parser := &Parser{
-		Collector: ctx.Collector,
-		Checker:   ctx.Checker,
+	shared := SharedParserFor(ctx, ParserSettings{
 		// Perform defaulting here to avoid ambiguity later
 		IgnoreUnexportedFields: g.IgnoreUnexportedFields != nil && *g.IgnoreUnexportedFields == true,
 		AllowDangerousTypes:    g.AllowDangerousTypes != nil && *g.AllowDangerousTypes == true,
 		// Indicates the parser on whether to register the ObjectMeta type or not
 		GenerateEmbeddedObjectMeta: g.GenerateEmbeddedObjectMeta != nil && *g.GenerateEmbeddedObjectMeta == true,
-	}
-
-	AddKnownTypes(parser)
-	for _, root := range ctx.Roots {
-		parser.NeedPackage(root)
-	}
-
-	metav1Pkg := FindMetav1(ctx.Roots)
-	if metav1Pkg == nil {
-		// no objects in the roots, since nothing imported metav1
-		return nil
-	}
+		DescriptionRules: DescriptionRules{
+			Separator:    g.DescriptionSeparator,
+			StripTODOs:   g.StripDescriptionTODOs != nil && *g.StripDescriptionTODOs == true,
+			RewriteLinks: g.RewriteDescriptionLinks != nil && *g.RewriteDescriptionLinks == true,
+		},
+	})
+	parser := shared.Parser
 
 	// TODO: allow selecting a specific object
-	kubeKinds := FindKubeKinds(parser, metav1Pkg)
+	kubeKinds := shared.KubeKinds
 	if len(kubeKinds) == 0 {
-		// no objects in the roots
+		// no objects in the roots (or nothing imported metav1)
 		return nil
 	}
 
@@ -146,14 +193,40 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 	}
 	headerText = strings.ReplaceAll(headerText, " YEAR", " "+g.Year)
 
+	fileNames, err := NewFileNamer(g.FileNameTemplate)
+	if err != nil {
+		return err
+	}
+
+	var sizeStats []SizeStats // (one per kind, in order)
 	for _, groupKind := range kubeKinds {
+		// the parser may be shared with another crd generator, and cached
+		// CRDs don't account for maxDescLen.  The CRD is copied out (since
+		// it gets modified below), so that the parser is only locked while
+		// it's actually needed.
+		shared.Lock()
+		delete(parser.CustomResourceDefinitions, groupKind)
 		parser.NeedCRDFor(groupKind, g.MaxDescLen)
 		crdRaw := parser.CustomResourceDefinitions[groupKind]
+		crdRaw = *crdRaw.DeepCopy()
+		shared.Unlock()
 		addAttribution(&crdRaw)
 
 		// Prevent the top level metadata for the CRD to be generate regardless of the intention in the arguments
 		FixTopLevelMetadata(crdRaw)
 
+		if g.SizeBudget != nil || g.SizeReport != "" {
+			budget := math.MaxInt
+			if g.SizeBudget != nil {
+				budget = *g.SizeBudget
+			}
+			stats, err := shared.FitCRDToBudget(&crdRaw, ctx.Roots, budget)
+			if err != nil {
+				return err
+			}
+			sizeStats = append(sizeStats, stats)
+		}
+
 		versionedCRDs := make([]interface{}, len(crdVersions))
 		for i, ver := range crdVersions {
 			conv, err := AsVersion(crdRaw, schema.GroupVersion{Group: apiext.SchemeGroupVersion.Group, Version: ver})
@@ -165,11 +238,21 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 
 		for i, crd := range versionedCRDs {
 			removeDescriptionFromMetadata(crd.(*apiext.CustomResourceDefinition))
-			var fileName string
+			var defaultName string
 			if i == 0 {
-				fileName = fmt.Sprintf("%s_%s.yaml", crdRaw.Spec.Group, crdRaw.Spec.Names.Plural)
+				defaultName = fmt.Sprintf("%s_%s.yaml", crdRaw.Spec.Group, crdRaw.Spec.Names.Plural)
 			} else {
-				fileName = fmt.Sprintf("%s_%s.%s.yaml", crdRaw.Spec.Group, crdRaw.Spec.Names.Plural, crdVersions[i])
+				defaultName = fmt.Sprintf("%s_%s.%s.yaml", crdRaw.Spec.Group, crdRaw.Spec.Names.Plural, crdVersions[i])
+			}
+			fileName, err := fileNames.FileName(FileNameData{
+				Group:      crdRaw.Spec.Group,
+				Kind:       crdRaw.Spec.Names.Kind,
+				Plural:     crdRaw.Spec.Names.Plural,
+				Singular:   crdRaw.Spec.Names.Singular,
+				CRDVersion: crdVersions[i],
+			}, defaultName)
+			if err != nil {
+				return err
 			}
 			if err := ctx.WriteYAML(fileName, headerText, []interface{}{crd}, genall.WithTransform(transformRemoveCRDStatus)); err != nil {
 				return err
@@ -177,6 +260,20 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 		}
 	}
 
+	if g.SizeReport != "" {
+		reports := make([]interface{}, len(sizeStats))
+		for i, stats := range sizeStats {
+			reports[i] = stats
+		}
+		if err := ctx.WriteYAML(g.SizeReport, "", reports); err != nil {
+			return err
+		}
+	}
+
+	if g.Kustomization != nil && *g.Kustomization {
+		return ctx.WriteKustomization(headerText, fileNames.FileNames())
+	}
+
 	return nil
 }
 
@@ -26,6 +26,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"sigs.k8s.io/controller-tools/pkg/crd"
 	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
@@ -120,6 +121,125 @@ var _ = Describe("CRD Generation proper defaulting", func() {
 		expectedOut := string(expectedFileFoos) + string(expectedFileZoos)
 		Expect(out.buf.String()).To(Equal(expectedOut), cmp.Diff(out.buf.String(), expectedOut))
 	})
+
+	It("should lay out files using the file name template, and list them in a kustomization", func() {
+		By("calling Generate on multiple packages")
+		kustomize := true
+		gen := &crd.Generator{
+			FileNameTemplate: "{{.Group}}/{{.Plural}}.yaml",
+			Kustomization:    &kustomize,
+		}
+		Expect(gen.Generate(ctx2)).NotTo(HaveOccurred())
+
+		By("checking the paths written")
+		Expect(out.paths).To(Equal([]string{
+			"bar.example.com/foos.yaml",
+			"bar.example.com/zooes.yaml",
+			"kustomization.yaml",
+		}))
+
+		By("checking the kustomization")
+		Expect(out.buf.String()).To(HaveSuffix(`---
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- bar.example.com/foos.yaml
+- bar.example.com/zooes.yaml
+`))
+	})
+
+	It("should refuse to write multiple CRDs to the same file", func() {
+		By("calling Generate on multiple packages")
+		gen := &crd.Generator{
+			FileNameTemplate: "{{.Group}}.yaml",
+		}
+		Expect(gen.Generate(ctx2)).To(MatchError(ContainSubstring(`multiple objects would be written to "bar.example.com.yaml"`)))
+	})
+
+	It("should share parsers between generators with the same settings", func() {
+		By("calling Generate without sharing anything")
+		gen := &crd.Generator{
+			CRDVersions: []string{"v1"},
+		}
+		Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+		unshared := out.buf.String()
+		out.buf.Reset()
+
+		ctx.Cache = &genall.Cache{}
+
+		By("asking for parsers with the same and different settings")
+		parser := crd.SharedParserFor(ctx, crd.ParserSettings{})
+		Expect(crd.SharedParserFor(ctx, crd.ParserSettings{})).To(BeIdenticalTo(parser))
+		Expect(crd.SharedParserFor(ctx, crd.ParserSettings{AllowDangerousTypes: true})).NotTo(BeIdenticalTo(parser))
+
+		By("calling Generate with a maxDescLen first, so it doesn't leak into later output")
+		maxDescLen := 0
+		gen.MaxDescLen = &maxDescLen
+		Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+		Expect(out.buf.String()).NotTo(ContainSubstring("description:"))
+
+		By("calling Generate again without one")
+		out.buf.Reset()
+		gen.MaxDescLen = nil
+		Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+
+		By("comparing to the output without sharing")
+		Expect(out.buf.String()).To(Equal(unshared), cmp.Diff(out.buf.String(), unshared))
+	})
+
+	It("should remove descriptions from CRDs over the size budget, and report on it", func() {
+		By("measuring the CRD without a budget")
+		gen := &crd.Generator{
+			SizeReport: "sizes.yaml",
+		}
+		Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+		Expect(out.paths).To(Equal([]string{"bar.example.com_foos.yaml", "sizes.yaml"}))
+		untrimmed := out.buf.String()
+		Expect(untrimmed).To(ContainSubstring("This tests that defaulted fields are stripped"))
+		Expect(untrimmed).NotTo(ContainSubstring("trimmed:"))
+
+		By("calling Generate with a budget just under that size")
+		parser := &crd.Parser{Collector: ctx.Collector, Checker: ctx.Checker}
+		crd.AddKnownTypes(parser)
+		for _, root := range ctx.Roots {
+			parser.NeedPackage(root)
+		}
+		groupKind := schema.GroupKind{Group: "bar.example.com", Kind: "Foo"}
+		parser.NeedCRDFor(groupKind, nil)
+		crdRaw := parser.CustomResourceDefinitions[groupKind]
+		crd.FixTopLevelMetadata(crdRaw)
+		size, err := crd.CRDSize(&crdRaw)
+		Expect(err).NotTo(HaveOccurred())
+
+		budget := size - 1
+		out.buf.Reset()
+		out.paths = nil
+		gen.SizeBudget = &budget
+		Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+
+		By("checking that only the nested descriptions were removed")
+		Expect(out.buf.String()).NotTo(ContainSubstring("This tests that defaulted fields are stripped"))
+		Expect(out.buf.String()).To(ContainSubstring("Spec comments SHOULD appear in the CRD spec"))
+		Expect(out.buf.String()).To(HaveSuffix(`---
+group: bar.example.com
+kind: Foo
+originalSize: 1653
+size: 1563
+trimmed:
+- path: .spec
+  saved: 90
+  type: testdata.kubebuilder.io/cronjob/gen.FooSpec
+  version: foo
+`))
+	})
+
+	It("should fail if a CRD doesn't fit into the size budget", func() {
+		budget := 100
+		gen := &crd.Generator{
+			SizeBudget: &budget,
+		}
+		Expect(gen.Generate(ctx)).To(MatchError(ContainSubstring("over the budget of 100 bytes")))
+	})
 })
 
 // fixAnnotations fixes the attribution annotation for tests.
@@ -128,10 +248,12 @@ func fixAnnotations(crdBytes []byte) []byte {
 }
 
 type outputRule struct {
-	buf *bytes.Buffer
+	buf   *bytes.Buffer
+	paths []string
 }
 
 func (o *outputRule) Open(_ *loader.Package, itemPath string) (io.WriteCloser, error) {
+	o.paths = append(o.paths, itemPath)
 	return nopCloser{o.buf}, nil
 }
 
@@ -0,0 +1,313 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// Printer columns and the scale subresource only really use a tiny subset of
+// JSONPath (`.field`, `[0]`, `[*]`, `[?(@.x=="y")]`), so we only check those.
+// Anything fancier (recursive descent, unions, etc) is assumed to be correct,
+// since we can't reasonably figure out what it refers to.
+
+// jsonPathSegment is a single step in a simple JSONPath expression.
+type jsonPathSegment struct {
+	// field is the name of the field to look up, or empty
+	// for an array index, wildcard or filter.
+	field string
+}
+
+// parseSimpleJSONPath splits the given JSONPath into segments.  If the path
+// uses features that can't be checked statically, supported is false.
+func parseSimpleJSONPath(path string) (segments []jsonPathSegment, supported bool, err error) {
+	rest := strings.TrimSpace(path)
+	if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") {
+		rest = rest[1 : len(rest)-1]
+	}
+	rest = strings.TrimPrefix(rest, "$")
+	if rest == "" || (rest[0] != '.' && rest[0] != '[') {
+		return nil, false, fmt.Errorf("JSONPath %q must start with a '.'", path)
+	}
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			if strings.HasPrefix(rest, "..") {
+				// recursive descent
+				return nil, false, nil
+			}
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, false, fmt.Errorf("JSONPath %q contains an empty field name", path)
+			}
+			if name == "*" {
+				return nil, false, nil
+			}
+			segments = append(segments, jsonPathSegment{field: name})
+			rest = rest[end+1:]
+		case '[':
+			end := closingBracket(rest)
+			if end == -1 {
+				return nil, false, fmt.Errorf("JSONPath %q contains an unterminated '['", path)
+			}
+			contents := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case strings.HasPrefix(contents, "'") || strings.HasPrefix(contents, `"`):
+				// quoted field name, like ['app.kubernetes.io/name']
+				name, err := unquoteJSONPathKey(contents)
+				if err != nil {
+					// probably a union of keys
+					return nil, false, nil
+				}
+				segments = append(segments, jsonPathSegment{field: name})
+			case strings.HasPrefix(contents, "?"):
+				// filter -- still an element of the array
+				segments = append(segments, jsonPathSegment{})
+			case strings.Contains(contents, ","):
+				// union
+				return nil, false, nil
+			default:
+				// index, slice, or wildcard
+				segments = append(segments, jsonPathSegment{})
+			}
+		default:
+			return nil, false, fmt.Errorf("JSONPath %q has unexpected character %q at %q", path, rest[0], rest)
+		}
+	}
+
+	return segments, true, nil
+}
+
+// closingBracket finds the index of the bracket closing the one at the start
+// of the given string, skipping over anything in quotes.
+func closingBracket(path string) int {
+	var quote byte
+	for i := 1; i < len(path); i++ {
+		switch {
+		case quote != 0 && path[i] == quote:
+			quote = 0
+		case quote != 0:
+			continue
+		case path[i] == '\'' || path[i] == '"':
+			quote = path[i]
+		case path[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteJSONPathKey unquotes a single- or double-quoted key in a JSONPath.
+func unquoteJSONPathKey(key string) (string, error) {
+	if key[0] == '\'' {
+		if len(key) < 2 || key[len(key)-1] != '\'' || strings.Contains(key[1:len(key)-1], "'") {
+			return "", fmt.Errorf("invalid quoted key %s", key)
+		}
+		return key[1 : len(key)-1], nil
+	}
+	return strconv.Unquote(key)
+}
+
+// isOpaqueSchema checks if the structure below the given schema is unknown,
+// meaning that paths into it can't be checked.
+func isOpaqueSchema(schema *apiext.JSONSchemaProps) bool {
+	if schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields {
+		return true
+	}
+	if schema.XIntOrString || (schema.Type != "" && schema.Type != "object") {
+		return false
+	}
+	return len(schema.Properties) == 0 && schema.AdditionalProperties == nil &&
+		len(schema.AllOf) == 0 && len(schema.AnyOf) == 0 && len(schema.OneOf) == 0
+}
+
+// lookupProperty finds the schema for the given property, looking in any
+// allOf/anyOf/oneOf branches if it's not directly present.
+func lookupProperty(schema *apiext.JSONSchemaProps, name string) (*apiext.JSONSchemaProps, bool) {
+	if prop, exists := schema.Properties[name]; exists {
+		return &prop, true
+	}
+	for _, branches := range [][]apiext.JSONSchemaProps{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for i := range branches {
+			if prop, exists := lookupProperty(&branches[i], name); exists {
+				return prop, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// resolveJSONPath walks the given (flattened) schema along the given JSONPath,
+// returning the schema of the field it refers to.  A nil schema with no error
+// indicates that the path leads somewhere whose structure isn't known (like
+// the object metadata, or preserve-unknown-fields), so it can't be checked
+// any further.
+func resolveJSONPath(root *apiext.JSONSchemaProps, path string) (*apiext.JSONSchemaProps, error) {
+	segments, supported, err := parseSimpleJSONPath(path)
+	if err != nil || !supported {
+		return nil, err
+	}
+
+	current := root
+	walked := ""
+	for i, segment := range segments {
+		if i == 0 && segment.field == "metadata" {
+			// the API server fills in the full ObjectMeta, regardless of what
+			// (if any) of it is described in the schema.
+			return nil, nil
+		}
+		if isOpaqueSchema(current) {
+			return nil, nil
+		}
+
+		if segment.field == "" {
+			if current.Type != "array" {
+				return nil, fmt.Errorf("JSONPath %q indexes %s, which is not an array", path, describePath(walked))
+			}
+			if current.Items == nil || current.Items.Schema == nil {
+				return nil, nil
+			}
+			current = current.Items.Schema
+			walked += "[]"
+			continue
+		}
+
+		if current.Type != "" && current.Type != "object" {
+			return nil, fmt.Errorf("JSONPath %q refers to field %q of %s, which is of type %s and has no fields", path, segment.field, describePath(walked), current.Type)
+		}
+		prop, exists := lookupProperty(current, segment.field)
+		switch {
+		case exists:
+			current = prop
+		case current.AdditionalProperties != nil && current.AdditionalProperties.Schema != nil:
+			current = current.AdditionalProperties.Schema
+		case current.AdditionalProperties != nil && current.AdditionalProperties.Allows:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("JSONPath %q refers to unknown field %q of %s", path, segment.field, describePath(walked))
+		}
+		walked += "." + segment.field
+	}
+
+	return current, nil
+}
+
+// describePath describes a partially-walked JSONPath for error messages.
+func describePath(walked string) string {
+	if walked == "" {
+		return "the object"
+	}
+	return fmt.Sprintf("%q", walked)
+}
+
+// checkColumnType checks that a printer column of the given type can actually
+// display the field with the given schema.  The API server leaves the cell
+// empty if the types don't match, except for string columns, which display
+// any value.
+func checkColumnType(colType string, field *apiext.JSONSchemaProps) error {
+	var allowed []string
+	switch colType {
+	case "string":
+		return nil
+	case "integer":
+		allowed = []string{"integer"}
+	case "number":
+		allowed = []string{"integer", "number"}
+	case "boolean":
+		allowed = []string{"boolean"}
+	case "date":
+		allowed = []string{"string"}
+	default:
+		return fmt.Errorf("unknown column type %q, must be one of integer, number, string, boolean or date", colType)
+	}
+
+	if field == nil || (field.Type == "" && !field.XIntOrString) {
+		// nothing we can check
+		return nil
+	}
+	if field.XIntOrString {
+		if colType == "integer" || colType == "number" {
+			return nil
+		}
+		return fmt.Errorf("column type %s does not match field type int-or-string", colType)
+	}
+	for _, typ := range allowed {
+		if field.Type == typ {
+			return nil
+		}
+	}
+	return fmt.Errorf("column type %s does not match field type %s", colType, field.Type)
+}
+
+// checkFieldType checks that the given field schema (if known) has the given type.
+func checkFieldType(field *apiext.JSONSchemaProps, typ string) error {
+	if field == nil || field.Type == "" || field.Type == typ {
+		return nil
+	}
+	return fmt.Errorf("field must be of type %s, not %s", typ, field.Type)
+}
+
+// checkMarkerJSONPaths checks that any JSONPaths in the given marker value
+// refer to fields of the right type in the given version schema.
+func checkMarkerJSONPaths(marker interface{}, versionSchema *apiext.JSONSchemaProps) error {
+	switch marker := marker.(type) {
+	case crdmarkers.PrintColumn:
+		field, err := resolveJSONPath(versionSchema, marker.JSONPath)
+		if err != nil {
+			return fmt.Errorf("invalid printer column %q: %w", marker.Name, err)
+		}
+		if err := checkColumnType(marker.Type, field); err != nil {
+			return fmt.Errorf("invalid printer column %q with JSONPath %q: %w", marker.Name, marker.JSONPath, err)
+		}
+	case crdmarkers.SubresourceScale:
+		paths := []struct {
+			name, path, typ string
+		}{
+			{name: "specpath", path: marker.SpecPath, typ: "integer"},
+			{name: "statuspath", path: marker.StatusPath, typ: "integer"},
+		}
+		if marker.SelectorPath != nil {
+			paths = append(paths, struct{ name, path, typ string }{name: "selectorpath", path: *marker.SelectorPath, typ: "string"})
+		}
+
+		var errs []error
+		for _, info := range paths {
+			field, err := resolveJSONPath(versionSchema, info.path)
+			if err == nil {
+				err = checkFieldType(field, info.typ)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid scale subresource %s %q: %w", info.name, info.path, err))
+			}
+		}
+		return loader.MaybeErrList(errs)
+	}
+	return nil
+}
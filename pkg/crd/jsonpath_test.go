@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var jsonPathTestSchema = &apiext.JSONSchemaProps{
+	Type: "object",
+	Properties: map[string]apiext.JSONSchemaProps{
+		"spec": {
+			Type: "object",
+			Properties: map[string]apiext.JSONSchemaProps{
+				"replicas": {Type: "integer"},
+				"labels": {
+					Type: "object",
+					AdditionalProperties: &apiext.JSONSchemaPropsOrBool{
+						Allows: true,
+						Schema: &apiext.JSONSchemaProps{Type: "string"},
+					},
+				},
+				"raw": {
+					Type:                   "object",
+					XPreserveUnknownFields: boolPtr(true),
+				},
+				"ports": {
+					Type: "array",
+					Items: &apiext.JSONSchemaPropsOrArray{
+						Schema: &apiext.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiext.JSONSchemaProps{
+								"port": {XIntOrString: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func Test_JSONPath_Resolve(t *testing.T) {
+	cases := []struct {
+		path    string
+		typ     string
+		unknown bool
+	}{
+		{path: ".spec.replicas", typ: "integer"},
+		{path: "{.spec.replicas}", typ: "integer"},
+		{path: ".spec.labels.app", typ: "string"},
+		{path: ".spec.labels['app.kubernetes.io/name']", typ: "string"},
+		{path: ".spec.ports[0]", typ: "object"},
+		{path: ".spec.ports[*].port"},
+		{path: `.spec.ports[?(@.name=="http")].port`},
+		{path: ".spec.raw.anything.at.all", unknown: true},
+		{path: ".metadata.creationTimestamp", unknown: true},
+		{path: "..replicas", unknown: true},
+		{path: ".spec.ports[0,1].port", unknown: true},
+	}
+
+	for _, c := range cases {
+		g := gomega.NewWithT(t)
+		field, err := resolveJSONPath(jsonPathTestSchema, c.path)
+		g.Expect(err).NotTo(gomega.HaveOccurred(), c.path)
+		if c.unknown {
+			g.Expect(field).To(gomega.BeNil(), c.path)
+			continue
+		}
+		g.Expect(field).NotTo(gomega.BeNil(), c.path)
+		g.Expect(field.Type).To(gomega.Equal(c.typ), c.path)
+	}
+}
+
+func Test_JSONPath_ResolveErrors(t *testing.T) {
+	cases := []struct {
+		path string
+		err  string
+	}{
+		{path: "spec.replicas", err: "must start with a '.'"},
+		{path: ".spec.replica", err: `unknown field "replica" of ".spec"`},
+		{path: ".spec.replicas.value", err: "which is of type integer and has no fields"},
+		{path: ".spec.replicas[0]", err: `indexes ".spec.replicas", which is not an array`},
+		{path: ".spec.ports[0].name", err: `unknown field "name" of ".spec.ports[]"`},
+		{path: ".spec[0", err: "unterminated '['"},
+	}
+
+	for _, c := range cases {
+		g := gomega.NewWithT(t)
+		_, err := resolveJSONPath(jsonPathTestSchema, c.path)
+		if c.err == "" {
+			g.Expect(err).NotTo(gomega.HaveOccurred(), c.path)
+			continue
+		}
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(c.err)), c.path)
+	}
+}
+
+func Test_JSONPath_ColumnType(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(checkColumnType("integer", &apiext.JSONSchemaProps{Type: "integer"})).To(gomega.Succeed())
+	g.Expect(checkColumnType("number", &apiext.JSONSchemaProps{Type: "integer"})).To(gomega.Succeed())
+	g.Expect(checkColumnType("string", &apiext.JSONSchemaProps{Type: "array"})).To(gomega.Succeed())
+	g.Expect(checkColumnType("date", &apiext.JSONSchemaProps{Type: "string", Format: "date-time"})).To(gomega.Succeed())
+	g.Expect(checkColumnType("integer", &apiext.JSONSchemaProps{XIntOrString: true})).To(gomega.Succeed())
+	g.Expect(checkColumnType("boolean", nil)).To(gomega.Succeed())
+
+	g.Expect(checkColumnType("integer", &apiext.JSONSchemaProps{Type: "string"})).To(gomega.MatchError("column type integer does not match field type string"))
+	g.Expect(checkColumnType("date", &apiext.JSONSchemaProps{Type: "integer"})).To(gomega.MatchError("column type date does not match field type integer"))
+	g.Expect(checkColumnType("int", &apiext.JSONSchemaProps{Type: "integer"})).To(gomega.MatchError(gomega.ContainSubstring(`unknown column type "int"`)))
+}
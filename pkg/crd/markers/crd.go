@@ -92,6 +92,9 @@ func (s SubresourceStatus) ApplyToCRD(crd *apiext.CustomResourceDefinitionSpec,
 // +controllertools:marker:generateHelp:category=CRD
 
 // SubresourceScale enables the "/scale" subresource on a CRD.
+//
+// The replicas paths must refer to integer fields, and the selector path to
+// a string field, in the schema of the version it's applied to.
 type SubresourceScale struct {
 	// marker names are leftover legacy cruft
 
@@ -200,6 +203,9 @@ type PrintColumn struct {
 	Type string
 
 	// JSONPath specifies the jsonpath expression used to extract the value of the column.
+	//
+	// The path is checked against the schema of the version it's applied to, and
+	// must refer to a field whose type matches the column's type.
 	JSONPath string `marker:"JSONPath"` // legacy cruft
 
 	// Description specifies the help/description for this column.
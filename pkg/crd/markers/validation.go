@@ -20,6 +20,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
+	"regexp"
+	"strings"
 
 	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
@@ -27,7 +30,8 @@ import (
 )
 
 const (
-	SchemalessName = "kubebuilder:validation:Schemaless"
+	SchemalessName     = "kubebuilder:validation:Schemaless"
+	EnumFromConstsName = "kubebuilder:validation:Enum:fromConsts"
 )
 
 // ValidationMarkers lists all available markers that affect CRD schema generation,
@@ -94,6 +98,9 @@ var FieldOnlyMarkers = []*definitionWithHelp{
 
 	must(markers.MakeDefinition(SchemalessName, markers.DescribesField, Schemaless{})).
 		WithHelp(Schemaless{}.Help()),
+
+	must(markers.MakeDefinition("kubebuilder:description", markers.DescribesField, Description(""))).
+		WithHelp(Description("").Help()),
 }
 
 // ValidationIshMarkers are field-and-type markers that don't fall under the
@@ -104,6 +111,8 @@ var ValidationIshMarkers = []*definitionWithHelp{
 		WithHelp(XPreserveUnknownFields{}.Help()),
 	must(markers.MakeDefinition("kubebuilder:pruning:PreserveUnknownFields", markers.DescribesType, XPreserveUnknownFields{})).
 		WithHelp(XPreserveUnknownFields{}.Help()),
+	must(markers.MakeDefinition(EnumFromConstsName, markers.DescribesType, EnumFromConsts{})).
+		WithHelp(EnumFromConsts{}.Help()),
 }
 
 func init() {
@@ -166,6 +175,11 @@ type MinLength int
 
 // +controllertools:marker:generateHelp:category="CRD validation"
 // Pattern specifies that this string must match the given regular expression.
+//
+// The expression must use the common subset of Go (RE2) and ECMA-262 regular
+// expression syntax, since it's evaluated by both the API server and
+// clients: Go-only features like `\A`, `\z`, `(?i)` flags, named groups
+// and POSIX character classes aren't allowed.
 type Pattern string
 
 // +controllertools:marker:generateHelp:category="CRD validation"
@@ -218,9 +232,10 @@ type Nullable struct{}
 // A default value will be accepted as any value valid for the
 // field. Formatting for common types include: boolean: `true`, string:
 // `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy:
-// "delete"}`). Defaults should be defined in pruned form, and only best-effort
-// validation will be performed. Full validation of a default requires
-// submission of the containing CRD to an apiserver.
+// "delete"}`). Defaults should be defined in pruned form. The default is
+// validated against the final schema of the field during generation, but
+// full validation of a default requires submission of the containing CRD
+// to an apiserver.
 type Default struct {
 	Value interface{}
 }
@@ -231,9 +246,10 @@ type Default struct {
 // An example value will be accepted as any value valid for the
 // field. Formatting for common types include: boolean: `true`, string:
 // `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy:
-// "delete"}`). Examples should be defined in pruned form, and only best-effort
-// validation will be performed. Full validation of an example requires
-// submission of the containing CRD to an apiserver.
+// "delete"}`). Examples should be defined in pruned form. The example is
+// validated against the final schema of the field during generation, but
+// full validation of an example requires submission of the containing CRD
+// to an apiserver.
 type Example struct {
 	Value interface{}
 }
@@ -280,6 +296,25 @@ type XIntOrString struct{}
 // to be used only as a last resort.
 type Schemaless struct{}
 
+// +controllertools:marker:generateHelp:category=CRD
+// Description overrides the description of this field in the schema.
+//
+// Normally, the description is taken from the field's Go documentation.
+// The override is used as-is, without applying any of the generator's
+// description processing (though it's still subject to maxDescLen).
+type Description string
+
+// +controllertools:marker:generateHelp:category="CRD validation"
+// EnumFromConsts restricts this type to the values of the constants declared with it.
+//
+// All exported package-level constants of the marked type are collected (in
+// declaration order) and used as the enum, so that the constants are the
+// single source of truth for the allowed values.  Constants sharing a value
+// (like aliases) only contribute it once, and unexported constants are
+// skipped.  It can't be combined with an explicit Enum marker on the same
+// type.
+type EnumFromConsts struct{}
+
 func hasNumericType(schema *apiext.JSONSchemaProps) bool {
 	return schema.Type == "integer" || schema.Type == "number"
 }
@@ -288,6 +323,50 @@ func isIntegral(value float64) bool {
 	return value == math.Trunc(value) && !math.IsNaN(value) && !math.IsInf(value, 0)
 }
 
+// checkValueType checks that a value parsed from a marker (a string, bool,
+// number, slice or map) matches the type of the given schema.  Schemata
+// with no type accept anything.
+func checkValueType(value interface{}, schema *apiext.JSONSchemaProps) error {
+	if value == nil {
+		// null is handled by nullable, which may not have been applied yet
+		return nil
+	}
+
+	var valueType string
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		valueType = "string"
+	case reflect.Bool:
+		valueType = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		valueType = "integer"
+	case reflect.Float32, reflect.Float64:
+		valueType = "number"
+	case reflect.Slice, reflect.Array:
+		valueType = "array"
+	case reflect.Map, reflect.Struct:
+		valueType = "object"
+	default:
+		return fmt.Errorf("unsupported value of type %T", value)
+	}
+
+	switch {
+	case schema.XIntOrString:
+		if valueType == "integer" || valueType == "string" {
+			return nil
+		}
+		return fmt.Errorf("must be an integer or a string, not %s", valueType)
+	case schema.Type == "", schema.Type == valueType:
+		return nil
+	case schema.Type == "number" && valueType == "integer":
+		return nil
+	case schema.Type == "integer" && valueType == "number" && isIntegral(reflect.ValueOf(value).Float()):
+		return nil
+	}
+	return fmt.Errorf("must be of type %s, not %s", schema.Type, valueType)
+}
+
 // +controllertools:marker:generateHelp:category="CRD validation"
 // XValidation marks a field as requiring a value for which a given
 // expression evaluates to true.
@@ -383,10 +462,50 @@ func (m Pattern) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
 	if schema.Type != "string" && !schema.XIntOrString {
 		return fmt.Errorf("must apply pattern to a `string` or `IntOrString`")
 	}
+	if err := checkPattern(string(m)); err != nil {
+		return err
+	}
 	schema.Pattern = string(m)
 	return nil
 }
 
+// checkPattern checks that the given pattern is a valid regular expression
+// that means the same thing in Go (which the API server uses) and ECMA-262
+// (which OpenAPI specifies, and most clients use).
+func checkPattern(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i+1 >= len(pattern) {
+				// already rejected by the Go parser
+				return nil
+			}
+			i++
+			switch pattern[i] {
+			case 'A', 'z', 'Q', 'E', 'p', 'P':
+				return fmt.Errorf("invalid pattern %q: `\\%c` is not supported by ECMA-262 regular expressions", pattern, pattern[i])
+			}
+		case '[':
+			if inClass && strings.HasPrefix(pattern[i:], "[:") {
+				return fmt.Errorf("invalid pattern %q: POSIX character classes are not supported by ECMA-262 regular expressions", pattern)
+			}
+			inClass = true
+		case ']':
+			inClass = false
+		case '(':
+			if !inClass && strings.HasPrefix(pattern[i:], "(?") && !strings.HasPrefix(pattern[i:], "(?:") {
+				return fmt.Errorf("invalid pattern %q: flags and named groups are not supported by ECMA-262 regular expressions", pattern)
+			}
+		}
+	}
+	return nil
+}
+
 func (m MaxItems) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
 	if schema.Type != "array" {
 		return fmt.Errorf("must apply maxitem to an array")
@@ -436,8 +555,9 @@ func (m Enum) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
 	// probably support AnyType better + using the schema structure
 	vals := make([]apiext.JSON, len(m))
 	for i, val := range m {
-		// TODO(directxman12): check actual type with schema type?
-		// if we're expecting a string, marshal the string properly...
+		if err := checkValueType(val, schema); err != nil {
+			return fmt.Errorf("invalid enum value %v: %w", val, err)
+		}
 		// NB(directxman12): we use json.Marshal to ensure we handle JSON escaping properly
 		valMarshalled, err := json.Marshal(val)
 		if err != nil {
@@ -490,6 +610,11 @@ func (m Example) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
 	return nil
 }
 
+func (m Description) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	schema.Description = string(m)
+	return nil
+}
+
 func (m XPreserveUnknownFields) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
 	defTrue := true
 	schema.XPreserveUnknownFields = &defTrue
@@ -30,7 +30,7 @@ func (Default) Help() *markers.DefinitionHelp {
 		Category: "CRD validation",
 		DetailedHelp: markers.DetailedHelp{
 			Summary: "sets the default value for this field. ",
-			Details: "A default value will be accepted as any value valid for the field. Formatting for common types include: boolean: `true`, string: `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy: \"delete\"}`). Defaults should be defined in pruned form, and only best-effort validation will be performed. Full validation of a default requires submission of the containing CRD to an apiserver.",
+			Details: "A default value will be accepted as any value valid for the field. Formatting for common types include: boolean: `true`, string: `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy: \"delete\"}`). Defaults should be defined in pruned form. The default is validated against the final schema of the field during generation, but full validation of a default requires submission of the containing CRD to an apiserver.",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{
 			"Value": {
@@ -57,6 +57,17 @@ func (DeprecatedVersion) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (Description) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "overrides the description of this field in the schema. ",
+			Details: "Normally, the description is taken from the field's Go documentation. The override is used as-is, without applying any of the generator's description processing (though it's still subject to maxDescLen).",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{},
+	}
+}
+
 func (Enum) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD validation",
@@ -68,12 +79,23 @@ func (Enum) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (EnumFromConsts) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD validation",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "restricts this type to the values of the constants declared with it. ",
+			Details: "All exported package-level constants of the marked type are collected (in declaration order) and used as the enum, so that the constants are the single source of truth for the allowed values.  Constants sharing a value (like aliases) only contribute it once, and unexported constants are skipped.  It can't be combined with an explicit Enum marker on the same type.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{},
+	}
+}
+
 func (Example) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD validation",
 		DetailedHelp: markers.DetailedHelp{
 			Summary: "sets the example value for this field. ",
-			Details: "An example value will be accepted as any value valid for the field. Formatting for common types include: boolean: `true`, string: `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy: \"delete\"}`). Examples should be defined in pruned form, and only best-effort validation will be performed. Full validation of an example requires submission of the containing CRD to an apiserver.",
+			Details: "An example value will be accepted as any value valid for the field. Formatting for common types include: boolean: `true`, string: `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy: \"delete\"}`). Examples should be defined in pruned form. The example is validated against the final schema of the field during generation, but full validation of an example requires submission of the containing CRD to an apiserver.",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{
 			"Value": {
@@ -284,8 +306,8 @@ func (Pattern) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD validation",
 		DetailedHelp: markers.DetailedHelp{
-			Summary: "specifies that this string must match the given regular expression.",
-			Details: "",
+			Summary: "specifies that this string must match the given regular expression. ",
+			Details: "The expression must use the common subset of Go (RE2) and ECMA-262 regular expression syntax, since it's evaluated by both the API server and clients: Go-only features like `\\A`, `\\z`, `(?i)` flags, named groups and POSIX character classes aren't allowed.",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{},
 	}
@@ -308,8 +330,8 @@ func (PrintColumn) Help() *markers.DefinitionHelp {
 				Details: "It may be any OpenAPI data type listed at https://github.com/OAI/OpenAPI-Specification/blob/master/versions/2.0.md#data-types.",
 			},
 			"JSONPath": {
-				Summary: "specifies the jsonpath expression used to extract the value of the column.",
-				Details: "",
+				Summary: "specifies the jsonpath expression used to extract the value of the column. ",
+				Details: "The path is checked against the schema of the version it's applied to, and must refer to a field whose type matches the column's type.",
 			},
 			"Description": {
 				Summary: "specifies the help/description for this column.",
@@ -407,8 +429,8 @@ func (SubresourceScale) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD",
 		DetailedHelp: markers.DetailedHelp{
-			Summary: "enables the \"/scale\" subresource on a CRD.",
-			Details: "",
+			Summary: "enables the \"/scale\" subresource on a CRD. ",
+			Details: "The replicas paths must refer to integer fields, and the selector path to a string field, in the schema of the version it's applied to.",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{
 			"SpecPath": {
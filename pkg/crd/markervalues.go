@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// boundsError is a conflict between a pair of markers setting the lower
+// and upper bound of the same value.
+type boundsError struct {
+	// markerName is the marker setting the upper bound, which is where
+	// the conflict gets reported.
+	markerName string
+	err        error
+}
+
+// checkBounds checks that the minimums set on the given schema don't
+// exceed the corresponding maximums.
+func checkBounds(props *apiext.JSONSchemaProps) []boundsError {
+	var errs []boundsError
+
+	if props.Minimum != nil && props.Maximum != nil {
+		min, max := *props.Minimum, *props.Maximum
+		switch {
+		case min > max:
+			errs = append(errs, boundsError{
+				markerName: "kubebuilder:validation:Maximum",
+				err:        fmt.Errorf("maximum %v is less than minimum %v", max, min),
+			})
+		case min == max && (props.ExclusiveMinimum || props.ExclusiveMaximum):
+			errs = append(errs, boundsError{
+				markerName: "kubebuilder:validation:Maximum",
+				err:        fmt.Errorf("minimum and maximum are both %v, but at least one of them is exclusive, so no value is valid", max),
+			})
+		}
+	}
+
+	limits := []struct {
+		name     string
+		min, max *int64
+	}{
+		{name: "Length", min: props.MinLength, max: props.MaxLength},
+		{name: "Items", min: props.MinItems, max: props.MaxItems},
+		{name: "Properties", min: props.MinProperties, max: props.MaxProperties},
+	}
+	for _, limit := range limits {
+		if limit.min == nil || limit.max == nil || *limit.min <= *limit.max {
+			continue
+		}
+		errs = append(errs, boundsError{
+			markerName: "kubebuilder:validation:Max" + limit.name,
+			err:        fmt.Errorf("max%s %d is less than min%s %d", limit.name, *limit.max, limit.name, *limit.min),
+		})
+	}
+
+	return errs
+}
+
+// markerValueCheck is a default or example value from a marker, which needs
+// to be validated against the schema of the field it's set on once that's
+// fully known (i.e. flattened).
+type markerValueCheck struct {
+	pkg *loader.Package
+	// node is where problems with the value are reported.
+	node ast.Node
+	// kind is the kind of value (default or example).
+	kind  string
+	value interface{}
+	// schema is the (unflattened) schema that the value applies to.
+	schema apiext.JSONSchemaProps
+}
+
+// needValueChecks queues up checks for any defaults or examples in the given
+// markers, which have been applied to the given schema.
+func (c *schemaContext) needValueChecks(markerSet markers.MarkerValues, props *apiext.JSONSchemaProps, owner ast.Node) {
+	if c.valueChecks == nil {
+		return
+	}
+
+	for markerName, markerValues := range markerSet {
+		for i, markerValue := range markerValues {
+			check := markerValueCheck{
+				pkg:  c.pkg,
+				node: c.markerNode(owner, markerName, i),
+			}
+			switch markerValue := markerValue.(type) {
+			case crdmarkers.Default:
+				check.kind, check.value = "default", markerValue.Value
+			case crdmarkers.Example:
+				check.kind, check.value = "example", markerValue.Value
+			default:
+				continue
+			}
+			check.schema = *props.DeepCopy()
+			*c.valueChecks = append(*c.valueChecks, check)
+		}
+	}
+}
+
+// checkMarkerValues validates any defaults and examples seen so far against
+// the flattened schemata of the fields they're set on.
+func (p *Parser) checkMarkerValues() {
+	// flattening may need new schemata, which may queue up more checks
+	for len(p.valueChecks) > 0 {
+		check := p.valueChecks[0]
+		p.valueChecks = p.valueChecks[1:]
+
+		schema := p.flattener.FlattenSchema(check.schema, check.pkg)
+		schema = FlattenEmbedded(schema, check.pkg)
+		if err := validateMarkerValue(check.kind, check.value, schema); err != nil {
+			check.pkg.AddError(loader.ErrFromNode(err, check.node))
+		}
+	}
+}
+
+// validateMarkerValue validates the given marker value against the given
+// (flattened) schema, the same way the API server validates custom objects.
+func validateMarkerValue(kind string, value interface{}, schema *apiext.JSONSchemaProps) error {
+	// round-trip through JSON, so that we validate what actually ends up in the
+	// CRD (and get numbers in the same form that the API server sees them in)
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", kind, err)
+	}
+	var jsonValue interface{}
+	if err := utiljson.Unmarshal(rawValue, &jsonValue); err != nil {
+		return fmt.Errorf("invalid %s: %w", kind, err)
+	}
+
+	internalSchema := &apiextensions.JSONSchemaProps{}
+	if err := apiext.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(schema, internalSchema, nil); err != nil {
+		return fmt.Errorf("unable to convert schema to check %s: %w", kind, err)
+	}
+	validator, _, err := validation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internalSchema})
+	if err != nil {
+		return fmt.Errorf("unable to construct validator to check %s: %w", kind, err)
+	}
+
+	if errs := validation.ValidateCustomResource(field.NewPath(kind), jsonValue, validator); len(errs) > 0 {
+		return fmt.Errorf("invalid %s %s: %w", kind, rawValue, errs.ToAggregate())
+	}
+	return nil
+}
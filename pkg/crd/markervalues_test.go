@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+)
+
+func Test_MarkerValues_Pattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		err     string
+	}{
+		{pattern: `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`},
+		{pattern: `^(?:\d+)(ms|s|m)$`},
+		{pattern: `^[\[\]()]+$`},
+		{pattern: `^\(?x`},
+		{pattern: `[a-`, err: "missing closing ]"},
+		{pattern: `\Afoo`, err: "`\\A` is not supported"},
+		{pattern: `foo\z`, err: "`\\z` is not supported"},
+		{pattern: `\p{Greek}`, err: "`\\p` is not supported"},
+		{pattern: `(?i)foo`, err: "flags and named groups are not supported"},
+		{pattern: `(?P<name>foo)`, err: "flags and named groups are not supported"},
+		{pattern: `[[:alpha:]]`, err: "POSIX character classes are not supported"},
+	}
+
+	for _, c := range cases {
+		g := gomega.NewWithT(t)
+		err := crdmarkers.Pattern(c.pattern).ApplyToSchema(&apiext.JSONSchemaProps{Type: "string"})
+		if c.err == "" {
+			g.Expect(err).NotTo(gomega.HaveOccurred(), c.pattern)
+			continue
+		}
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(c.err)), c.pattern)
+	}
+}
+
+func Test_MarkerValues_EnumTypes(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(crdmarkers.Enum{"a", "b"}.ApplyToSchema(&apiext.JSONSchemaProps{Type: "string"})).To(gomega.Succeed())
+	g.Expect(crdmarkers.Enum{1, 2.5}.ApplyToSchema(&apiext.JSONSchemaProps{Type: "number"})).To(gomega.Succeed())
+	g.Expect(crdmarkers.Enum{1, 2.0}.ApplyToSchema(&apiext.JSONSchemaProps{Type: "integer"})).To(gomega.Succeed())
+	g.Expect(crdmarkers.Enum{1, "auto"}.ApplyToSchema(&apiext.JSONSchemaProps{XIntOrString: true})).To(gomega.Succeed())
+	g.Expect(crdmarkers.Enum{true, "x"}.ApplyToSchema(&apiext.JSONSchemaProps{})).To(gomega.Succeed())
+
+	g.Expect(crdmarkers.Enum{"a", 1}.ApplyToSchema(&apiext.JSONSchemaProps{Type: "string"})).To(gomega.MatchError("invalid enum value 1: must be of type string, not integer"))
+	g.Expect(crdmarkers.Enum{2.5}.ApplyToSchema(&apiext.JSONSchemaProps{Type: "integer"})).To(gomega.MatchError("invalid enum value 2.5: must be of type integer, not number"))
+	g.Expect(crdmarkers.Enum{true}.ApplyToSchema(&apiext.JSONSchemaProps{XIntOrString: true})).To(gomega.MatchError("invalid enum value true: must be an integer or a string, not boolean"))
+}
+
+func Test_MarkerValues_Bounds(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	one, five := float64(1), float64(5)
+	three, four := int64(3), int64(4)
+
+	g.Expect(checkBounds(&apiext.JSONSchemaProps{Minimum: &one, Maximum: &five, MinLength: &three, MaxLength: &four})).To(gomega.BeEmpty())
+	g.Expect(checkBounds(&apiext.JSONSchemaProps{Minimum: &one, Maximum: &one})).To(gomega.BeEmpty())
+
+	g.Expect(boundsMessages(&apiext.JSONSchemaProps{Minimum: &five, Maximum: &one})).To(gomega.ConsistOf(
+		"kubebuilder:validation:Maximum: maximum 1 is less than minimum 5",
+	))
+	g.Expect(boundsMessages(&apiext.JSONSchemaProps{Minimum: &one, Maximum: &one, ExclusiveMaximum: true})).To(gomega.ConsistOf(
+		"kubebuilder:validation:Maximum: minimum and maximum are both 1, but at least one of them is exclusive, so no value is valid",
+	))
+	g.Expect(boundsMessages(&apiext.JSONSchemaProps{MinItems: &four, MaxItems: &three, MinProperties: &four, MaxProperties: &three})).To(gomega.ConsistOf(
+		"kubebuilder:validation:MaxItems: maxItems 3 is less than minItems 4",
+		"kubebuilder:validation:MaxProperties: maxProperties 3 is less than minProperties 4",
+	))
+}
+
+func Test_MarkerValues_Validate(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	ten := float64(10)
+	schema := &apiext.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]apiext.JSONSchemaProps{
+			"name":     {Type: "string", Pattern: "^[a-z]+$"},
+			"replicas": {Type: "integer", Maximum: &ten},
+			"port":     {XIntOrString: true},
+		},
+	}
+
+	g.Expect(validateMarkerValue("default", map[string]interface{}{"name": "foo", "replicas": 3, "port": "http"}, schema)).To(gomega.Succeed())
+	g.Expect(validateMarkerValue("default", map[string]interface{}{"replicas": 3}, schema)).To(gomega.MatchError(gomega.ContainSubstring("default.name: Required value")))
+	g.Expect(validateMarkerValue("example", map[string]interface{}{"name": "Foo"}, schema)).To(gomega.MatchError(gomega.ContainSubstring("should match '^[a-z]+$'")))
+	g.Expect(validateMarkerValue("default", map[string]interface{}{"name": "foo", "replicas": 11}, schema)).To(gomega.MatchError(gomega.ContainSubstring("less than or equal to 10")))
+	g.Expect(validateMarkerValue("default", map[string]interface{}{"name": "foo", "port": true}, schema)).To(gomega.HaveOccurred())
+}
+
+// boundsMessages runs checkBounds, returning each problem as "marker: message".
+func boundsMessages(props *apiext.JSONSchemaProps) []string {
+	var msgs []string
+	for _, boundsErr := range checkBounds(props) {
+		msgs = append(msgs, boundsErr.markerName+": "+boundsErr.err.Error())
+	}
+	return msgs
+}
@@ -73,6 +73,10 @@ type Parser struct {
 
 	flattener *Flattener
 
+	// valueChecks are defaults and examples waiting to be checked against
+	// their flattened schemata (see checkMarkerValues).
+	valueChecks []markerValueCheck
+
 	// AllowDangerousTypes controls the handling of non-recommended types such as float. If
 	// false (the default), these types are not supported.
 	// There is a continuum here:
@@ -92,6 +96,14 @@ type Parser struct {
 
 	// GenerateEmbeddedObjectMeta specifies if any embedded ObjectMeta should be generated
 	GenerateEmbeddedObjectMeta bool
+
+	// DescriptionRules configures how Go documentation is turned into
+	// schema descriptions.
+	DescriptionRules DescriptionRules
+
+	// Store, if set, persists flattened schemata between runs, so that
+	// they're only computed again for packages that have changed.
+	Store markers.PackageStore
 }
 
 func (p *Parser) init() {
@@ -182,6 +194,9 @@ func (p *Parser) NeedSchemaFor(typ TypeIdent) {
 	p.Schemata[typ] = apiext.JSONSchemaProps{}
 
 	schemaCtx := newSchemaContext(typ.Package, p, p.AllowDangerousTypes, p.IgnoreUnexportedFields)
+	schemaCtx.collector = p.Collector
+	schemaCtx.valueChecks = &p.valueChecks
+	schemaCtx.descriptionRules = p.DescriptionRules
 	ctxForInfo := schemaCtx.ForInfo(info)
 
 	pkgMarkers, err := markers.PackageMarkers(p.Collector, typ.Package)
@@ -202,11 +217,38 @@ func (p *Parser) NeedFlattenedSchemaFor(typ TypeIdent) {
 		return
 	}
 
+	// the schema depends on the type's package, and the packages it imports,
+	// which is exactly what stored values are checked against.
+	storeName := fmt.Sprintf("crd flattened schema %s %+v", typ.Name, p.settings())
+	if p.Store != nil {
+		var stored apiext.JSONSchemaProps
+		if p.Store.Load(typ.Package, storeName, &stored) {
+			p.FlattenedSchemata[typ] = stored
+			return
+		}
+	}
+
 	p.NeedSchemaFor(typ)
 	partialFlattened := p.flattener.FlattenType(typ)
 	fullyFlattened := FlattenEmbedded(partialFlattened, typ.Package)
 
 	p.FlattenedSchemata[typ] = *fullyFlattened
+
+	p.checkMarkerValues()
+
+	if p.Store != nil {
+		p.Store.Save(typ.Package, storeName, *fullyFlattened)
+	}
+}
+
+// settings returns the settings of this parser that affect its schemata.
+func (p *Parser) settings() ParserSettings {
+	return ParserSettings{
+		AllowDangerousTypes:        p.AllowDangerousTypes,
+		IgnoreUnexportedFields:     p.IgnoreUnexportedFields,
+		GenerateEmbeddedObjectMeta: p.GenerateEmbeddedObjectMeta,
+		DescriptionRules:           p.DescriptionRules,
+	}
 }
 
 // NeedCRDFor lives off in spec.go
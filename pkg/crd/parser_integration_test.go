@@ -17,6 +17,7 @@ limitations under the License.
 package crd_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -52,6 +53,28 @@ func packageErrors(pkg *loader.Package, filterKinds ...packages.ErrorKind) error
 	return outErr
 }
 
+// memoryStore is a markers.PackageStore that keeps values in memory,
+// regardless of whether their packages change.
+type memoryStore struct {
+	values map[string][]byte
+	loads  int
+}
+
+func (s *memoryStore) Load(_ *loader.Package, name string, out interface{}) bool {
+	raw, stored := s.values[name]
+	if !stored {
+		return false
+	}
+	s.loads++
+	return json.Unmarshal(raw, out) == nil
+}
+
+func (s *memoryStore) Save(_ *loader.Package, name string, value interface{}) {
+	raw, err := json.Marshal(value)
+	Expect(err).NotTo(HaveOccurred())
+	s.values[name] = raw
+}
+
 var _ = Describe("CRD Generation From Parsing to CustomResourceDefinition", func() {
 	Context("should properly generate and flatten the rewritten schemas", func() {
 
@@ -184,6 +207,64 @@ var _ = Describe("CRD Generation From Parsing to CustomResourceDefinition", func
 				assertError(pkgs[0], "CronJob", "is not in 'xxx=xxx' format")
 			})
 		})
+
+		Context("Widget API with invalid printer column and scale JSONPaths", func() {
+			BeforeEach(func() {
+				pkgPaths = []string{"./wrong_jsonpath"}
+				expPkgLen = 1
+			})
+			It("should report each invalid JSONPath at its marker", func() {
+				By("requesting that the Widget CRD be generated")
+				parser.NeedCRDFor(schema.GroupKind{Kind: "Widget", Group: "testdata.kubebuilder.io"}, nil)
+
+				By("checking that all (and only) the invalid paths were reported")
+				var msgs []string
+				for _, err := range pkgs[0].Errors {
+					if err.Kind == packages.TypeError {
+						continue
+					}
+					msgs = append(msgs, err.Error())
+				}
+				Expect(msgs).To(ConsistOf(
+					And(ContainSubstring("widget_types.go:48"), ContainSubstring(`invalid printer column "Typo"`), ContainSubstring(`refers to unknown field "readyReplica" of ".status"`)),
+					And(ContainSubstring("widget_types.go:49"), ContainSubstring(`invalid printer column "Mistyped"`), ContainSubstring("column type boolean does not match field type integer")),
+					And(ContainSubstring("widget_types.go:50"), ContainSubstring(`invalid printer column "NotAList"`), ContainSubstring("not an array")),
+					And(ContainSubstring("widget_types.go:51"), ContainSubstring(`invalid scale subresource statuspath ".status.selector"`), ContainSubstring("must be of type integer, not string")),
+				))
+			})
+		})
+
+		Context("Gadget API with marker values that don't match their fields", func() {
+			BeforeEach(func() {
+				pkgPaths = []string{"./wrong_marker_values"}
+				expPkgLen = 1
+			})
+			It("should report each invalid value at its marker", func() {
+				By("requesting that the Gadget CRD be generated")
+				parser.NeedCRDFor(schema.GroupKind{Kind: "Gadget", Group: "testdata.kubebuilder.io"}, nil)
+
+				By("checking that all (and only) the invalid values were reported")
+				var msgs []string
+				for _, err := range pkgs[0].Errors {
+					if err.Kind == packages.TypeError {
+						continue
+					}
+					msgs = append(msgs, err.Error())
+				}
+				Expect(msgs).To(ConsistOf(
+					And(ContainSubstring("gadget_types.go:30"), ContainSubstring("maximum 1 is less than minimum 5")),
+					And(ContainSubstring("gadget_types.go:50"), ContainSubstring(`invalid default "Turbo"`), ContainSubstring(`supported values: "Fast", "Slow"`)),
+					And(ContainSubstring("gadget_types.go:54"), ContainSubstring("invalid default 11"), ContainSubstring("less than or equal to 10")),
+					And(ContainSubstring("gadget_types.go:58"), ContainSubstring(`invalid example "abc"`), ContainSubstring("should match '^[0-9]+$'")),
+					And(ContainSubstring("gadget_types.go:61"), ContainSubstring("`\\A` is not supported by ECMA-262")),
+					And(ContainSubstring("gadget_types.go:64"), ContainSubstring("missing closing ]")),
+					And(ContainSubstring("gadget_types.go:67"), ContainSubstring("invalid enum value three: must be of type integer, not string")),
+					And(ContainSubstring("gadget_types.go:71"), ContainSubstring("maxLength 3 is less than minLength 5")),
+					And(ContainSubstring("gadget_types.go:89"), ContainSubstring("no constants of type GadgetShape found")),
+					And(ContainSubstring("gadget_types.go:94"), ContainSubstring("can't have both an explicit enum and an enum from its constants")),
+				))
+			})
+		})
 	})
 
 	It("should generate plural words for Kind correctly", func() {
@@ -232,6 +313,48 @@ var _ = Describe("CRD Generation From Parsing to CustomResourceDefinition", func
 		Expect(parser.CustomResourceDefinitions[groupKind]).To(Equal(crd), "type not as expected, check pkg/crd/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(parser.CustomResourceDefinitions[groupKind], crd))
 	})
 
+	It("should reuse stored flattened schemata", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/plural")).To(Succeed())
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		store := &memoryStore{values: make(map[string][]byte)}
+		groupKind := schema.GroupKind{Kind: "TestQuota", Group: "plural.example.com"}
+		generate := func() apiext.CustomResourceDefinition {
+			By("loading the roots")
+			pkgs, err := loader.LoadRoots(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pkgs).To(HaveLen(1))
+
+			By("setting up the parser")
+			reg := &markers.Registry{}
+			Expect(crdmarkers.Register(reg)).To(Succeed())
+			parser := &crd.Parser{
+				Collector: &markers.Collector{Registry: reg},
+				Checker:   &loader.TypeChecker{},
+				Store:     store,
+			}
+			crd.AddKnownTypes(parser)
+
+			By("requesting that the CRD be generated")
+			parser.NeedPackage(pkgs[0])
+			parser.NeedCRDFor(groupKind, nil)
+			Expect(packageErrors(pkgs[0], packages.TypeError)).NotTo(HaveOccurred())
+			return parser.CustomResourceDefinitions[groupKind]
+		}
+
+		By("generating the CRD for the first time")
+		computed := generate()
+		Expect(store.values).To(HaveLen(1))
+		Expect(store.loads).To(Equal(0))
+
+		By("generating the CRD again")
+		Expect(generate()).To(Equal(computed))
+		Expect(store.loads).To(Equal(1))
+	})
+
 	It("should skip api internal package", func() {
 		By("switching into testdata to appease go modules")
 		cwd, err := os.Getwd()
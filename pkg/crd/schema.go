@@ -20,8 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
+	"sort"
 	"strings"
 
 	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -69,6 +71,15 @@ type schemaContext struct {
 	schemaRequester schemaRequester
 	PackageMarkers  markers.MarkerValues
 
+	// collector, if set, is used to find the marker comments
+	// that problems with marker values are reported at.
+	collector *markers.Collector
+	// valueChecks, if set, collects default and example values
+	// that need to be checked once the final schema is known.
+	valueChecks *[]markerValueCheck
+	// descriptionRules configures how docs are turned into descriptions.
+	descriptionRules DescriptionRules
+
 	allowDangerousTypes    bool
 	ignoreUnexportedFields bool
 }
@@ -92,6 +103,9 @@ func (c *schemaContext) ForInfo(info *markers.TypeInfo) *schemaContext {
 		pkg:                    c.pkg,
 		info:                   info,
 		schemaRequester:        c.schemaRequester,
+		collector:              c.collector,
+		valueChecks:            c.valueChecks,
+		descriptionRules:       c.descriptionRules,
 		allowDangerousTypes:    c.allowDangerousTypes,
 		ignoreUnexportedFields: c.ignoreUnexportedFields,
 	}
@@ -110,13 +124,35 @@ func (c *schemaContext) requestSchema(pkgPath, typeName string) {
 	})
 }
 
+// markerNode returns the node to report problems with the i-th value of the
+// given marker at -- the marker comment itself if we can find it, otherwise
+// the node (type spec or field) that the marker is attached to.
+func (c *schemaContext) markerNode(owner ast.Node, markerName string, i int) ast.Node {
+	if c.collector == nil {
+		return owner
+	}
+	if comments := c.collector.MarkerComments(c.pkg, owner, markerName); i < len(comments) {
+		return comments[i]
+	}
+	return owner
+}
+
+// describe produces the description of a type or field from its doc (and the
+// raw lines that it was joined from), applying any description rules.
+func (c *schemaContext) describe(doc string, docLines []string) string {
+	if c.descriptionRules == (DescriptionRules{}) {
+		return doc
+	}
+	return c.descriptionRules.Describe(docLines)
+}
+
 // infoToSchema creates a schema for the type in the given set of type information.
 func infoToSchema(ctx *schemaContext) *apiext.JSONSchemaProps {
 	// If the obj implements a JSON marshaler and has a marker, use the markers value and do not traverse as
 	// the marshaler could be doing anything. If there is no marker, fall back to traversing.
 	if obj := ctx.pkg.Types.Scope().Lookup(ctx.info.Name); obj != nil && implementsJSONMarshaler(obj.Type()) {
 		schema := &apiext.JSONSchemaProps{}
-		applyMarkers(ctx, ctx.info.Markers, schema, ctx.info.RawSpec.Type)
+		applyMarkers(ctx, ctx.info.Markers, schema, ctx.info.RawSpec)
 		if schema.Type != "" {
 			return schema
 		}
@@ -125,10 +161,12 @@ func infoToSchema(ctx *schemaContext) *apiext.JSONSchemaProps {
 }
 
 // applyMarkers applies schema markers to the given schema, respecting "apply first" markers.
+// Problems are reported at the offending marker, falling back to the given node (the
+// type spec or field that the markers are attached to).
 func applyMarkers(ctx *schemaContext, markerSet markers.MarkerValues, props *apiext.JSONSchemaProps, node ast.Node) {
 	// apply "apply first" markers first...
-	for _, markerValues := range markerSet {
-		for _, markerValue := range markerValues {
+	for markerName, markerValues := range markerSet {
+		for i, markerValue := range markerValues {
 			if _, isApplyFirst := markerValue.(applyFirstMarker); !isApplyFirst {
 				continue
 			}
@@ -139,14 +177,14 @@ func applyMarkers(ctx *schemaContext, markerSet markers.MarkerValues, props *api
 			}
 
 			if err := schemaMarker.ApplyToSchema(props); err != nil {
-				ctx.pkg.AddError(loader.ErrFromNode(err /* an okay guess */, node))
+				ctx.pkg.AddError(loader.ErrFromNode(err, ctx.markerNode(node, markerName, i)))
 			}
 		}
 	}
 
 	// ...then the rest of the markers
-	for _, markerValues := range markerSet {
-		for _, markerValue := range markerValues {
+	for markerName, markerValues := range markerSet {
+		for i, markerValue := range markerValues {
 			if _, isApplyFirst := markerValue.(applyFirstMarker); isApplyFirst {
 				// skip apply-first markers, which were already applied
 				continue
@@ -157,10 +195,19 @@ func applyMarkers(ctx *schemaContext, markerSet markers.MarkerValues, props *api
 				continue
 			}
 			if err := schemaMarker.ApplyToSchema(props); err != nil {
-				ctx.pkg.AddError(loader.ErrFromNode(err /* an okay guess */, node))
+				ctx.pkg.AddError(loader.ErrFromNode(err, ctx.markerNode(node, markerName, i)))
 			}
 		}
 	}
+
+	// ...then make sure that they agree with each other...
+	for _, boundsErr := range checkBounds(props) {
+		ctx.pkg.AddError(loader.ErrFromNode(boundsErr.err, ctx.markerNode(node, boundsErr.markerName, 0)))
+	}
+
+	// ...and finally queue up any defaults and examples to be checked once
+	// the full schema is known (we may just have a reference at this point).
+	ctx.needValueChecks(markerSet, props, node)
 }
 
 // typeToSchema creates a schema for the given AST type.
@@ -185,13 +232,97 @@ func typeToSchema(ctx *schemaContext, rawType ast.Expr) *apiext.JSONSchemaProps
 		return &apiext.JSONSchemaProps{}
 	}
 
-	props.Description = ctx.info.Doc
+	props.Description = ctx.describe(ctx.info.Doc, ctx.info.DocLines)
 
-	applyMarkers(ctx, ctx.info.Markers, props, rawType)
+	var markerOwner ast.Node = rawType
+	if ctx.info.RawSpec != nil {
+		markerOwner = ctx.info.RawSpec
+	}
+	applyMarkers(ctx, ctx.info.Markers, props, markerOwner)
+
+	if ctx.info.Markers.Get(crdmarkers.EnumFromConstsName) != nil {
+		applyEnumFromConsts(ctx, props)
+	}
 
 	return props
 }
 
+// applyEnumFromConsts sets the enum of the given schema to the distinct values
+// of the exported package-level constants of the current type, in declaration
+// order.
+func applyEnumFromConsts(ctx *schemaContext, props *apiext.JSONSchemaProps) {
+	markerNode := ctx.markerNode(ctx.info.RawSpec, crdmarkers.EnumFromConstsName, 0)
+	if ctx.info.Markers.Get("kubebuilder:validation:Enum") != nil {
+		ctx.pkg.AddError(loader.ErrFromNode(fmt.Errorf("type %s can't have both an explicit enum and an enum from its constants", ctx.info.Name), markerNode))
+		return
+	}
+
+	typeObj := ctx.pkg.Types.Scope().Lookup(ctx.info.Name)
+	if typeObj == nil {
+		ctx.pkg.AddError(loader.ErrFromNode(fmt.Errorf("unknown type %s", ctx.info.Name), markerNode))
+		return
+	}
+
+	var consts []*types.Const
+	scope := ctx.pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		constObj, isConst := scope.Lookup(name).(*types.Const)
+		// unexported constants are usually sentinels, not allowed values
+		if !isConst || !constObj.Exported() || !types.Identical(constObj.Type(), typeObj.Type()) {
+			continue
+		}
+		consts = append(consts, constObj)
+	}
+	if len(consts) == 0 {
+		ctx.pkg.AddError(loader.ErrFromNode(fmt.Errorf("no constants of type %s found to use as its enum", ctx.info.Name), markerNode))
+		return
+	}
+	sort.Slice(consts, func(i, j int) bool {
+		return consts[i].Pos() < consts[j].Pos()
+	})
+
+	var vals crdmarkers.Enum
+	seen := make(map[interface{}]struct{}, len(consts))
+	for _, constObj := range consts {
+		val, err := constantToValue(constObj.Val())
+		if err != nil {
+			ctx.pkg.AddError(loader.ErrFromNode(fmt.Errorf("unable to use constant %s as an enum value: %w", constObj.Name(), err), markerNode))
+			return
+		}
+		// aliases (like a default) share the value of an earlier constant
+		if _, isDup := seen[val]; isDup {
+			continue
+		}
+		seen[val] = struct{}{}
+		vals = append(vals, val)
+	}
+	if err := vals.ApplyToSchema(props); err != nil {
+		ctx.pkg.AddError(loader.ErrFromNode(err, markerNode))
+	}
+}
+
+// constantToValue converts a typed constant's value into the equivalent
+// Go value, as if it had been written in a marker.
+func constantToValue(val constant.Value) (interface{}, error) {
+	switch val.Kind() {
+	case constant.String:
+		return constant.StringVal(val), nil
+	case constant.Bool:
+		return constant.BoolVal(val), nil
+	case constant.Int:
+		intVal, exact := constant.Int64Val(val)
+		if !exact {
+			return nil, fmt.Errorf("%s does not fit in an int64", val)
+		}
+		return intVal, nil
+	case constant.Float:
+		floatVal, _ := constant.Float64Val(val)
+		return floatVal, nil
+	default:
+		return nil, fmt.Errorf("unsupported constant %s", val)
+	}
+}
+
 // qualifiedName constructs a JSONSchema-safe qualified name for a type
 // (`<typeName>` or `<safePkgPath>~0<typeName>`, where `<safePkgPath>`
 // is the package path with `/` replaced by `~1`, according to JSONPointer
@@ -400,7 +531,7 @@ func structToSchema(ctx *schemaContext, structType *ast.StructType) *apiext.JSON
 		} else {
 			propSchema = typeToSchema(ctx.ForInfo(&markers.TypeInfo{}), field.RawField.Type)
 		}
-		propSchema.Description = field.Doc
+		propSchema.Description = ctx.describe(field.Doc, field.DocLines)
 
 		applyMarkers(ctx, field.Markers, propSchema, field.RawField)
 
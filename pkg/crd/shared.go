@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+// ParserSettings are the settings of a Parser that affect the schemata it
+// produces.  Generators whose parsers have the same settings can share them.
+type ParserSettings struct {
+	// AllowDangerousTypes is Parser.AllowDangerousTypes.
+	AllowDangerousTypes bool
+	// IgnoreUnexportedFields is Parser.IgnoreUnexportedFields.
+	IgnoreUnexportedFields bool
+	// GenerateEmbeddedObjectMeta is Parser.GenerateEmbeddedObjectMeta.
+	GenerateEmbeddedObjectMeta bool
+	// DescriptionRules is Parser.DescriptionRules.
+	DescriptionRules DescriptionRules
+}
+
+// SharedParser is a Parser that's shared between the generators in a run.
+// Since generators may run in parallel, it must be locked while in use, but
+// only then, so that generators sharing it aren't run one after another.
+type SharedParser struct {
+	*Parser
+	sync.Mutex
+
+	// KubeKinds are the kinds defined in the roots (see FindKubeKinds).
+	// They're found before any other generator uses the parser, since
+	// that may load types from other packages as well.
+	KubeKinds []schema.GroupKind
+}
+
+// sharedParserKey is the genall.Cache key for shared parsers.
+type sharedParserKey struct {
+	settings ParserSettings
+}
+
+// SharedParserFor returns a Parser with the given settings that has loaded all
+// the roots of the given context (with the known types added).
+//
+// Generators in the same run asking for the same settings get the same parser
+// (see genall.Cache), so types are only parsed, and schemata only flattened,
+// once.  Callers must hold the lock on the returned parser while using it, and
+// must copy anything they get out of its caches before releasing the lock
+// (rather than modifying it, or using it afterwards).
+func SharedParserFor(ctx *genall.GenerationContext, settings ParserSettings) *SharedParser {
+	return ctx.Cache.Get(sharedParserKey{settings: settings}, func() interface{} {
+		parser := &Parser{
+			Collector: ctx.Collector,
+			Checker:   ctx.Checker,
+
+			AllowDangerousTypes:        settings.AllowDangerousTypes,
+			IgnoreUnexportedFields:     settings.IgnoreUnexportedFields,
+			GenerateEmbeddedObjectMeta: settings.GenerateEmbeddedObjectMeta,
+			DescriptionRules:           settings.DescriptionRules,
+
+			Store: ctx.Store,
+		}
+
+		AddKnownTypes(parser)
+		for _, root := range ctx.Roots {
+			parser.NeedPackage(root)
+		}
+
+		shared := &SharedParser{Parser: parser}
+		if metav1Pkg := FindMetav1(ctx.Roots); metav1Pkg != nil {
+			shared.KubeKinds = FindKubeKinds(parser, metav1Pkg)
+		}
+		return shared
+	}).(*SharedParser)
+}
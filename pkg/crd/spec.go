@@ -114,9 +114,19 @@ func (p *Parser) NeedCRDFor(groupKind schema.GroupKind, maxDescLen *int) {
 			continue
 		}
 		ver := p.GroupVersions[pkg].Version
+		versionSchema := p.FlattenedSchemata[typeIdent]
+
+		for markerName, markerVals := range typeInfo.Markers {
+			for i, val := range markerVals {
+				// make sure columns, scale paths, etc actually point at something
+				if err := checkMarkerJSONPaths(val, &versionSchema); err != nil {
+					var markerNode loader.Node = typeInfo.RawSpec
+					if comments := p.Collector.MarkerComments(pkg, typeInfo.RawSpec, markerName); i < len(comments) {
+						markerNode = comments[i]
+					}
+					pkg.AddError(loader.ErrFromNode(err, markerNode))
+				}
 
-		for _, markerVals := range typeInfo.Markers {
-			for _, val := range markerVals {
 				if specMarker, isSpecMarker := val.(SpecMarker); isSpecMarker {
 					if err := specMarker.ApplyToCRD(&crd.Spec, ver); err != nil {
 						pkg.AddError(loader.ErrFromNode(err /* an okay guess */, typeInfo.RawSpec))
@@ -56,6 +56,15 @@ type CronJobSpec struct {
 	// +optional
 	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
 
+	// This tests that an enum can be derived from the constants of its type.
+	// +optional
+	MissedRunPolicy MissedRunPolicy `json:"missedRunPolicy,omitempty"`
+
+	// This doc is replaced in the schema.
+	// +kubebuilder:description="This tests that the description of a field can be overridden."
+	// +optional
+	OverriddenDescription string `json:"overriddenDescription,omitempty"`
+
 	// This flag tells the controller to suspend subsequent executions, it does
 	// not apply to already started executions.  Defaults to false.
 	// +optional
@@ -116,12 +125,12 @@ type CronJobSpec struct {
 	DefaultedSlice []string `json:"defaultedSlice"`
 
 	// This tests that object defaulting can be performed.
-	// +kubebuilder:default={{nested: {foo: "baz", bar: true}},{nested: {bar: false}}}
-	// +kubebuilder:example={{nested: {foo: "baz", bar: true}},{nested: {bar: false}}}
+	// +kubebuilder:default={{nested: {foo: "baz", bar: true}},{nested: {foo: "qux", bar: false}}}
+	// +kubebuilder:example={{nested: {foo: "baz", bar: true}},{nested: {foo: "qux", bar: false}}}
 	DefaultedObject []RootObject `json:"defaultedObject"`
 
 	// This tests that pattern validator is properly applied.
-	// +kubebuilder:validation:Pattern=`^$|^((https):\/\/?)[^\s()<>]+(?:\([\w\d]+\)|([^[:punct:]\s]|\/?))$`
+	// +kubebuilder:validation:Pattern=`^$|^((https):\/\/?)[^\s()<>]+(?:\([\w\d]+\)|([^!-\/:-@\[-_{-~\s]|\/?))$`
 	PatternObject string `json:"patternObject"`
 
 	// +kubebuilder:validation:EmbeddedResource
@@ -469,6 +478,27 @@ const (
 	ReplaceConcurrent ConcurrencyPolicy = "Replace"
 )
 
+// MissedRunPolicy describes what happens to runs missed while the CronJob was suspended.
+// +kubebuilder:validation:Enum:fromConsts
+type MissedRunPolicy string
+
+const (
+	// SkipMissedRuns forgets about any missed runs.
+	SkipMissedRuns MissedRunPolicy = "Skip"
+
+	// RunOnceForMissedRuns runs the job once, no matter how many runs were missed.
+	RunOnceForMissedRuns MissedRunPolicy = "RunOnce"
+
+	// RunAllMissedRuns runs the job for every missed run.
+	RunAllMissedRuns MissedRunPolicy = "RunAll"
+
+	// DefaultMissedRunPolicy is an alias, so it doesn't add to the enum.
+	DefaultMissedRunPolicy MissedRunPolicy = SkipMissedRuns
+
+	// missedRunPolicyUnset is unexported, so it's left out of the enum.
+	missedRunPolicyUnset MissedRunPolicy = "Unset"
+)
+
 // CronJobStatus defines the observed state of CronJob
 type CronJobStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -0,0 +1,58 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=testdata.kubebuilder.io
+// +versionName=v1
+package wrongjsonpath
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type WidgetSpec struct {
+	Replicas int32 `json:"replicas"`
+
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+type WidgetStatus struct {
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	Selector string `json:"selector,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Widget has printer columns and a scale subresource, some of which are valid
+// and some of which aren't.
+//
+// These columns and paths are all fine:
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type=='Available')].status"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="App",type="string",JSONPath=".spec.selector.matchLabels['app.kubernetes.io/name']"
+//
+// These are not:
+// +kubebuilder:printcolumn:name="Typo",type="integer",JSONPath=".status.readyReplica"
+// +kubebuilder:printcolumn:name="Mistyped",type="boolean",JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="NotAList",type="string",JSONPath=".spec.replicas[0]"
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.selector,selectorpath=.status.selector
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WidgetSpec   `json:"spec"`
+	Status WidgetStatus `json:"status,omitempty"`
+}
@@ -0,0 +1,101 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=testdata.kubebuilder.io
+// +versionName=v1
+package wrongmarkervalues
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GadgetMode is how fast a gadget goes.
+// +kubebuilder:validation:Enum=Fast;Slow
+type GadgetMode string
+
+// Percent has bounds that can't both be met.
+// +kubebuilder:validation:Minimum=5
+// +kubebuilder:validation:Maximum=1
+type Percent int32
+
+type GadgetSpec struct {
+	// These markers are all fine.
+	// +kubebuilder:default=Fast
+	// +kubebuilder:example=Slow
+	Mode GadgetMode `json:"mode,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`^[a-z]+(?:-[a-z]+)*$`
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name,omitempty"`
+
+	// These aren't.
+	// +kubebuilder:default=Turbo
+	OtherMode GadgetMode `json:"otherMode,omitempty"`
+
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=11
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	// +kubebuilder:example=abc
+	Code string `json:"code,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`\Aabc\z`
+	Anchored string `json:"anchored,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`[a-`
+	Broken string `json:"broken,omitempty"`
+
+	// +kubebuilder:validation:Enum=1;2;three
+	Level int32 `json:"level,omitempty"`
+
+	// +kubebuilder:validation:MinLength=5
+	// +kubebuilder:validation:MaxLength=3
+	Label string `json:"label,omitempty"`
+
+	Percent Percent `json:"percent,omitempty"`
+}
+
+// Gadget has markers with values that don't match the schema of their fields.
+type Gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GadgetSpec `json:"spec"`
+
+	Shape GadgetShape `json:"shape,omitempty"`
+	Size  GadgetSize  `json:"size,omitempty"`
+}
+
+// GadgetShape wants an enum from its constants, but doesn't have any.
+// +kubebuilder:validation:Enum:fromConsts
+type GadgetShape string
+
+// GadgetSize has an enum both from its constants and from a marker.
+// +kubebuilder:validation:Enum=S;M;L
+// +kubebuilder:validation:Enum:fromConsts
+type GadgetSize string
+
+const (
+	SmallGadget  GadgetSize = "S"
+	MediumGadget GadgetSize = "M"
+	LargeGadget  GadgetSize = "L"
+)
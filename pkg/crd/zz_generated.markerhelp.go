@@ -45,6 +45,18 @@ func (Generator) Help() *markers.DefinitionHelp {
 				Summary: "specifies the maximum description length for fields in CRD's OpenAPI schema. ",
 				Details: "0 indicates drop the description for all fields completely. n indicates limit the description to at most n characters and truncate the description to closest sentence boundary if it exceeds n characters.",
 			},
+			"DescriptionSeparator": {
+				Summary: "cuts descriptions off at the first line of Go documentation consisting only of this string (quote it on the command line, e.g. descriptionSeparator=\"---\"), so that implementation notes can follow it without ending up in the schema.",
+				Details: "",
+			},
+			"StripDescriptionTODOs": {
+				Summary: "removes lines starting with TODO or FIXME from descriptions. ",
+				Details: "Left unspecified, the default is false.",
+			},
+			"RewriteDescriptionLinks": {
+				Summary: "rewrites Go doc links in descriptions into plain text: `[pkg.Foo]` becomes `pkg.Foo`, and `[text]` with a matching `[text]: URL` definition becomes `text (URL)`. ",
+				Details: "Left unspecified, the default is false.",
+			},
 			"CRDVersions": {
 				Summary: "specifies the target API versions of the CRD type itself to generate. Defaults to v1. ",
 				Details: "Currently, the only supported value is v1. \n The first version listed will be assumed to be the \"default\" version and will not get a version suffix in the output filename. \n You'll need to use \"v1\" to get support for features like defaulting, along with an API server that supports it (Kubernetes 1.16+).",
@@ -61,6 +73,22 @@ func (Generator) Help() *markers.DefinitionHelp {
 				Summary: "specifies the year to substitute for \" YEAR\" in the header file.",
 				Details: "",
 			},
+			"FileNameTemplate": {
+				Summary: "specifies a Go template for the path of each generated file, relative to the output directory.  Since templates contain braces, quote the value on the command line, e.g. fileNameTemplate=\"{{.Group}}/{{.Plural}}.yaml\". ",
+				Details: "The template may refer to .Group, .Kind, .Plural, .Singular and .CRDVersion. Left unspecified, files are named \"<group>_<plural>.yaml\", with a \".<crdVersion>\" suffix for every CRD version after the first.",
+			},
+			"Kustomization": {
+				Summary: "indicates that a kustomization.yaml listing every generated file should be written as well. ",
+				Details: "Left unspecified, the default is false.",
+			},
+			"SizeBudget": {
+				Summary: "specifies the maximum size, in bytes, of each generated CRD when serialized as JSON (which is how kubectl stores it in the last-applied-configuration annotation, limited to 262144 bytes). ",
+				Details: "Descriptions are removed from CRDs over budget one subtree at a time until they fit, starting with the deepest (and then largest) subtrees generated from types outside of the input packages, like an embedded PodTemplateSpec. Unlike maxDescLen, CRDs under budget keep all their descriptions.",
+			},
+			"SizeReport": {
+				Summary: "specifies a file (relative to the output) to write size statistics for each generated CRD to, including which subtrees had their descriptions removed to fit the size budget.",
+				Details: "",
+			},
 		},
 	}
 }
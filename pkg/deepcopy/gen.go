@@ -66,6 +66,10 @@ func (Generator) CheckFilter() loader.NodeFilter {
 	}
 }
 
+func (Generator) CacheScope() genall.CacheScope {
+	return genall.CachePerPackage
+}
+
 func (Generator) RegisterMarkers(into *markers.Registry) error {
 	if err := markers.RegisterAll(into,
 		enablePkgMarker, legacyEnablePkgMarker, enableTypeMarker,
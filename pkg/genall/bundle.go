@@ -0,0 +1,254 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// Artifact is a single generated file, as passed to a BundleOutputRule.
+type Artifact struct {
+	// Package is the package the artifact is associated with, if any.
+	Package *loader.Package
+	// ItemPath is the path the generator opened the artifact with.
+	ItemPath string
+	// Contents are the contents of the artifact.
+	Contents []byte
+}
+
+// Path returns the path of the artifact within a bundle.  Package-associated
+// artifacts are nested under their package's import path.
+func (a Artifact) Path() string {
+	if a.Package == nil {
+		return filepath.ToSlash(a.ItemPath)
+	}
+	return path.Join(a.Package.PkgPath, filepath.ToSlash(a.ItemPath))
+}
+
+// BundleOutputRule is an OutputRule that combines artifacts into a single
+// output, instead of writing them out one at a time.
+//
+// Runtime.Run collects the artifacts from every generator using the same
+// rule (so rules must be comparable), and passes them to WriteBundle once
+// all generators have finished, sorted by path.
+type BundleOutputRule interface {
+	OutputRule
+	// WriteBundle writes out the given artifacts.
+	WriteBundle(artifacts []Artifact) error
+}
+
+// bundleArtifact collects a single artifact, writing it out as a bundle of
+// one when closed.  It's used when a BundleOutputRule is opened directly.
+type bundleArtifact struct {
+	bytes.Buffer
+	rule     BundleOutputRule
+	pkg      *loader.Package
+	itemPath string
+}
+
+func (a *bundleArtifact) Close() error {
+	return a.rule.WriteBundle([]Artifact{{Package: a.pkg, ItemPath: a.itemPath, Contents: a.Bytes()}})
+}
+
+// sortArtifacts sorts the given artifacts by path, keeping the generation
+// order of artifacts with the same path, and failing if paths are repeated.
+func sortArtifacts(artifacts []Artifact) error {
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].Path() < artifacts[j].Path()
+	})
+	for i := 1; i < len(artifacts); i++ {
+		if artifacts[i].Path() == artifacts[i-1].Path() {
+			return fmt.Errorf("multiple artifacts would be bundled as %s", artifacts[i].Path())
+		}
+	}
+	return nil
+}
+
+// createBundle opens the given path for writing, creating its parent
+// directory if needed.  An empty path means standard out.
+func createBundle(outPath string) (io.WriteCloser, error) {
+	if outPath == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return os.Create(outPath)
+}
+
+// writeBundle writes out a bundle to the given path using the given
+// function, making sure errors from closing the file are reported.
+func writeBundle(outPath string, write func(io.Writer) error) error {
+	out, err := createBundle(outPath)
+	if err != nil {
+		return err
+	}
+	writeErr := write(out)
+	if err := out.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return writeErr
+}
+
+// +controllertools:marker:generateHelp:category=""
+
+// OutputBundle outputs all YAML artifacts as a single multi-document YAML
+// file, ordered by path.
+//
+// Kustomization files are left out of the bundle, and non-YAML artifacts (like
+// generated code) cause an error.
+type OutputBundle struct {
+	// Path is the file to write the bundle to (defaults to standard out).
+	Path string `marker:",optional"`
+}
+
+func (o OutputBundle) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	return &bundleArtifact{rule: o, pkg: pkg, itemPath: itemPath}, nil
+}
+
+func (o OutputBundle) WriteBundle(artifacts []Artifact) error {
+	if err := sortArtifacts(artifacts); err != nil {
+		return err
+	}
+	var docs bytes.Buffer
+	for _, artifact := range artifacts {
+		switch ext := path.Ext(artifact.ItemPath); {
+		case path.Base(artifact.ItemPath) == KustomizationFileName:
+			continue
+		case ext != ".yaml" && ext != ".yml":
+			return fmt.Errorf("cannot add non-YAML artifact %s to a YAML bundle", artifact.Path())
+		}
+
+		if !startsWithDocument(artifact.Contents) {
+			docs.WriteString("---\n")
+		}
+		docs.Write(artifact.Contents)
+		if len(artifact.Contents) > 0 && !bytes.HasSuffix(artifact.Contents, []byte("\n")) {
+			docs.WriteString("\n")
+		}
+	}
+	return writeBundle(o.Path, func(out io.Writer) error {
+		_, err := out.Write(docs.Bytes())
+		return err
+	})
+}
+
+// startsWithDocument checks if the given YAML starts with a document
+// separator, ignoring any leading comments (like headers).
+func startsWithDocument(contents []byte) bool {
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "---")
+	}
+	return false
+}
+
+// +controllertools:marker:generateHelp:category=""
+
+// OutputArchive outputs all artifacts as a gzipped tarball, ordered by path.
+//
+// Package-associated artifacts (like generated code) are nested under their
+// package's import path.  Timestamps and ownership are left unset, so the
+// same artifacts always produce the same archive.
+type OutputArchive struct {
+	// Path is the .tar.gz file to write.
+	Path string
+}
+
+func (o OutputArchive) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	return &bundleArtifact{rule: o, pkg: pkg, itemPath: itemPath}, nil
+}
+
+func (o OutputArchive) WriteBundle(artifacts []Artifact) error {
+	if err := sortArtifacts(artifacts); err != nil {
+		return err
+	}
+	return writeBundle(o.Path, func(out io.Writer) error {
+		zipped := gzip.NewWriter(out)
+		archive := tar.NewWriter(zipped)
+		for _, artifact := range artifacts {
+			if err := archive.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     artifact.Path(),
+				Mode:     0644,
+				Size:     int64(len(artifact.Contents)),
+				Format:   tar.FormatPAX,
+			}); err != nil {
+				return err
+			}
+			if _, err := archive.Write(artifact.Contents); err != nil {
+				return err
+			}
+		}
+		if err := archive.Close(); err != nil {
+			return err
+		}
+		return zipped.Close()
+	})
+}
+
+// +controllertools:marker:generateHelp:category=""
+
+// OutputJSONStream outputs all artifacts as a stream of JSON records, one per
+// line and ordered by path, for consumption by other tools.
+//
+// Each record has a "path" field (with package-associated artifacts nested
+// under their package's import path) and a "contents" field.
+type OutputJSONStream struct {
+	// Path is the file to write the stream to (defaults to standard out).
+	Path string `marker:",optional"`
+}
+
+// jsonStreamRecord is a single record written by OutputJSONStream.
+type jsonStreamRecord struct {
+	Path     string `json:"path"`
+	Contents string `json:"contents"`
+}
+
+func (o OutputJSONStream) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	return &bundleArtifact{rule: o, pkg: pkg, itemPath: itemPath}, nil
+}
+
+func (o OutputJSONStream) WriteBundle(artifacts []Artifact) error {
+	if err := sortArtifacts(artifacts); err != nil {
+		return err
+	}
+	return writeBundle(o.Path, func(out io.Writer) error {
+		enc := json.NewEncoder(out)
+		for _, artifact := range artifacts {
+			if err := enc.Encode(jsonStreamRecord{Path: artifact.Path(), Contents: string(artifact.Contents)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// bundleTestRuntime produces a runtime whose generators write artifacts in
+// the opposite of path order.
+func bundleTestRuntime(rule OutputRule) (*Runtime, *bytes.Buffer) {
+	var second, first Generator = filesGenerator{"c.yaml": "c"}, filesGenerator{"a.yaml": "a", "b.yaml": "b"}
+	errs := &bytes.Buffer{}
+	return &Runtime{
+		Generators:  Generators{&second, &first},
+		OutputRules: OutputRules{Default: rule},
+		ErrorWriter: errs,
+	}, errs
+}
+
+// packageFileGenerator writes a single package-associated artifact.
+type packageFileGenerator struct {
+	pkg *loader.Package
+}
+
+func (g packageFileGenerator) RegisterMarkers(*markers.Registry) error { return nil }
+
+func (g packageFileGenerator) Generate(ctx *GenerationContext) error {
+	out, err := ctx.Open(g.pkg, "zz_generated.go")
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = out.Write([]byte("package v1\n"))
+	return err
+}
+
+func TestOutputBundle(t *testing.T) {
+	g := gomega.NewWithT(t)
+	out := filepath.Join(t.TempDir(), "dist", "bundle.yaml")
+
+	rt, errs := bundleTestRuntime(OutputBundle{Path: out})
+	g.Expect(rt.Run()).To(gomega.BeFalse(), errs.String())
+	contents, err := os.ReadFile(out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(contents)).To(gomega.Equal("---\nvalue: a\n---\nvalue: b\n---\nvalue: c\n"))
+
+	// header comments and missing separators are dealt with, and
+	// kustomizations are left out
+	g.Expect(OutputBundle{Path: out}.WriteBundle([]Artifact{
+		{ItemPath: "x.yaml", Contents: []byte("# header\n---\nx: 1\n")},
+		{ItemPath: "y.yml", Contents: []byte("y: 2")},
+		{ItemPath: KustomizationFileName, Contents: []byte("resources: []\n")},
+	})).To(gomega.Succeed())
+	contents, err = os.ReadFile(out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(contents)).To(gomega.Equal("# header\n---\nx: 1\n---\ny: 2\n"))
+
+	g.Expect(OutputBundle{Path: out}.WriteBundle([]Artifact{{ItemPath: "zz_generated.go"}})).
+		To(gomega.MatchError(gomega.ContainSubstring("non-YAML artifact zz_generated.go")))
+	g.Expect(OutputBundle{Path: out}.WriteBundle([]Artifact{{ItemPath: "a.yaml"}, {ItemPath: "a.yaml"}})).
+		To(gomega.MatchError(gomega.ContainSubstring("multiple artifacts would be bundled as a.yaml")))
+}
+
+func TestOutputArchive(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+
+	pkg := &loader.Package{Package: &packages.Package{PkgPath: "example.com/api/v1"}}
+	archive := func(name string) []byte {
+		out := filepath.Join(dir, name)
+		rt, errs := bundleTestRuntime(OutputArchive{Path: out})
+		var code Generator = packageFileGenerator{pkg: pkg}
+		rt.Generators = append(rt.Generators, &code)
+		g.Expect(rt.Run()).To(gomega.BeFalse(), errs.String())
+		contents, err := os.ReadFile(out)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return contents
+	}
+
+	first := archive("first.tar.gz")
+	g.Expect(archive("second.tar.gz")).To(gomega.Equal(first), "archives should be reproducible")
+
+	zipped, err := gzip.NewReader(bytes.NewReader(first))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	files := tar.NewReader(zipped)
+	var names, contents []string
+	for {
+		header, err := files.Next()
+		if err == io.EOF {
+			break
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		body, err := io.ReadAll(files)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		names = append(names, header.Name)
+		contents = append(contents, string(body))
+	}
+	g.Expect(names).To(gomega.Equal([]string{"a.yaml", "b.yaml", "c.yaml", "example.com/api/v1/zz_generated.go"}))
+	g.Expect(contents).To(gomega.Equal([]string{"---\nvalue: a\n", "---\nvalue: b\n", "---\nvalue: c\n", "package v1\n"}))
+}
+
+func TestOutputJSONStream(t *testing.T) {
+	g := gomega.NewWithT(t)
+	out := filepath.Join(t.TempDir(), "stream.json")
+
+	rt, errs := bundleTestRuntime(OutputJSONStream{Path: out})
+	g.Expect(rt.Run()).To(gomega.BeFalse(), errs.String())
+	contents, err := os.ReadFile(out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	var records []jsonStreamRecord
+	for dec.More() {
+		var record jsonStreamRecord
+		g.Expect(dec.Decode(&record)).To(gomega.Succeed())
+		records = append(records, record)
+	}
+	g.Expect(records).To(gomega.Equal([]jsonStreamRecord{
+		{Path: "a.yaml", Contents: "---\nvalue: a\n"},
+		{Path: "b.yaml", Contents: "---\nvalue: b\n"},
+		{Path: "c.yaml", Contents: "---\nvalue: c\n"},
+	}))
+
+	// opening the rule directly writes a stream of one
+	writer, err := OutputJSONStream{Path: out}.Open(nil, "d.yaml")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = writer.Write([]byte("d"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(writer.Close()).To(gomega.Succeed())
+	contents, err = os.ReadFile(out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(contents)).To(gomega.Equal(`{"path":"d.yaml","contents":"d"}` + "\n"))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"reflect"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// +controllertools:marker:generateHelp:category=""
+
+// CheckMarkers reports markers that look like they're meant for a generator,
+// but aren't valid: ones with a typo in their name (suggesting what was
+// probably meant), and ones used in the wrong place, like a field marker on a
+// type.
+//
+// Only markers that share a prefix with a known marker (like `kubebuilder:`
+// or `groupName`) are checked.  Markers of any available generator are
+// known, not just ones being run.  Markers belonging to other tools that use
+// the same prefixes, like kubebuilder's scaffolding markers and the
+// Kubernetes code generators' `k8s:` markers, are allowed.
+type CheckMarkers struct {
+	// Allow lists other markers that belong to other tools.
+	//
+	// Each entry allows markers with that name, or whose names start with it
+	// followed by a `:`.
+	Allow []string `marker:",optional"`
+}
+
+// DefaultAllowedMarkers are markers that other tools use with the same
+// prefixes as our markers, which are always allowed by CheckMarkers.
+var DefaultAllowedMarkers = []string{
+	"kubebuilder:scaffold",
+	"kubebuilder:docs-gen",
+	"k8s:openapi-gen",
+	"k8s:conversion-gen",
+	"k8s:conversion-gen-external-types",
+	"k8s:conversion-fn",
+	"k8s:defaulter-gen",
+	"k8s:defaulter-gen-input",
+	"k8s:prerelease-lifecycle-gen",
+}
+
+// AllGeneratorMarkers returns a registry containing the markers of every
+// generator in the given options registry, whether or not it's being run.
+func AllGeneratorMarkers(optionsRegistry *markers.Registry) *markers.Registry {
+	all := &markers.Registry{}
+	genType := reflect.TypeOf((*Generator)(nil)).Elem()
+	for _, def := range optionsRegistry.AllDefinitions() {
+		var gen Generator
+		if factory, isGenerator := generatorFactoryFor(def); isGenerator {
+			gen = factory(reflect.Zero(def.Output).Interface())
+		} else if def.Output.Implements(genType) {
+			gen = reflect.Zero(def.Output).Interface().(Generator)
+		} else {
+			continue
+		}
+		// these are just for reference, so it doesn't matter if some
+		// generator has trouble registering its markers
+		_ = gen.RegisterMarkers(all)
+	}
+	return all
+}
+
+// unknownMarkerCheck returns the check to use for the given options registry,
+// which knows about the markers of every generator in it.
+func (c CheckMarkers) unknownMarkerCheck(optionsRegistry *markers.Registry) *markers.UnknownMarkerCheck {
+	return &markers.UnknownMarkerCheck{
+		Known: AllGeneratorMarkers(optionsRegistry),
+		Allow: append(append([]string(nil), DefaultAllowedMarkers...), c.Allow...),
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// typeMarkerGenerator collects its type-level marker.
+type typeMarkerGenerator struct{}
+
+func (typeMarkerGenerator) RegisterMarkers(into *markers.Registry) error {
+	return into.Register(markers.Must(markers.MakeDefinition("test:first", markers.DescribesType, 0)))
+}
+
+func (typeMarkerGenerator) Generate(ctx *GenerationContext) error {
+	for _, root := range ctx.Roots {
+		if _, err := ctx.Collector.MarkersInPackage(root); err != nil {
+			root.AddError(err)
+		}
+	}
+	return nil
+}
+
+// fieldMarkerGenerator has a field-level marker, but is never run.
+type fieldMarkerGenerator struct{}
+
+func (fieldMarkerGenerator) RegisterMarkers(into *markers.Registry) error {
+	return into.Register(markers.Must(markers.MakeDefinition("test:second", markers.DescribesField, 0)))
+}
+
+func (fieldMarkerGenerator) Generate(*GenerationContext) error { return nil }
+
+func TestCheckMarkers(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "a"), os.ModePerm)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/check\n\ngo 1.19\n"), 0644)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte(`package a
+
+// +test:frist=1
+// +test:second=2
+// +test:third:thing=3
+type A struct {
+	// +test:second=4
+	B int
+}
+`), 0644)).To(gomega.Succeed())
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	optionsRegistry := &markers.Registry{}
+	g.Expect(optionsRegistry.Define("first", markers.DescribesPackage, typeMarkerGenerator{})).To(gomega.Succeed())
+	g.Expect(optionsRegistry.Define("second", markers.DescribesPackage, fieldMarkerGenerator{})).To(gomega.Succeed())
+	g.Expect(RegisterOptionsMarkers(optionsRegistry)).To(gomega.Succeed())
+
+	run := func(opts ...string) []string {
+		rt, err := FromOptions(optionsRegistry, append([]string{"first", "paths=./a"}, opts...))
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		var out bytes.Buffer
+		rt.ErrorWriter = &out
+		rt.DiagnosticsFormat = DiagnosticsJSON
+		rt.Run()
+
+		var report diagnosticsReport
+		g.Expect(json.Unmarshal(out.Bytes(), &report)).To(gomega.Succeed())
+		var msgs []string
+		for _, diag := range report.Diagnostics {
+			msgs = append(msgs, diag.Message)
+		}
+		return msgs
+	}
+
+	// unregistered markers are ignored by default
+	g.Expect(run()).To(gomega.BeEmpty())
+
+	// markers of generators that aren't run are known, just not on types
+	g.Expect(run(`markers:check:allow="test:third"`)).To(gomega.ConsistOf(
+		`unknown marker "test:frist" (did you mean "test:first"?)`,
+		`marker "test:second" can only be used on a field, not a type`,
+	))
+
+	// other tools' markers need to be allowed
+	g.Expect(run("markers:check")).To(gomega.ContainElement(`unknown marker "test:third:thing"`))
+}
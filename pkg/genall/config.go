@@ -0,0 +1,244 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	rawyaml "gopkg.in/yaml.v2"
+)
+
+// configOptions are the options that can be set in a configuration file, or
+// a profile within it.
+type configOptions struct {
+	// Paths are the package roots, as per InputPaths.
+	Paths []string `yaml:"paths,omitempty"`
+	// Generators maps generator names to their arguments.
+	Generators rawyaml.MapSlice `yaml:"generators,omitempty"`
+	// Output maps generator names (or "default") to output rules, either as
+	// the name of a rule, or a map from the name of a rule to its arguments.
+	Output rawyaml.MapSlice `yaml:"output,omitempty"`
+	// Options maps other option names (like "cache:dir") to their arguments.
+	Options rawyaml.MapSlice `yaml:"options,omitempty"`
+}
+
+// configFile is the contents of a configuration file.
+type configFile struct {
+	configOptions `yaml:",inline"`
+	// Profiles are named sets of options, which are merged on top of the
+	// options at the top level of the file when selected.
+	Profiles map[string]configOptions `yaml:"profiles,omitempty"`
+}
+
+// OptionsFromConfig converts the given YAML configuration file into options
+// for FromOptions, using the given profile (if any).
+//
+// The file looks like:
+//
+//	paths: [./apis/...]
+//	generators:
+//	  crd: {maxDescLen: 0}
+//	  rbac: {roleName: manager-role}
+//	  object: {}
+//	output:
+//	  crd: {dir: config/crd/bases}
+//	  default: {artifacts: {config: config}}
+//	options:
+//	  cache:dir: .cache/controller-gen
+//	profiles:
+//	  release:
+//	    generators:
+//	      crd: {maxDescLen: 0, allowDangerousTypes: false}
+//	    options:
+//	      prune: {}
+//
+// Each entry turns into a single option, with maps turning into named
+// arguments (as in `crd:maxDescLen=0`), other values into a single argument
+// (as in `cache:dir=.cache/controller-gen`), and empty values into a bare
+// option (as in `object`).  Entries in a profile replace the corresponding
+// ones at the top level, with false disabling them entirely.  Relative paths
+// are interpreted relative to the working directory, as on the command line.
+func OptionsFromConfig(contents []byte, profile string) ([]string, error) {
+	var config configFile
+	if err := rawyaml.UnmarshalStrict(contents, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse configuration: %w", err)
+	}
+
+	opts := config.configOptions
+	if profile != "" {
+		profileOpts, known := config.Profiles[profile]
+		if !known {
+			known := make([]string, 0, len(config.Profiles))
+			for name := range config.Profiles {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown profile %q (known profiles: %s)", profile, strings.Join(known, ", "))
+		}
+		if profileOpts.Paths != nil {
+			opts.Paths = profileOpts.Paths
+		}
+		opts.Generators = mergeConfigEntries(opts.Generators, profileOpts.Generators)
+		opts.Output = mergeConfigEntries(opts.Output, profileOpts.Output)
+		opts.Options = mergeConfigEntries(opts.Options, profileOpts.Options)
+	}
+
+	var options []string
+	for _, path := range opts.Paths {
+		options = append(options, "paths="+strconv.Quote(path))
+	}
+	for _, entry := range opts.Generators {
+		option, enabled, err := configOption("", entry)
+		if err != nil {
+			return nil, fmt.Errorf("generator %v: %w", entry.Key, err)
+		}
+		if enabled {
+			options = append(options, option)
+		}
+	}
+	for _, entry := range opts.Output {
+		option, enabled, err := configOutputOption(entry)
+		if err != nil {
+			return nil, fmt.Errorf("output for %v: %w", entry.Key, err)
+		}
+		if enabled {
+			options = append(options, option)
+		}
+	}
+	for _, entry := range opts.Options {
+		option, enabled, err := configOption("", entry)
+		if err != nil {
+			return nil, fmt.Errorf("option %v: %w", entry.Key, err)
+		}
+		if enabled {
+			options = append(options, option)
+		}
+	}
+	return options, nil
+}
+
+// mergeConfigEntries replaces entries in base with ones in overrides of the
+// same name, adding any new ones at the end.
+func mergeConfigEntries(base, overrides rawyaml.MapSlice) rawyaml.MapSlice {
+	merged := append(rawyaml.MapSlice(nil), base...)
+	for _, override := range overrides {
+		replaced := false
+		for i, entry := range merged {
+			if entry.Key == override.Key {
+				merged[i] = override
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
+
+// configOutputOption converts an entry in the output section into an option.
+func configOutputOption(entry rawyaml.MapItem) (string, bool, error) {
+	prefix := fmt.Sprintf("output:%v:", entry.Key)
+	if entry.Key == "default" {
+		prefix = "output:"
+	}
+
+	switch rule := entry.Value.(type) {
+	case bool:
+		if rule {
+			return "", false, fmt.Errorf("expected the name of an output rule")
+		}
+		return "", false, nil
+	case string:
+		return prefix + rule, true, nil
+	case rawyaml.MapSlice:
+		if len(rule) != 1 {
+			return "", false, fmt.Errorf("expected exactly one output rule, not %d", len(rule))
+		}
+		return configOption(prefix, rule[0])
+	default:
+		return "", false, fmt.Errorf("expected an output rule, not %v", entry.Value)
+	}
+}
+
+// configOption converts a name and arguments into an option, returning false
+// if it's disabled.
+func configOption(prefix string, entry rawyaml.MapItem) (string, bool, error) {
+	name := prefix + fmt.Sprint(entry.Key)
+	switch args := entry.Value.(type) {
+	case nil:
+		return name, true, nil
+	case bool:
+		return name, args, nil
+	case rawyaml.MapSlice:
+		if len(args) == 0 {
+			return name, true, nil
+		}
+		formatted := make([]string, len(args))
+		for i, arg := range args {
+			val, err := formatConfigValue(arg.Value)
+			if err != nil {
+				return "", false, fmt.Errorf("argument %v: %w", arg.Key, err)
+			}
+			formatted[i] = fmt.Sprintf("%v=%s", arg.Key, val)
+		}
+		return name + ":" + strings.Join(formatted, ","), true, nil
+	default:
+		val, err := formatConfigValue(args)
+		if err != nil {
+			return "", false, err
+		}
+		return name + "=" + val, true, nil
+	}
+}
+
+// formatConfigValue formats a value from a configuration file as a marker
+// argument.
+func formatConfigValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value), nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(value), nil
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			formatted, err := formatConfigValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = formatted
+		}
+		return "{" + strings.Join(items, ",") + "}", nil
+	case rawyaml.MapSlice:
+		items := make([]string, len(value))
+		for i, item := range value {
+			formatted, err := formatConfigValue(item.Value)
+			if err != nil {
+				return "", err
+			}
+			items[i] = fmt.Sprintf("%s: %s", strconv.Quote(fmt.Sprint(item.Key)), formatted)
+		}
+		return "{" + strings.Join(items, ",") + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
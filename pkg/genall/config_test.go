@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// configTestGenerator is a generator with a variety of options.
+type configTestGenerator struct {
+	Name   string            `marker:",optional"`
+	Count  int               `marker:",optional"`
+	Strict *bool             `marker:",optional"`
+	Items  []string          `marker:",optional"`
+	Labels map[string]string `marker:",optional"`
+}
+
+func (configTestGenerator) RegisterMarkers(*markers.Registry) error { return nil }
+func (configTestGenerator) Generate(*GenerationContext) error       { return nil }
+
+const testConfig = `
+paths: [./apis/..., "./other, with comma/..."]
+generators:
+  test:
+    name: 'with "quotes", commas'
+    count: 3
+    items: [a, b]
+    labels: {app: widget}
+  other: {}
+output:
+  test: {dir: out/test}
+  default: {artifacts: {config: config, code: "code dir"}}
+options:
+  cache:dir: .cache
+profiles:
+  release:
+    paths: [./apis/...]
+    generators:
+      test: {strict: true}
+      other: false
+    output:
+      test: stdout
+    options:
+      prune: {dryRun: true}
+`
+
+func TestOptionsFromConfig(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts, err := OptionsFromConfig([]byte(testConfig), "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(opts).To(gomega.Equal([]string{
+		`paths="./apis/..."`,
+		`paths="./other, with comma/..."`,
+		`test:name="with \"quotes\", commas",count=3,items={"a","b"},labels={"app": "widget"}`,
+		`other`,
+		`output:test:dir="out/test"`,
+		`output:artifacts:config="config",code="code dir"`,
+		`cache:dir=".cache"`,
+	}))
+
+	// the options should be parseable by the usual option registry
+	reg := &markers.Registry{}
+	g.Expect(RegisterOptionsMarkers(reg)).To(gomega.Succeed())
+	for _, name := range []string{"test", "other"} {
+		g.Expect(reg.Register(markers.Must(markers.MakeDefinition(name, markers.DescribesPackage, configTestGenerator{})))).To(gomega.Succeed())
+		g.Expect(reg.Register(markers.Must(markers.MakeDefinition("output:"+name+":dir", markers.DescribesPackage, OutputToDirectory(""))))).To(gomega.Succeed())
+		g.Expect(reg.Register(markers.Must(markers.MakeDefinition("output:"+name+":stdout", markers.DescribesPackage, OutputToStdout)))).To(gomega.Succeed())
+	}
+	g.Expect(reg.Register(markers.Must(markers.MakeDefinition("output:artifacts", markers.DescribesPackage, OutputArtifacts{})))).To(gomega.Succeed())
+
+	proto, err := protoFromOptions(reg, opts)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(proto.Paths).To(gomega.Equal([]string{"./apis/...", "./other, with comma/..."}))
+	g.Expect(proto.Generators).To(gomega.HaveLen(2))
+	g.Expect(*proto.Generators[0]).To(gomega.Equal(configTestGenerator{
+		Name:   `with "quotes", commas`,
+		Count:  3,
+		Items:  []string{"a", "b"},
+		Labels: map[string]string{"app": "widget"},
+	}))
+	g.Expect(proto.OutputRules.ByGenerator[proto.Generators[0]]).To(gomega.Equal(OutputToDirectory("out/test")))
+	g.Expect(proto.OutputRules.Default).To(gomega.Equal(OutputArtifacts{Config: "config", Code: "code dir"}))
+	g.Expect(proto.CacheDir).To(gomega.Equal(".cache"))
+
+	// profiles replace entries from the top level
+	opts, err = OptionsFromConfig([]byte(testConfig), "release")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(opts).To(gomega.Equal([]string{
+		`paths="./apis/..."`,
+		`test:strict=true`,
+		`output:test:stdout`,
+		`output:artifacts:config="config",code="code dir"`,
+		`cache:dir=".cache"`,
+		`prune:dryRun=true`,
+	}))
+	proto, err = protoFromOptions(reg, opts)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(proto.Prune).To(gomega.Equal(&Prune{DryRun: true}))
+
+	_, err = OptionsFromConfig([]byte(testConfig), "dev")
+	g.Expect(err).To(gomega.MatchError(`unknown profile "dev" (known profiles: release)`))
+	_, err = OptionsFromConfig([]byte("generator: {}"), "")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("field generator not found")))
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/version"
+)
+
+// DiagnosticsFormat is the format that a Runtime reports errors and warnings
+// in.
+type DiagnosticsFormat string
+
+const (
+	// DiagnosticsText prints errors and warnings as plain text, as they're
+	// found.
+	DiagnosticsText DiagnosticsFormat = ""
+	// DiagnosticsJSON writes a single JSON object listing every diagnostic
+	// once the run is done.
+	DiagnosticsJSON DiagnosticsFormat = "json"
+	// DiagnosticsSARIF writes a SARIF 2.1.0 log once the run is done, as
+	// understood by code scanning and review tools.
+	DiagnosticsSARIF DiagnosticsFormat = "sarif"
+)
+
+// ParseDiagnosticsFormat parses the name of a diagnostics format ("text",
+// "json" or "sarif").
+func ParseDiagnosticsFormat(name string) (DiagnosticsFormat, error) {
+	switch format := DiagnosticsFormat(name); format {
+	case "text":
+		return DiagnosticsText, nil
+	case DiagnosticsText, DiagnosticsJSON, DiagnosticsSARIF:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown diagnostics format %q (expected text, json or sarif)", name)
+	}
+}
+
+// Severity is how serious a diagnostic is.
+type Severity string
+
+const (
+	// SeverityError diagnostics cause the run to fail.
+	SeverityError Severity = "error"
+	// SeverityWarning diagnostics don't affect the result of the run.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single error or warning reported during a run.
+type Diagnostic struct {
+	// File, Line and Column are where the problem is, if known.
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+
+	Severity Severity `json:"severity"`
+	// Generator is the name of the generator that reported the problem, if
+	// it was reported by one (rather than when loading packages).
+	Generator string `json:"generator,omitempty"`
+	// Marker is the name of the marker the problem is with, if it's
+	// reported at a marker comment.
+	Marker  string `json:"marker,omitempty"`
+	Message string `json:"message"`
+}
+
+// diagnosticsReport is the top-level object written in the JSON format.
+type diagnosticsReport struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// diagnostics reports the errors and warnings from a run, either printing
+// them as they're found, or collecting them to write out as a structured
+// report at the end.
+type diagnostics struct {
+	format DiagnosticsFormat
+	out    io.Writer
+	// registry is used to figure out which marker an error is reported at.
+	registry *markers.Registry
+
+	found []Diagnostic
+	// seen holds the number of errors each package had the last time we
+	// checked, so that each is only reported once.
+	seen map[*packages.Package]int
+	// sources holds the lines of source files read to find marker names.
+	sources map[string][]string
+}
+
+// structured checks if diagnostics are being collected for a structured
+// report, rather than printed as they're found.
+func (d *diagnostics) structured() bool {
+	return d.format != DiagnosticsText
+}
+
+// error reports an error not associated with any particular position.
+func (d *diagnostics) error(generator string, err error) {
+	if !d.structured() {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	d.found = append(d.found, Diagnostic{Severity: SeverityError, Generator: generator, Message: err.Error()})
+}
+
+// warning reports a warning not associated with any particular position.
+func (d *diagnostics) warning(generator, msg string) {
+	if !d.structured() {
+		fmt.Fprintln(d.out, msg)
+		return
+	}
+	d.found = append(d.found, Diagnostic{Severity: SeverityWarning, Generator: generator, Message: msg})
+}
+
+// packageErrors collects the errors added to packages reachable from the
+// given roots since it was last called, returning true if there were any.
+// Errors added by generators (as opposed to ones from listing or parsing
+// packages) are attributed to the given generator.  Like with
+// loader.PrintErrors, type errors are skipped.
+func (d *diagnostics) packageErrors(generator string, roots []*loader.Package) bool {
+	if d.seen == nil {
+		d.seen = make(map[*packages.Package]int)
+	}
+	rawRoots := make([]*packages.Package, len(roots))
+	for i, root := range roots {
+		rawRoots[i] = root.Package
+	}
+
+	var found []Diagnostic
+	packages.Visit(rawRoots, nil, func(pkg *packages.Package) {
+		start := d.seen[pkg]
+		if start > len(pkg.Errors) {
+			start = 0
+		}
+		d.seen[pkg] = len(pkg.Errors)
+		for _, err := range pkg.Errors[start:] {
+			if err.Kind == packages.TypeError {
+				continue
+			}
+			diag := Diagnostic{Severity: SeverityError, Message: err.Msg}
+			// errors not tied to a file are reported at "<package ID>:-"
+			if err.Pos != "-" && !strings.HasSuffix(err.Pos, ":-") {
+				diag.File, diag.Line, diag.Column = loader.ErrorPosition(err)
+			}
+			if err.Kind == packages.UnknownError {
+				diag.Generator = generator
+				diag.Marker = d.markerAt(diag.File, diag.Line, diag.Column)
+			}
+			found = append(found, diag)
+		}
+	})
+	sort.SliceStable(found, func(i, j int) bool {
+		switch {
+		case found[i].File != found[j].File:
+			return found[i].File < found[j].File
+		case found[i].Line != found[j].Line:
+			return found[i].Line < found[j].Line
+		case found[i].Column != found[j].Column:
+			return found[i].Column < found[j].Column
+		default:
+			return found[i].Message < found[j].Message
+		}
+	})
+	for i, diag := range found {
+		// markers that fail to parse are reported each time something asks
+		// for them, so skip the repeats
+		if i > 0 && diag == found[i-1] {
+			continue
+		}
+		d.found = append(d.found, diag)
+	}
+	return len(found) > 0
+}
+
+// markerAt returns the name of the marker whose comment starts at the given
+// position, if there is one.
+func (d *diagnostics) markerAt(file string, line, col int) string {
+	if d.registry == nil || file == "" || line == 0 || col == 0 {
+		return ""
+	}
+	if d.sources == nil {
+		d.sources = make(map[string][]string)
+	}
+	lines, read := d.sources[file]
+	if !read {
+		if contents, err := os.ReadFile(file); err == nil {
+			lines = strings.Split(string(contents), "\n")
+		}
+		d.sources[file] = lines
+	}
+	if line > len(lines) || col > len(lines[line-1]) {
+		return ""
+	}
+
+	text := lines[line-1][col-1:]
+	if !strings.HasPrefix(text, "//") {
+		return ""
+	}
+	text = strings.TrimSpace(text[2:])
+	if !strings.HasPrefix(text, "+") {
+		return ""
+	}
+	for _, target := range []markers.TargetType{markers.DescribesField, markers.DescribesType, markers.DescribesPackage} {
+		if def := d.registry.Lookup(text, target); def != nil {
+			return def.Name
+		}
+	}
+	return ""
+}
+
+// flush writes out the collected diagnostics, if they're being collected.
+func (d *diagnostics) flush() error {
+	var report interface{}
+	switch d.format {
+	case DiagnosticsJSON:
+		report = diagnosticsReport{Diagnostics: append([]Diagnostic{}, d.found...)}
+	case DiagnosticsSARIF:
+		report = sarifFor(d.found)
+	default:
+		return nil
+	}
+	enc := json.NewEncoder(d.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// sarifLog is the subset of the SARIF 2.1.0 log format that we produce.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId,omitempty"`
+	Level      Severity          `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifFor converts the given diagnostics into a SARIF log.  Generators are
+// used as rules, and files under the working directory are reported relative
+// to %SRCROOT%, so that tools can match them up with the files in a
+// repository.
+func sarifFor(diags []Diagnostic) sarifLog {
+	wd, _ := os.Getwd()
+	ruleIDs := make(map[string]struct{})
+	results := make([]sarifResult, 0, len(diags))
+	for _, diag := range diags {
+		result := sarifResult{
+			RuleID:  diag.Generator,
+			Level:   diag.Severity,
+			Message: sarifMessage{Text: diag.Message},
+		}
+		if diag.Generator != "" {
+			ruleIDs[diag.Generator] = struct{}{}
+		}
+		if diag.Marker != "" {
+			result.Properties = map[string]string{"marker": diag.Marker}
+		}
+		if diag.File != "" {
+			loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocationFor(wd, diag.File)}
+			if diag.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: diag.Line, StartColumn: diag.Column}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		results = append(results, result)
+	}
+
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "controller-gen",
+				Version:        version.Version(),
+				InformationURI: "https://sigs.k8s.io/controller-tools",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// sarifArtifactLocationFor returns the location of the given file, relative
+// to %SRCROOT% (the given working directory) if it's inside it, and as an
+// absolute file URI otherwise.
+func sarifArtifactLocationFor(wd, file string) sarifArtifactLocation {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		absFile = file
+	}
+	if rel, err := filepath.Rel(wd, absFile); wd != "" && err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return sarifArtifactLocation{
+			URI:       (&url.URL{Path: filepath.ToSlash(rel)}).String(),
+			URIBaseID: "%SRCROOT%",
+		}
+	}
+	return sarifArtifactLocation{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(absFile)}).String()}
+}
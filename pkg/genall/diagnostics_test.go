@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// markerErrorGenerator reports errors from parsing its marker, and then
+// fails outright.
+type markerErrorGenerator struct{}
+
+func (markerErrorGenerator) RegisterMarkers(into *markers.Registry) error {
+	return into.Register(markers.Must(markers.MakeDefinition("test:value", markers.DescribesType, 0)))
+}
+
+func (markerErrorGenerator) Generate(ctx *GenerationContext) error {
+	for _, root := range ctx.Roots {
+		if _, err := ctx.Collector.MarkersInPackage(root); err != nil {
+			root.AddError(err)
+		}
+	}
+	return errors.New("something else went wrong")
+}
+
+func TestDiagnostics(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "a"), os.ModePerm)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/diags\n\ngo 1.19\n"), 0644)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte("package a\n\n// +test:value=abc\ntype A struct{}\n"), 0644)).To(gomega.Succeed())
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	run := func(format DiagnosticsFormat) string {
+		var gen Generator = markerErrorGenerator{}
+		rt, err := Generators{&gen}.ForRoots("./a")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		var out bytes.Buffer
+		rt.ErrorWriter = &out
+		rt.GeneratorNames = map[*Generator]string{&gen: "test"}
+		rt.DiagnosticsFormat = format
+		g.Expect(rt.Run()).To(gomega.BeTrue())
+		return out.String()
+	}
+
+	// reporting errors as text as they're found
+	g.Expect(run(DiagnosticsText)).To(gomega.Equal("something else went wrong\n"))
+
+	// collecting errors, with their positions, generators and markers, as JSON
+	var report diagnosticsReport
+	g.Expect(json.Unmarshal([]byte(run(DiagnosticsJSON)), &report)).To(gomega.Succeed())
+	g.Expect(report.Diagnostics).To(gomega.HaveLen(2))
+	markerDiag := report.Diagnostics[0]
+	g.Expect(filepath.Base(markerDiag.File)).To(gomega.Equal("a.go"))
+	markerDiag.File = ""
+	g.Expect(markerDiag).To(gomega.Equal(Diagnostic{
+		Line:      3,
+		Column:    1,
+		Severity:  SeverityError,
+		Generator: "test",
+		Marker:    "test:value",
+		Message:   `expected integer, got "abc" (at <input>:1:1)`,
+	}))
+	g.Expect(report.Diagnostics[1]).To(gomega.Equal(Diagnostic{
+		Severity:  SeverityError,
+		Generator: "test",
+		Message:   "something else went wrong",
+	}))
+
+	// writing the same as SARIF, relative to the working directory
+	var log sarifLog
+	g.Expect(json.Unmarshal([]byte(run(DiagnosticsSARIF)), &log)).To(gomega.Succeed())
+	g.Expect(log.Version).To(gomega.Equal("2.1.0"))
+	g.Expect(log.Runs).To(gomega.HaveLen(1))
+	g.Expect(log.Runs[0].Tool.Driver.Rules).To(gomega.Equal([]sarifRule{{ID: "test"}}))
+	g.Expect(log.Runs[0].Results).To(gomega.Equal([]sarifResult{
+		{
+			RuleID:  "test",
+			Level:   SeverityError,
+			Message: sarifMessage{Text: `expected integer, got "abc" (at <input>:1:1)`},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "a/a.go", URIBaseID: "%SRCROOT%"},
+				Region:           &sarifRegion{StartLine: 3, StartColumn: 1},
+			}}},
+			Properties: map[string]string{"marker": "test:value"},
+		},
+		{
+			RuleID:  "test",
+			Level:   SeverityError,
+			Message: sarifMessage{Text: "something else went wrong"},
+		},
+	}))
+}
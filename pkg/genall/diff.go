@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+	// maxDiffEdits bounds the work done to find a minimal diff.  Past that
+	// many changed lines, the changed region is shown as a single replacement.
+	maxDiffEdits = 2000
+)
+
+// diffLine is a single line of an edit script.
+type diffLine struct {
+	// kind is ' ' for unchanged lines, '-' for removed ones, and '+' for
+	// added ones.
+	kind byte
+	text string
+}
+
+// splitLines splits text into lines, without their trailing newlines.
+func splitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes an edit script turning a into b.
+func diffLines(a, b []string) []diffLine {
+	// trim the common prefix and suffix, which is usually most of the file
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var script []diffLine
+	for _, line := range a[:prefix] {
+		script = append(script, diffLine{kind: ' ', text: line})
+	}
+	script = append(script, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		script = append(script, diffLine{kind: ' ', text: line})
+	}
+	return script
+}
+
+// diffMiddle computes an edit script for the part of two files that differs,
+// using Myers' algorithm.
+func diffMiddle(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d] holds v[-d..d] as it was before step d, so that we can walk
+	// back through the edits afterwards.
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// walk backwards from the end, building the script in reverse
+	var script []diffLine
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			script = append(script, diffLine{kind: ' ', text: a[x]})
+		}
+		if x == prevX {
+			y--
+			script = append(script, diffLine{kind: '+', text: b[y]})
+		} else {
+			x--
+			script = append(script, diffLine{kind: '-', text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		script = append(script, diffLine{kind: ' ', text: a[x]})
+	}
+
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
+
+// replaceAll produces an edit script that removes all of a, and then adds
+// all of b.
+func replaceAll(a, b []string) []diffLine {
+	script := make([]diffLine, 0, len(a)+len(b))
+	for _, line := range a {
+		script = append(script, diffLine{kind: '-', text: line})
+	}
+	for _, line := range b {
+		script = append(script, diffLine{kind: '+', text: line})
+	}
+	return script
+}
+
+// unifiedDiff renders the differences between the given old and new contents
+// in unified diff format, returning an empty string if they're the same.
+func unifiedDiff(oldName, newName string, oldText, newText []byte) string {
+	script := diffLines(splitLines(oldText), splitLines(newText))
+
+	out := &strings.Builder{}
+	// oldLine and newLine track the (0-based) position of script[i] in each file
+	oldLine, newLine := 0, 0
+	for i := 0; i < len(script); {
+		if script[i].kind == ' ' {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// found a change -- extend the hunk until we see more than twice
+		// the context of unchanged lines in a row (or the end of the file).
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for unchanged := 0; end < len(script) && unchanged <= 2*diffContext; end++ {
+			if script[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// trim trailing context back down
+		for end > i && script[end-1].kind == ' ' {
+			end--
+		}
+		end += diffContext
+		if end > len(script) {
+			end = len(script)
+		}
+
+		hunkOldStart, hunkNewStart := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		for _, line := range script[start:end] {
+			if line.kind != '+' {
+				oldCount++
+			}
+			if line.kind != '-' {
+				newCount++
+			}
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(hunkOldStart, oldCount), hunkRange(hunkNewStart, newCount))
+		for _, line := range script[start:end] {
+			fmt.Fprintf(out, "%c%s\n", line.kind, line.text)
+		}
+
+		for _, line := range script[i:end] {
+			if line.kind != '+' {
+				oldLine++
+			}
+			if line.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the start (0-based) and length of a hunk as a unified
+// diff range.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// empty ranges refer to the line before the change
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sync"
 
 	"golang.org/x/tools/go/packages"
 	rawyaml "gopkg.in/yaml.v2"
@@ -103,6 +104,27 @@ type Runtime struct {
 	OutputRules OutputRules
 	// ErrorWriter defines where to write error messages.
 	ErrorWriter io.Writer
+	// CacheDir, if set, is a directory in which to cache the output of
+	// Cacheable generators between runs (see CacheDir).
+	CacheDir string
+	// Prune, if set, removes files generated by earlier runs that are no
+	// longer generated (see Prune).
+	Prune *Prune
+	// Paths are the paths the roots were loaded from, which Watch uses to
+	// find new packages.
+	Paths []string
+	// GeneratorNames are the names the generators were specified with
+	// (e.g. "crd"), used to say which generator reported a diagnostic.
+	GeneratorNames map[*Generator]string
+	// DiagnosticsFormat is the format errors and warnings are reported in.
+	// Structured formats are written to ErrorWriter as a single report once
+	// the run is done.
+	DiagnosticsFormat DiagnosticsFormat
+
+	// rootsFor restricts the given generators to running on the given
+	// roots, skipping them entirely if there are none.  It's used by Watch
+	// to regenerate only what's affected by a change.
+	rootsFor map[*Generator][]*loader.Package
 }
 
 // GenerationContext defines the common information needed for each Generator
@@ -119,6 +141,55 @@ type GenerationContext struct {
 	// InputRule describes how to load associated boilerplate artifacts.
 	// It should *not* be used to load source files.
 	InputRule
+	// Cache holds values that are shared between the generators in a run,
+	// like parsed types.  If nil, nothing is shared.
+	Cache *Cache
+	// Store persists values computed from packages between runs, like
+	// schemata (see CacheDir).  If nil, nothing is persisted.
+	Store markers.PackageStore
+}
+
+// Cache holds values computed by one generator that other generators in
+// the same run may reuse, keyed by arbitrary comparable keys.  It's safe
+// for concurrent use.
+//
+// Keys should be of unexported types defined by the package using them,
+// to avoid collisions (like context.Context values).
+type Cache struct {
+	entries map[interface{}]*cacheEntry
+	mu      sync.Mutex
+}
+
+// cacheEntry is a single value in a Cache, computed exactly once.
+type cacheEntry struct {
+	value interface{}
+	once  sync.Once
+}
+
+// Get returns the value for the given key, calling compute to produce it if
+// this is the first time it's been requested.  Concurrent calls for the same
+// key wait for the first one to finish computing the value.  If the cache is
+// nil, compute is always called.
+func (c *Cache) Get(key interface{}, compute func() interface{}) interface{} {
+	if c == nil {
+		return compute()
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[interface{}]*cacheEntry)
+	}
+	entry, exists := c.entries[key]
+	if !exists {
+		entry = &cacheEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.value = compute()
+	})
+	return entry.value
 }
 
 // WriteYAMLOptions implements the Options Pattern for WriteYAML.
@@ -165,6 +236,20 @@ func (g GenerationContext) WriteYAML(itemPath, headerText string, objs []interfa
 	return nil
 }
 
+// KustomizationFileName is the name of the kustomization file written by WriteKustomization.
+const KustomizationFileName = "kustomization.yaml"
+
+// WriteKustomization writes a kustomization.yaml listing the given resources
+// (paths relative to the output location) using the context's OutputRule.
+func (g GenerationContext) WriteKustomization(headerText string, resources []string) error {
+	kustomization := map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  resources,
+	}
+	return g.WriteYAML(KustomizationFileName, headerText, []interface{}{kustomization})
+}
+
 // yamlMarshal is based on sigs.k8s.io/yaml.Marshal, but allows for transforming the final data before writing.
 func yamlMarshal(o interface{}, options ...*WriteYAMLOptions) ([]byte, error) {
 	j, err := json.Marshal(o)
@@ -228,8 +313,10 @@ func (g Generators) ForRoots(rootPaths ...string) (*Runtime, error) {
 			Checker: &loader.TypeChecker{
 				NodeFilters: g.CheckFilters(),
 			},
+			Cache: &Cache{},
 		},
 		OutputRules: OutputRules{Default: OutputToNothing},
+		Paths:       rootPaths,
 	}
 	if err := rt.Generators.RegisterMarkers(rt.Collector.Registry); err != nil {
 		return nil, err
@@ -240,22 +327,91 @@ func (g Generators) ForRoots(rootPaths ...string) (*Runtime, error) {
 // Run runs the Generators in this Runtime against its packages, printing
 // errors (except type errors, which common result from using TypeChecker with
 // filters), returning true if errors were found.
+//
+// Generators are run in parallel.  Their output is held in memory until
+// they've all finished, and then written out (and errors printed) in the
+// order the generators were specified, so the results don't depend on which
+// generator finishes first.  Generators using the same BundleOutputRule have
+// their output combined, and written out after everything else.
+//
+// When using a structured DiagnosticsFormat, generators are run one at a
+// time instead, so that errors they add to packages can be attributed to
+// them.
 func (r *Runtime) Run() bool {
-	// TODO(directxman12): we could make this parallel,
-	// but we'd need to ensure all underlying machinery is threadsafe
-
 	if r.ErrorWriter == nil {
 		r.ErrorWriter = os.Stderr
 	}
+	diags := &diagnostics{format: r.DiagnosticsFormat, out: r.ErrorWriter}
+	if r.Collector != nil {
+		diags.registry = r.Collector.Registry
+	}
+	defer func() {
+		if err := diags.flush(); err != nil {
+			fmt.Fprintln(r.ErrorWriter, err)
+		}
+	}()
 	if len(r.Generators) == 0 {
-		fmt.Fprintln(r.ErrorWriter, "no generators to run")
+		diags.error("", fmt.Errorf("no generators to run"))
 		return true
 	}
-
 	hadErrs := false
-	for _, gen := range r.Generators {
+	if diags.structured() {
+		// errors from loading the roots
+		hadErrs = diags.packageErrors("", r.Roots)
+	}
+
+	var cache *incrementalCache
+	if r.CacheDir != "" {
+		cache = &incrementalCache{dir: r.CacheDir}
+		r.Store = cache
+		if r.Collector != nil {
+			r.Collector.Store = cache
+		}
+	}
+	rootsByID := make(map[string]*loader.Package, len(r.Roots))
+	for _, root := range r.Roots {
+		rootsByID[root.ID] = root
+	}
+
+	outputs := make([]*bufferedOutput, len(r.Generators))
+	plans := make([]*cachedGeneration, len(r.Generators))
+	// plannedRoots are the roots each cached generator was planned for,
+	// which are all the roots unless it's restricted to some of them.
+	plannedRoots := make([][]*loader.Package, len(r.Generators))
+	errs := make([]error, len(r.Generators))
+	var wg sync.WaitGroup
+	for i, gen := range r.Generators {
+		outputs[i] = &bufferedOutput{}
+
 		ctx := r.GenerationContext // make a shallow copy
-		ctx.OutputRule = r.OutputRules.ForGenerator(gen)
+		ctx.OutputRule = outputs[i]
+		if roots, restricted := r.rootsFor[gen]; restricted {
+			if len(roots) == 0 {
+				continue
+			}
+			ctx.Roots = roots
+		}
+
+		if cache != nil {
+			plannedRoots[i] = ctx.Roots
+			reused, roots, plan, err := cache.planGeneration(gen, ctx.Roots, ctx.InputRule)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			if len(roots) == 0 {
+				// nothing changed, so just write out the cached output
+				for _, artifact := range reused {
+					outputs[i].artifacts = append(outputs[i].artifacts, artifact.toBuffered(rootsByID[artifact.Package]))
+				}
+				continue
+			}
+			ctx.Roots = roots
+			if plan != nil {
+				plans[i] = plan
+				ctx.InputRule = plan.input
+			}
+		}
 
 		// don't pass a typechecker to generators that don't provide a filter
 		// to avoid accidents
@@ -263,12 +419,98 @@ func (r *Runtime) Run() bool {
 			ctx.Checker = nil
 		}
 
-		if err := (*gen).Generate(&ctx); err != nil {
-			fmt.Fprintln(r.ErrorWriter, err)
+		if diags.structured() {
+			errs[i] = (*gen).Generate(&ctx)
+			hadErrs = diags.packageErrors(r.GeneratorNames[gen], r.Roots) || hadErrs
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, gen *Generator, ctx GenerationContext) {
+			defer wg.Done()
+			errs[i] = (*gen).Generate(&ctx)
+		}(i, gen, ctx)
+	}
+	wg.Wait()
+
+	toSave := make(map[string]*diskCacheEntry)
+	bundles := make(map[BundleOutputRule][]Artifact)
+	var bundleOrder []BundleOutputRule
+	var prune *pruner
+	if r.Prune != nil {
+		prune = &pruner{Prune: *r.Prune, diags: diags}
+	}
+	for i, gen := range r.Generators {
+		if plans[i] != nil {
+			for key, entry := range cache.finishGeneration(plans[i], plannedRoots[i], outputs[i]) {
+				if errs[i] == nil {
+					toSave[key] = entry
+				}
+			}
+		}
+		rule := r.OutputRules.ForGenerator(gen)
+		// generators restricted to some roots won't have written everything
+		if _, restricted := r.rootsFor[gen]; prune != nil && !restricted {
+			prune.record(r.pruneKey(gen), rule, outputs[i])
+		}
+		if bundleRule, isBundle := rule.(BundleOutputRule); isBundle {
+			if _, seen := bundles[bundleRule]; !seen {
+				bundleOrder = append(bundleOrder, bundleRule)
+			}
+			bundles[bundleRule] = append(bundles[bundleRule], outputs[i].toArtifacts()...)
+		} else {
+			for _, err := range outputs[i].writeTo(rule) {
+				diags.error(r.GeneratorNames[gen], err)
+				hadErrs = true
+			}
+		}
+		if errs[i] != nil {
+			diags.error(r.GeneratorNames[gen], errs[i])
+			hadErrs = true
+		}
+	}
+
+	// bundles combine output from several generators, so they can only be
+	// written once everything else is done.
+	for _, bundleRule := range bundleOrder {
+		if err := bundleRule.WriteBundle(bundles[bundleRule]); err != nil {
+			diags.error("", err)
 			hadErrs = true
 		}
 	}
 
 	// skip TypeErrors -- they're probably just from partial typechecking in crd-gen
-	return loader.PrintErrors(r.Roots, packages.TypeError) || hadErrs
+	if diags.structured() {
+		hadErrs = diags.packageErrors("", r.Roots) || hadErrs
+	} else {
+		hadErrs = loader.PrintErrors(r.Roots, packages.TypeError) || hadErrs
+	}
+
+	// only prune after entirely successful runs, since generators might not
+	// have produced everything they normally would otherwise.
+	if prune != nil && !hadErrs {
+		for _, err := range prune.prune() {
+			diags.error("", err)
+			hadErrs = true
+		}
+	}
+
+	// only cache output from entirely successful runs, since we won't see
+	// any errors (or warnings) again when using it.
+	if !hadErrs {
+		for key, entry := range toSave {
+			if err := cache.store(key, entry); err != nil {
+				diags.error("", err)
+				hadErrs = true
+			}
+		}
+		if cache != nil {
+			if err := cache.storeValues(); err != nil {
+				diags.error("", err)
+				hadErrs = true
+			}
+		}
+	}
+
+	return hadErrs
 }
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// waitingGenerator writes an artifact and optionally fails, once the
+// generator it waits for (if any) has finished.
+type waitingGenerator struct {
+	name    string
+	waitFor <-chan struct{}
+	done    chan<- struct{}
+	fail    bool
+}
+
+func (g waitingGenerator) RegisterMarkers(*markers.Registry) error { return nil }
+
+func (g waitingGenerator) Generate(ctx *GenerationContext) error {
+	if g.done != nil {
+		defer close(g.done)
+	}
+	if g.waitFor != nil {
+		<-g.waitFor
+	}
+	out, err := ctx.Open(nil, g.name+".txt")
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := out.Write([]byte(g.name + "\n")); err != nil {
+		return err
+	}
+	if g.fail {
+		return errors.New(g.name + " failed")
+	}
+	return nil
+}
+
+// recordingOutput records the paths and contents of the artifacts written to it.
+type recordingOutput struct {
+	paths []string
+	buf   bytes.Buffer
+}
+
+func (o *recordingOutput) Open(_ *loader.Package, itemPath string) (io.WriteCloser, error) {
+	o.paths = append(o.paths, itemPath)
+	return nopCloser{&o.buf}, nil
+}
+
+func TestRunParallelOutputOrder(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// make the generators finish in the reverse of the order they're specified in
+	firstDone := make(chan struct{})
+	var first, second, third Generator = waitingGenerator{name: "first", waitFor: firstDone, fail: true},
+		waitingGenerator{name: "second", done: firstDone, fail: true},
+		waitingGenerator{name: "third"}
+	out := &recordingOutput{}
+	var errs bytes.Buffer
+	rt := &Runtime{
+		Generators:  Generators{&first, &second, &third},
+		OutputRules: OutputRules{Default: out},
+		ErrorWriter: &errs,
+	}
+
+	g.Expect(rt.Run()).To(gomega.BeTrue())
+	g.Expect(out.paths).To(gomega.Equal([]string{"first.txt", "second.txt", "third.txt"}))
+	g.Expect(out.buf.String()).To(gomega.Equal("first\nsecond\nthird\n"))
+	g.Expect(errs.String()).To(gomega.Equal("first failed\nsecond failed\n"))
+}
+
+func TestCache(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	type key struct{ name string }
+	calls := 0
+	compute := func() interface{} {
+		calls++
+		return &calls
+	}
+
+	cache := &Cache{}
+	first := cache.Get(key{"a"}, compute)
+	g.Expect(cache.Get(key{"a"}, compute)).To(gomega.BeIdenticalTo(first))
+	g.Expect(calls).To(gomega.Equal(1))
+	cache.Get(key{"b"}, compute)
+	g.Expect(calls).To(gomega.Equal(2))
+
+	// a nil cache doesn't cache anything
+	var nilCache *Cache
+	nilCache.Get(key{"a"}, compute)
+	nilCache.Get(key{"a"}, compute)
+	g.Expect(calls).To(gomega.Equal(4))
+}
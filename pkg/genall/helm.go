@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+const (
+	defaultHelmNamePrefix   = "{{ .Release.Name }}-"
+	defaultHelmNamespace    = "{{ .Release.Namespace }}"
+	defaultHelmService      = "{{ .Release.Name }}-webhook-service"
+	defaultHelmCAInjectFrom = "{{ .Release.Namespace }}/{{ .Release.Name }}-serving-cert"
+
+	// caInjectAnnotation is the annotation cert-manager uses to find the
+	// certificate whose CA should be injected into webhook configurations.
+	caInjectAnnotation = "cert-manager.io/inject-ca-from"
+)
+
+// +controllertools:marker:generateHelp:category=""
+
+// OutputHelmChart outputs artifacts into the layout of a Helm chart.
+//
+// CustomResourceDefinitions are written to the chart's crds directory as-is,
+// while RBAC and webhook configuration objects are written to its templates
+// directory, with their names, namespaces, service references and CA
+// injection annotations replaced by template expressions.  Other objects are
+// written to templates unchanged, and YAML that doesn't describe objects
+// (like kustomizations) is written to the chart's root directory.
+// Package-associated artifacts (like generated code) are written to their
+// package's source files' directory.
+type OutputHelmChart struct {
+	// Dir is the root directory of the chart.
+	Dir string
+	// NamePrefix is prepended to the names of RBAC and webhook objects (defaults to `{{ .Release.Name }}-`).
+	NamePrefix string `marker:",optional"`
+	// Namespace replaces the namespace of namespaced RBAC objects, service account subjects and webhook services (defaults to `{{ .Release.Namespace }}`).
+	Namespace string `marker:",optional"`
+	// Service replaces the name of the service webhooks are served by (defaults to `{{ .Release.Name }}-webhook-service`).
+	Service string `marker:",optional"`
+	// CAInjectFrom is the cert-manager.io/inject-ca-from annotation added to webhook configurations, used to fill in their caBundles (defaults to `{{ .Release.Namespace }}/{{ .Release.Name }}-serving-cert`).
+	CAInjectFrom string `marker:",optional"`
+}
+
+func (o OutputHelmChart) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	if pkg != nil {
+		return OutputArtifacts{Config: OutputToDirectory(o.Dir)}.Open(pkg, itemPath)
+	}
+	return &helmArtifact{rule: o, itemPath: itemPath}, nil
+}
+
+// helmArtifact collects a configuration artifact, sorting its objects into
+// the chart when closed.
+type helmArtifact struct {
+	bytes.Buffer
+	rule     OutputHelmChart
+	itemPath string
+}
+
+func (a *helmArtifact) Close() error {
+	ext := filepath.Ext(a.itemPath)
+	if ext != ".yaml" && ext != ".yml" {
+		return a.write(a.itemPath, a.Bytes())
+	}
+
+	header, objs, err := splitObjects(a.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", a.itemPath, err)
+	}
+	for _, obj := range objs {
+		if kind, _ := obj["kind"].(string); kind == "" || kind == "Kustomization" {
+			return a.write(a.itemPath, a.Bytes())
+		}
+	}
+
+	crds := bytes.NewBufferString(header)
+	templates := bytes.NewBufferString(header)
+	numCRDs := 0
+	for _, obj := range objs {
+		out := templates
+		if obj["kind"] == "CustomResourceDefinition" {
+			out = crds
+			numCRDs++
+		} else {
+			a.rule.templatize(obj)
+		}
+		objYAML, err := yamlMarshal(obj)
+		if err != nil {
+			return err
+		}
+		out.WriteString("---\n")
+		out.Write(objYAML)
+	}
+
+	if numCRDs > 0 {
+		if err := a.write(filepath.Join("crds", a.itemPath), crds.Bytes()); err != nil {
+			return err
+		}
+	}
+	if numCRDs < len(objs) {
+		return a.write(filepath.Join("templates", a.itemPath), templates.Bytes())
+	}
+	return nil
+}
+
+// write writes the given contents to the given path within the chart.
+func (a *helmArtifact) write(itemPath string, contents []byte) error {
+	out, err := OutputToDirectory(a.rule.Dir).Open(nil, itemPath)
+	if err != nil {
+		return err
+	}
+	_, writeErr := out.Write(contents)
+	if err := out.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return writeErr
+}
+
+// splitObjects splits multi-document YAML into objects, returning any
+// leading comments (like headers) separately.
+func splitObjects(contents []byte) (string, []map[string]interface{}, error) {
+	var header strings.Builder
+	for _, line := range strings.SplitAfter(string(contents), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			break
+		}
+		header.WriteString(line)
+	}
+
+	var objs []map[string]interface{}
+	docs := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(contents)))
+	for {
+		doc, err := docs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return "", nil, err
+		}
+		if obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	return header.String(), objs, nil
+}
+
+// templatize replaces the names, namespaces and service references of RBAC
+// and webhook objects with template expressions.
+func (o OutputHelmChart) templatize(obj map[string]interface{}) {
+	namePrefix := valueOrDefault(o.NamePrefix, defaultHelmNamePrefix)
+	namespace := valueOrDefault(o.Namespace, defaultHelmNamespace)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		return
+	}
+
+	switch obj["kind"] {
+	case "ClusterRole", "Role", "ClusterRoleBinding", "RoleBinding", "ServiceAccount":
+		prefixName(metadata, namePrefix)
+		if _, namespaced := metadata["namespace"]; namespaced || obj["kind"] == "ServiceAccount" {
+			metadata["namespace"] = namespace
+		}
+		if roleRef, isMap := obj["roleRef"].(map[string]interface{}); isMap {
+			prefixName(roleRef, namePrefix)
+		}
+		subjects, _ := obj["subjects"].([]interface{})
+		for _, rawSubject := range subjects {
+			subject, isMap := rawSubject.(map[string]interface{})
+			if !isMap || subject["kind"] != "ServiceAccount" {
+				continue
+			}
+			prefixName(subject, namePrefix)
+			subject["namespace"] = namespace
+		}
+	case "MutatingWebhookConfiguration", "ValidatingWebhookConfiguration":
+		prefixName(metadata, namePrefix)
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if annotations == nil {
+			annotations = make(map[string]interface{})
+			metadata["annotations"] = annotations
+		}
+		annotations[caInjectAnnotation] = valueOrDefault(o.CAInjectFrom, defaultHelmCAInjectFrom)
+
+		webhooks, _ := obj["webhooks"].([]interface{})
+		for _, rawWebhook := range webhooks {
+			webhook, _ := rawWebhook.(map[string]interface{})
+			clientConfig, _ := webhook["clientConfig"].(map[string]interface{})
+			service, _ := clientConfig["service"].(map[string]interface{})
+			if service == nil {
+				continue
+			}
+			service["name"] = valueOrDefault(o.Service, defaultHelmService)
+			service["namespace"] = namespace
+		}
+	}
+}
+
+// prefixName prepends the given prefix to the "name" field of the given map,
+// if it has one.
+func prefixName(obj map[string]interface{}, prefix string) {
+	if name, hasName := obj["name"].(string); hasName {
+		obj["name"] = prefix + name
+	}
+}
+
+// valueOrDefault returns the given value, or the given default if it's empty.
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+func writeHelmTestArtifact(g *gomega.WithT, rule OutputHelmChart, itemPath, contents string) {
+	out, err := rule.Open(nil, itemPath)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = out.Write([]byte(contents))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(out.Close()).To(gomega.Succeed())
+}
+
+func readHelmTestFile(g *gomega.WithT, path string) string {
+	contents, err := os.ReadFile(path)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	return string(contents)
+}
+
+func TestOutputHelmChart(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+	rule := OutputHelmChart{Dir: dir}
+
+	writeHelmTestArtifact(g, rule, "role.yaml", `# header
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: manager-role
+rules: []
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: leader-election
+  namespace: system
+rules: []
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: manager-rolebinding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: manager-role
+subjects:
+- kind: ServiceAccount
+  name: controller-manager
+  namespace: system
+`)
+	g.Expect(readHelmTestFile(g, filepath.Join(dir, "templates", "role.yaml"))).To(gomega.Equal(`# header
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: '{{ .Release.Name }}-manager-role'
+rules: []
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: '{{ .Release.Name }}-leader-election'
+  namespace: '{{ .Release.Namespace }}'
+rules: []
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: '{{ .Release.Name }}-manager-rolebinding'
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: '{{ .Release.Name }}-manager-role'
+subjects:
+- kind: ServiceAccount
+  name: '{{ .Release.Name }}-controller-manager'
+  namespace: '{{ .Release.Namespace }}'
+`))
+
+	rule = OutputHelmChart{Dir: dir, NamePrefix: `{{ include "op.fullname" . }}-`, Service: "webhooks", CAInjectFrom: "{{ .Values.caFrom }}"}
+	writeHelmTestArtifact(g, rule, "manifests.yaml", `---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: validating-webhook-configuration
+webhooks:
+- admissionReviewVersions:
+  - v1
+  clientConfig:
+    service:
+      name: webhook-service
+      namespace: system
+      path: /validate
+  name: vwidget.example.com
+`)
+	var webhookConfig map[string]interface{}
+	g.Expect(yaml.Unmarshal([]byte(readHelmTestFile(g, filepath.Join(dir, "templates", "manifests.yaml"))), &webhookConfig)).To(gomega.Succeed())
+	g.Expect(webhookConfig["metadata"]).To(gomega.Equal(map[string]interface{}{
+		"name":        `{{ include "op.fullname" . }}-validating-webhook-configuration`,
+		"annotations": map[string]interface{}{"cert-manager.io/inject-ca-from": "{{ .Values.caFrom }}"},
+	}))
+	g.Expect(webhookConfig["webhooks"]).To(gomega.ConsistOf(gomega.HaveKeyWithValue("clientConfig", map[string]interface{}{
+		"service": map[string]interface{}{"name": "webhooks", "namespace": "{{ .Release.Namespace }}", "path": "/validate"},
+	})))
+
+	// CRDs are left alone, and non-objects go in the chart's root
+	crd := `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+`
+	writeHelmTestArtifact(g, rule, "example.com_widgets.yaml", crd)
+	g.Expect(readHelmTestFile(g, filepath.Join(dir, "crds", "example.com_widgets.yaml"))).To(gomega.Equal(crd))
+	g.Expect(filepath.Join(dir, "templates", "example.com_widgets.yaml")).NotTo(gomega.BeAnExistingFile())
+
+	writeHelmTestArtifact(g, rule, "report.yaml", "---\nsize: 10\n")
+	g.Expect(readHelmTestFile(g, filepath.Join(dir, "report.yaml"))).To(gomega.Equal("---\nsize: 10\n"))
+}
@@ -0,0 +1,27 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reference renders marker help as a complete reference document,
+// in Markdown (WriteMarkdown) or as a standalone HTML page (WriteHTML),
+// for publishing alongside documentation rather than reading in a terminal
+// (see the pretty package for that).
+//
+// The reference has a section for each category from help.ByCategory, and
+// an entry for each marker in it, with its syntax, targets, summary, details,
+// deprecation notice and arguments (with their types and whether they're
+// optional).  Markers registered for several targets with the same help are
+// combined into a single entry.
+package reference
@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reference
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/genall/help"
+)
+
+// uncategorized is the heading used for markers without a category.
+const uncategorized = "Other"
+
+// entry is a marker in the reference, possibly for several targets.
+type entry struct {
+	help.MarkerDoc
+	// Targets are the targets the marker can be used on.
+	Targets []string
+}
+
+// section is a category in the reference.
+type section struct {
+	Title   string
+	Anchor  string
+	Entries []entry
+}
+
+// sectionsFor turns the given categories into sections, putting any
+// uncategorized markers last.
+func sectionsFor(categories []help.CategoryDoc) []section {
+	var res []section
+	var other *section
+	for _, cat := range categories {
+		if len(cat.Markers) == 0 {
+			continue
+		}
+		sec := section{Title: cat.Category, Entries: entriesFor(cat.Markers)}
+		if sec.Title == "" {
+			sec.Title = uncategorized
+			other = &sec
+			continue
+		}
+		res = append(res, sec)
+	}
+	if other != nil {
+		res = append(res, *other)
+	}
+
+	for i := range res {
+		res[i].Anchor = anchorFor(res[i].Title)
+	}
+	return res
+}
+
+// entriesFor returns the entries for the given markers, sorted by name, and
+// combining markers with the same name and help for different targets.
+func entriesFor(markers []help.MarkerDoc) []entry {
+	sorted := append([]help.MarkerDoc(nil), markers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Target < sorted[j].Target
+	})
+
+	var res []entry
+	for _, marker := range sorted {
+		if len(res) > 0 && sameExceptTarget(res[len(res)-1].MarkerDoc, marker) {
+			last := &res[len(res)-1]
+			last.Targets = append(last.Targets, marker.Target)
+			continue
+		}
+		res = append(res, entry{MarkerDoc: marker, Targets: []string{marker.Target}})
+	}
+	return res
+}
+
+// sameExceptTarget checks if the given docs are for the same marker on
+// different targets.
+func sameExceptTarget(a, b help.MarkerDoc) bool {
+	b.Target = a.Target
+	return reflect.DeepEqual(a, b)
+}
+
+// syntax returns the syntax for the given marker, like
+// `+name:arg=<int>[,other=<string>]`.
+func syntax(marker help.MarkerDoc) string {
+	var out strings.Builder
+	out.WriteString("+")
+	out.WriteString(marker.Name)
+	if marker.Empty() {
+		return out.String()
+	}
+
+	sep := ":"
+	if marker.AnonymousField() {
+		sep = ""
+	}
+	for _, field := range marker.Fields {
+		if field.Optional {
+			out.WriteString("[")
+		}
+		out.WriteString(sep)
+		if field.Name != "" {
+			out.WriteString(field.Name)
+		}
+		out.WriteString("=<")
+		out.WriteString(field.TypeString())
+		out.WriteString(">")
+		if field.Optional {
+			out.WriteString("]")
+		}
+		sep = ","
+	}
+	return out.String()
+}
+
+// anchorFor returns the anchor that the usual Markdown renderers (like
+// GitHub's) give a heading with the given text.
+func anchorFor(heading string) string {
+	var out strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ' || r == '-':
+			out.WriteRune('-')
+		case r == '_' || 'a' <= r && r <= 'z' || '0' <= r && r <= '9':
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// paragraphs splits text into paragraphs at blank lines, trimming trailing
+// whitespace from each line.
+func paragraphs(text string) []string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	var res []string
+	for _, para := range strings.Split(strings.Join(lines, "\n"), "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			res = append(res, para)
+		}
+	}
+	return res
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reference
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/genall/help"
+)
+
+var htmlTemplate = template.Must(template.New("reference").Funcs(template.FuncMap{
+	"syntax":     syntax,
+	"paragraphs": paragraphs,
+	"join":       strings.Join,
+	"trim":       strings.TrimSpace,
+	"deref":      func(s *string) string { return *s },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; padding: 1em; }
+code { background: #f4f4f4; padding: 0 0.2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+.deprecated { color: #a00; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- if gt (len .Sections) 1 }}
+<ul>
+{{- range .Sections }}
+<li><a href="#{{ .Anchor }}">{{ .Title }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+{{- range .Sections }}
+<h2 id="{{ .Anchor }}">{{ .Title }}</h2>
+{{- range .Entries }}
+<h3><code>+{{ .Name }}</code></h3>
+<p><code>{{ syntax .MarkerDoc }}</code></p>
+<p><strong>Applies to:</strong> {{ join .Targets ", " }}</p>
+{{- if .DeprecatedInFavorOf }}
+{{- with deref .DeprecatedInFavorOf }}
+<p class="deprecated"><strong>Deprecated:</strong> use <code>+{{ . }}</code> instead.</p>
+{{- else }}
+<p class="deprecated"><strong>Deprecated.</strong></p>
+{{- end }}
+{{- end }}
+{{- with trim .Summary }}
+<p>{{ . }}</p>
+{{- end }}
+{{- range paragraphs .Details }}
+<p>{{ . }}</p>
+{{- end }}
+{{- if .AnonymousField }}
+{{- with index .Fields 0 }}
+<p><strong>Value:</strong> <code>{{ .TypeString }}</code>{{ if .Optional }} (optional){{ end }}</p>
+{{- end }}
+{{- else if not .Empty }}
+<table>
+<tr><th>Argument</th><th>Type</th><th>Optional</th><th>Description</th></tr>
+{{- range .Fields }}
+<tr><td><code>{{ .Name }}</code></td><td><code>{{ .TypeString }}</code></td><td>{{ if .Optional }}yes{{ else }}no{{ end }}</td><td>{{ .Summary }}{{ range paragraphs .Details }}<p>{{ . }}</p>{{ end }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// WriteHTML writes a reference for the markers in the given categories, with
+// the given title, as a standalone HTML page.
+func WriteHTML(out io.Writer, title string, categories []help.CategoryDoc) error {
+	return htmlTemplate.Execute(out, struct {
+		Title    string
+		Sections []section
+	}{
+		Title:    title,
+		Sections: sectionsFor(categories),
+	})
+}
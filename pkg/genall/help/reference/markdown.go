@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reference
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/genall/help"
+)
+
+// proseEscaper escapes characters in help text that Markdown renderers
+// would otherwise treat as HTML (help text often mentions things like
+// `<name>`).
+var proseEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;")
+
+// cellEscaper additionally makes help text fit in a single table cell.
+var cellEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;", "|", "\\|", "\n\n", "<br><br>", "\n", " ")
+
+// WriteMarkdown writes a Markdown reference for the markers in the given
+// categories, with the given title.
+func WriteMarkdown(out io.Writer, title string, categories []help.CategoryDoc) error {
+	w := bufio.NewWriter(out)
+	sections := sectionsFor(categories)
+
+	fmt.Fprintf(w, "# %s\n", title)
+	if len(sections) > 1 {
+		w.WriteString("\n")
+		for _, sec := range sections {
+			fmt.Fprintf(w, "- [%s](#%s)\n", sec.Title, sec.Anchor)
+		}
+	}
+
+	for _, sec := range sections {
+		fmt.Fprintf(w, "\n## %s\n", sec.Title)
+		for _, marker := range sec.Entries {
+			writeMarkdownEntry(w, marker)
+		}
+	}
+
+	return w.Flush()
+}
+
+// writeMarkdownEntry writes the reference for a single marker.
+func writeMarkdownEntry(w *bufio.Writer, marker entry) {
+	fmt.Fprintf(w, "\n### `+%s`\n\n", marker.Name)
+	fmt.Fprintf(w, "`%s`\n\n", syntax(marker.MarkerDoc))
+	fmt.Fprintf(w, "**Applies to:** %s\n", strings.Join(marker.Targets, ", "))
+
+	if marker.DeprecatedInFavorOf != nil {
+		if *marker.DeprecatedInFavorOf != "" {
+			fmt.Fprintf(w, "\n> **Deprecated:** use `+%s` instead.\n", *marker.DeprecatedInFavorOf)
+		} else {
+			w.WriteString("\n> **Deprecated.**\n")
+		}
+	}
+
+	if marker.Summary != "" {
+		fmt.Fprintf(w, "\n%s\n", proseEscaper.Replace(strings.TrimSpace(marker.Summary)))
+	}
+	for _, para := range paragraphs(marker.Details) {
+		fmt.Fprintf(w, "\n%s\n", proseEscaper.Replace(para))
+	}
+
+	switch {
+	case marker.Empty():
+	case marker.AnonymousField():
+		field := marker.Fields[0]
+		optional := ""
+		if field.Optional {
+			optional = " (optional)"
+		}
+		fmt.Fprintf(w, "\n**Value:** `%s`%s\n", field.TypeString(), optional)
+	default:
+		w.WriteString("\n| Argument | Type | Optional | Description |\n")
+		w.WriteString("| --- | --- | --- | --- |\n")
+		for _, field := range marker.Fields {
+			optional := "no"
+			if field.Optional {
+				optional = "yes"
+			}
+			fmt.Fprintf(w, "| `%s` | `%s` | %s | %s |\n", field.Name, field.TypeString(), optional, fieldDescription(field))
+		}
+	}
+}
+
+// fieldDescription returns the description of a field for a table cell.
+func fieldDescription(field help.FieldHelp) string {
+	desc := cellEscaper.Replace(strings.TrimSpace(field.Summary))
+	if details := strings.TrimSpace(field.Details); details != "" {
+		if desc != "" {
+			desc += "<br><br>"
+		}
+		desc += cellEscaper.Replace(details)
+	}
+	return desc
+}
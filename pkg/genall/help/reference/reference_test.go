@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reference
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall/help"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+type limits struct {
+	Max   int      `marker:"max"`
+	Names []string `marker:"names,optional"`
+}
+
+func testCategories(g *gomega.WithT) []help.CategoryDoc {
+	reg := &markers.Registry{}
+	define := func(name string, target markers.TargetType, obj interface{}, defHelp *markers.DefinitionHelp) {
+		def := markers.Must(markers.MakeDefinition(name, target, obj))
+		g.Expect(reg.Register(def)).To(gomega.Succeed())
+		if defHelp != nil {
+			reg.AddHelp(def, defHelp)
+		}
+	}
+
+	limitsHelp := &markers.DefinitionHelp{
+		Category: "Test things",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "limits <things>. ",
+			Details: "Some details.  \n\nMore details.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Max":   {Summary: "is the maximum | limit."},
+			"Names": {Summary: "are names.", Details: "Like\nthese."},
+		},
+	}
+	define("test:limits", markers.DescribesType, limits{}, limitsHelp)
+	define("test:limits", markers.DescribesField, limits{}, limitsHelp)
+
+	oldName := "test:limits"
+	define("test:old", markers.DescribesField, 0, &markers.DefinitionHelp{
+		Category:            "Test things",
+		DetailedHelp:        markers.DetailedHelp{Summary: "is the old way."},
+		DeprecatedInFavorOf: &oldName,
+	})
+	define("test:flag", markers.DescribesPackage, struct{}{}, nil)
+
+	return help.ByCategory(reg, help.SortByCategory)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var out bytes.Buffer
+	g.Expect(WriteMarkdown(&out, "Test markers", testCategories(g))).To(gomega.Succeed())
+	g.Expect(out.String()).To(gomega.Equal("# Test markers\n" +
+		"\n" +
+		"- [Test things](#test-things)\n" +
+		"- [Other](#other)\n" +
+		"\n" +
+		"## Test things\n" +
+		"\n" +
+		"### `+test:limits`\n" +
+		"\n" +
+		"`+test:limits:max=<int>[,names=<[]string>]`\n" +
+		"\n" +
+		"**Applies to:** field, type\n" +
+		"\n" +
+		"limits &lt;things&gt;.\n" +
+		"\n" +
+		"Some details.\n" +
+		"\n" +
+		"More details.\n" +
+		"\n" +
+		"| Argument | Type | Optional | Description |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `max` | `int` | no | is the maximum \\| limit. |\n" +
+		"| `names` | `[]string` | yes | are names.<br><br>Like these. |\n" +
+		"\n" +
+		"### `+test:old`\n" +
+		"\n" +
+		"`+test:old=<int>`\n" +
+		"\n" +
+		"**Applies to:** field\n" +
+		"\n" +
+		"> **Deprecated:** use `+test:limits` instead.\n" +
+		"\n" +
+		"is the old way.\n" +
+		"\n" +
+		"**Value:** `int`\n" +
+		"\n" +
+		"## Other\n" +
+		"\n" +
+		"### `+test:flag`\n" +
+		"\n" +
+		"`+test:flag`\n" +
+		"\n" +
+		"**Applies to:** package\n"))
+}
+
+func TestWriteHTML(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var out bytes.Buffer
+	g.Expect(WriteHTML(&out, "Test markers", testCategories(g))).To(gomega.Succeed())
+	g.Expect(out.String()).To(gomega.ContainSubstring(`<li><a href="#test-things">Test things</a></li>`))
+	g.Expect(out.String()).To(gomega.ContainSubstring(`<p><strong>Applies to:</strong> field, type</p>`))
+	g.Expect(out.String()).To(gomega.ContainSubstring(`<p>limits &lt;things&gt;.</p>`))
+	g.Expect(out.String()).To(gomega.ContainSubstring(`<tr><td><code>names</code></td><td><code>[]string</code></td><td>yes</td><td>are names.<p>Like
+these.</p></td></tr>`))
+	g.Expect(out.String()).To(gomega.ContainSubstring(`<p class="deprecated"><strong>Deprecated:</strong> use <code>+test:limits</code> instead.</p>`))
+}
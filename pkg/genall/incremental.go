@@ -0,0 +1,563 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// CacheScope describes what the output of a Cacheable generator depends on.
+type CacheScope int
+
+const (
+	// CachePerRun means the output depends on all the root packages together,
+	// so it's cached (and regenerated) as a whole.
+	CachePerRun CacheScope = iota
+	// CachePerPackage means the output for each root package depends only on
+	// that package (and its dependencies), and is always written with that
+	// package (see OutputRule).  Only changed packages are regenerated.
+	CachePerPackage
+)
+
+// Cacheable is implemented by Generators whose output can be cached between
+// runs (see Runtime.CacheDir).  The output of such a generator must depend only
+// on its options, the loaded packages, and files read using the context's
+// InputRule.
+type Cacheable interface {
+	// CacheScope indicates what the generator's output depends on.
+	CacheScope() CacheScope
+}
+
+// +controllertools:marker:generateHelp:category=""
+
+// CacheDir specifies a directory in which to cache generator output between runs.
+//
+// Generators whose options, input packages (and their dependencies), and
+// input files haven't changed since an earlier successful run are skipped,
+// and their earlier output is written instead.  The markers collected from
+// each package, and the schemata computed for its types, are cached too, so
+// generators that do run only recompute them for packages that changed.
+// Entries are keyed by content (and the build of controller-gen), not paths
+// or times, so the directory may be shared (e.g. in CI), and it's always safe
+// to delete it.
+type CacheDir string
+
+// cacheFormat is mixed into every key, and should be bumped whenever the
+// format of entries (or how keys are computed) changes.
+const cacheFormat = "controller-gen incremental cache v2"
+
+var (
+	buildIDOnce sync.Once
+	buildID     string
+	buildIDErr  error
+)
+
+// buildIdentity identifies the build of controller-gen that's running, so
+// that output from other builds (which may generate different output) is
+// never reused.  Builds from a clean checkout are identified by their VCS
+// revision, and released builds by their version.  Anything else (local or
+// modified builds) is identified by the hash of the executable itself.
+func buildIdentity() (string, error) {
+	buildIDOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if ok {
+			settings := make(map[string]string)
+			for _, setting := range info.Settings {
+				settings[setting.Key] = setting.Value
+			}
+			revision, modified := settings["vcs.revision"], settings["vcs.modified"]
+			switch {
+			case revision != "" && modified == "false":
+				buildID = fmt.Sprintf("%s revision %s", info.Main.Version, revision)
+				return
+			case revision == "" && info.Main.Version != "" && info.Main.Version != "(devel)" && !strings.HasSuffix(info.Main.Version, "+dirty"):
+				buildID = fmt.Sprintf("%s %s", info.Main.Path, info.Main.Version)
+				return
+			}
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			buildIDErr = fmt.Errorf("unable to identify the build of controller-gen for caching: %w", err)
+			return
+		}
+		in, err := os.Open(exe)
+		if err != nil {
+			buildIDErr = fmt.Errorf("unable to identify the build of controller-gen for caching: %w", err)
+			return
+		}
+		defer in.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, in); err != nil {
+			buildIDErr = fmt.Errorf("unable to identify the build of controller-gen for caching: %w", err)
+			return
+		}
+		buildID = "executable " + hex.EncodeToString(h.Sum(nil))
+	})
+	return buildID, buildIDErr
+}
+
+// incrementalCache stores generator output on disk, keyed by the content of
+// the generator's inputs.
+type incrementalCache struct {
+	dir string
+
+	// hashes memoizes content hashes for packages.
+	hashes   map[*packages.Package]string
+	hashesMu sync.Mutex
+
+	// values are values saved for packages during this run (see Save),
+	// by key, to be stored once it's done.
+	values   map[string]interface{}
+	valuesMu sync.Mutex
+}
+
+var _ markers.PackageStore = &incrementalCache{}
+
+// diskCacheEntry is the on-disk form of cached output.
+type diskCacheEntry struct {
+	// Inputs are the non-code files read while generating, by path.
+	Inputs []cachedInput `json:"inputs,omitempty"`
+	// Artifacts are the artifacts written, in order.
+	Artifacts []cachedArtifact `json:"artifacts,omitempty"`
+}
+
+// cachedInput is a non-code file read while generating output.
+type cachedInput struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// cachedArtifact is a single artifact written by a generator.
+type cachedArtifact struct {
+	// Package is the ID of the package the artifact was written with, if any.
+	Package  string `json:"package,omitempty"`
+	Path     string `json:"path"`
+	Contents []byte `json:"contents"`
+}
+
+// runKey computes the key for the output of the given generator over all the
+// given roots.
+func (c *incrementalCache) runKey(gen *Generator, roots []*loader.Package) (string, error) {
+	return c.key(gen, roots...)
+}
+
+// packageKey computes the key for the output of the given generator for just
+// the given root.
+func (c *incrementalCache) packageKey(gen *Generator, root *loader.Package) (string, error) {
+	return c.key(gen, root)
+}
+
+// key hashes together the controller-gen build, the generator & its
+// options, and the content of the given packages.
+func (c *incrementalCache) key(gen *Generator, roots ...*loader.Package) (string, error) {
+	opts, err := json.Marshal(*gen)
+	if err != nil {
+		return "", fmt.Errorf("unable to compute cache key for generator %T: %w", *gen, err)
+	}
+	build, err := buildIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%T\n%s\n", cacheFormat, build, *gen, opts)
+	for _, root := range roots {
+		pkgHash, err := c.packageHash(root.Package)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", root.ID, pkgHash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// packageHash hashes the given package and everything it imports.
+//
+// Packages from versioned modules (and the standard library) are hashed by
+// their module version (or Go version), since that determines their content
+// (and thus their export data).  Everything else (the main module, and any
+// modules replaced with local directories) is hashed by file content.
+func (c *incrementalCache) packageHash(pkg *packages.Package) (string, error) {
+	c.hashesMu.Lock()
+	if c.hashes == nil {
+		c.hashes = make(map[*packages.Package]string)
+	}
+	hash, known := c.hashes[pkg]
+	c.hashesMu.Unlock()
+	if known {
+		return hash, nil
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", pkg.PkgPath)
+	switch {
+	case pkg.Module != nil && pkg.Module.Replace != nil && pkg.Module.Replace.Version != "":
+		fmt.Fprintf(h, "module %s@%s\n", pkg.Module.Replace.Path, pkg.Module.Replace.Version)
+	case pkg.Module != nil && pkg.Module.Replace == nil && !pkg.Module.Main && pkg.Module.Version != "":
+		fmt.Fprintf(h, "module %s@%s\n", pkg.Module.Path, pkg.Module.Version)
+	case pkg.Module == nil && isStandardPackage(pkg.PkgPath):
+		fmt.Fprintf(h, "std %s\n", runtime.Version())
+	default:
+		files := append([]string(nil), pkg.GoFiles...)
+		sort.Strings(files)
+		for _, file := range files {
+			contents, err := ioutil.ReadFile(file)
+			if err != nil {
+				return "", err
+			}
+			// hash the base name, not the path, so that the cache can be shared across checkouts
+			fmt.Fprintf(h, "file %s %d\n", filepath.Base(file), len(contents))
+			h.Write(contents)
+		}
+	}
+
+	importPaths := make([]string, 0, len(pkg.Imports))
+	for importPath := range pkg.Imports {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+	for _, importPath := range importPaths {
+		importHash, err := c.packageHash(pkg.Imports[importPath])
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "import %s %s\n", importPath, importHash)
+	}
+
+	hash = hex.EncodeToString(h.Sum(nil))
+	c.hashesMu.Lock()
+	defer c.hashesMu.Unlock()
+	c.hashes[pkg] = hash
+	return hash, nil
+}
+
+// isStandardPackage guesses whether the given package path is part of the
+// standard library (whose first path element never contains a dot).
+func isStandardPackage(pkgPath string) bool {
+	firstElem := strings.SplitN(pkgPath, "/", 2)[0]
+	return !strings.Contains(firstElem, ".")
+}
+
+// entryPath returns the path at which the entry with the given key is stored.
+func (c *incrementalCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// load fetches the entry with the given key, returning false if there isn't
+// one, it can't be read, or the input files it was generated from have changed.
+func (c *incrementalCache) load(key string, inputs InputRule) (*diskCacheEntry, bool) {
+	raw, err := ioutil.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+	entry := &diskCacheEntry{}
+	if err := json.Unmarshal(raw, entry); err != nil {
+		// treat corrupt entries as missing -- they'll be overwritten
+		return nil, false
+	}
+	for _, input := range entry.Inputs {
+		hash, err := hashInput(inputs, input.Path)
+		if err != nil || hash != input.Hash {
+			return nil, false
+		}
+	}
+	return entry, true
+}
+
+// store saves the given entry (or package value) under the given key.
+// Entries are written to a temporary file first, so that concurrent runs
+// sharing the cache never see partial entries.
+func (c *incrementalCache) store(key string, entry interface{}) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	path := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// valueKey computes the key for the value saved with the given name for the
+// given package.
+func (c *incrementalCache) valueKey(pkg *loader.Package, name string) (string, error) {
+	build, err := buildIdentity()
+	if err != nil {
+		return "", err
+	}
+	pkgHash, err := c.packageHash(pkg.Package)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\nvalue %s\n%s %s\n", cacheFormat, build, name, pkg.ID, pkgHash)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Load implements markers.PackageStore, loading values saved by earlier
+// runs.  Values that can't be read are treated as missing.
+func (c *incrementalCache) Load(pkg *loader.Package, name string, out interface{}) bool {
+	key, err := c.valueKey(pkg, name)
+	if err != nil {
+		return false
+	}
+	raw, err := ioutil.ReadFile(c.entryPath(key))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, out) == nil
+}
+
+// Save implements markers.PackageStore.  Values are only stored once the
+// run is done, and then only if it was entirely successful (see
+// storeValues).
+func (c *incrementalCache) Save(pkg *loader.Package, name string, value interface{}) {
+	key, err := c.valueKey(pkg, name)
+	if err != nil {
+		// just don't cache it
+		return
+	}
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+// storeValues stores the values saved during this run.
+func (c *incrementalCache) storeValues() error {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	for key, value := range c.values {
+		if err := c.store(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashInput hashes the contents of the given non-code file.
+func hashInput(inputs InputRule, path string) (string, error) {
+	in, err := inputs.OpenForRead(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, in); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recordingInput is an InputRule that records the files read through it,
+// so that cached output can be invalidated when they change.
+type recordingInput struct {
+	InputRule
+
+	inputs []cachedInput
+	mu     sync.Mutex
+}
+
+func (r *recordingInput) OpenForRead(path string) (io.ReadCloser, error) {
+	in, err := r.InputRule.OpenForRead(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	contents, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(contents)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inputs = append(r.inputs, cachedInput{Path: path, Hash: hex.EncodeToString(sum[:])})
+	return ioutil.NopCloser(bytes.NewReader(contents)), nil
+}
+
+// cachedGeneration tracks the cache entries for a single generator in a run.
+type cachedGeneration struct {
+	scope CacheScope
+	input *recordingInput
+
+	// runKey is the key for CachePerRun generators.
+	runKey string
+	// packageKeys are the keys for each root for CachePerPackage generators.
+	packageKeys map[*loader.Package]string
+	// hits are the entries found for each root for CachePerPackage generators.
+	hits map[*loader.Package]*diskCacheEntry
+}
+
+// planGeneration figures out what output can be reused for the given
+// generator, returning the artifacts to reuse, the roots that still need to
+// be generated for (if any), and the information needed to save the new
+// output afterwards (nil if the generator can't be cached).
+func (c *incrementalCache) planGeneration(gen *Generator, roots []*loader.Package, inputs InputRule) ([]cachedArtifact, []*loader.Package, *cachedGeneration, error) {
+	cacheable, isCacheable := (*gen).(Cacheable)
+	if !isCacheable {
+		return nil, roots, nil, nil
+	}
+	plan := &cachedGeneration{
+		scope: cacheable.CacheScope(),
+		input: &recordingInput{InputRule: inputs},
+	}
+
+	if plan.scope != CachePerPackage {
+		key, err := c.runKey(gen, roots)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		plan.runKey = key
+		if entry, hit := c.load(key, inputs); hit {
+			return entry.Artifacts, nil, nil, nil
+		}
+		return nil, roots, plan, nil
+	}
+
+	plan.packageKeys = make(map[*loader.Package]string, len(roots))
+	plan.hits = make(map[*loader.Package]*diskCacheEntry)
+	var misses []*loader.Package
+	for _, root := range roots {
+		key, err := c.packageKey(gen, root)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		plan.packageKeys[root] = key
+		if entry, hit := c.load(key, inputs); hit {
+			plan.hits[root] = entry
+			continue
+		}
+		misses = append(misses, root)
+	}
+	if len(misses) == 0 {
+		var artifacts []cachedArtifact
+		for _, root := range roots {
+			artifacts = append(artifacts, plan.hits[root].Artifacts...)
+		}
+		return artifacts, nil, nil, nil
+	}
+	return nil, misses, plan, nil
+}
+
+// finishGeneration merges the reused output of a partially cached generator
+// into its fresh output (keeping it in the same order as the roots), and
+// returns the entries to save for the fresh output.
+func (c *incrementalCache) finishGeneration(plan *cachedGeneration, roots []*loader.Package, output *bufferedOutput) map[string]*diskCacheEntry {
+	output.mu.Lock()
+	defer output.mu.Unlock()
+
+	if plan.scope != CachePerPackage {
+		entry := &diskCacheEntry{Inputs: plan.input.inputs}
+		for _, artifact := range output.artifacts {
+			entry.Artifacts = append(entry.Artifacts, artifact.toCached())
+		}
+		return map[string]*diskCacheEntry{plan.runKey: entry}
+	}
+
+	byPackage := make(map[*loader.Package][]*bufferedArtifact)
+	var unassociated []*bufferedArtifact
+	for _, artifact := range output.artifacts {
+		if artifact.pkg == nil {
+			unassociated = append(unassociated, artifact)
+			continue
+		}
+		byPackage[artifact.pkg] = append(byPackage[artifact.pkg], artifact)
+	}
+
+	toSave := make(map[string]*diskCacheEntry)
+	var merged []*bufferedArtifact
+	for _, root := range roots {
+		if entry, hit := plan.hits[root]; hit {
+			for _, cached := range entry.Artifacts {
+				merged = append(merged, cached.toBuffered(root))
+			}
+			continue
+		}
+		merged = append(merged, byPackage[root]...)
+
+		entry := &diskCacheEntry{Inputs: plan.input.inputs}
+		for _, artifact := range byPackage[root] {
+			entry.Artifacts = append(entry.Artifacts, artifact.toCached())
+		}
+		toSave[plan.packageKeys[root]] = entry
+	}
+	output.artifacts = append(merged, unassociated...)
+
+	if len(unassociated) > 0 {
+		// don't cache output we can't attribute to a single package
+		return nil
+	}
+	return toSave
+}
+
+// toCached converts a buffered artifact into its on-disk form.
+func (a *bufferedArtifact) toCached() cachedArtifact {
+	cached := cachedArtifact{
+		Path:     a.itemPath,
+		Contents: a.contents.Bytes(),
+	}
+	if a.pkg != nil {
+		cached.Package = a.pkg.ID
+	}
+	return cached
+}
+
+// toBuffered converts a cached artifact back into a buffered one,
+// associated with the given package if it was associated with any.
+func (a cachedArtifact) toBuffered(pkg *loader.Package) *bufferedArtifact {
+	artifact := &bufferedArtifact{itemPath: a.Path}
+	if a.Package != "" {
+		artifact.pkg = pkg
+	}
+	artifact.contents.Write(a.Contents)
+	return artifact
+}
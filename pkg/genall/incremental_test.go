@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// countingGenerator records the roots it's run for, and writes out their
+// names (along with a header), either all together or per package.
+type countingGenerator struct {
+	scope CacheScope
+	runs  *[][]string
+}
+
+func (g countingGenerator) RegisterMarkers(*markers.Registry) error { return nil }
+func (g countingGenerator) CacheScope() CacheScope                  { return g.scope }
+
+func (g countingGenerator) Generate(ctx *GenerationContext) error {
+	header, err := ctx.ReadFile("header.txt")
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, root := range ctx.Roots {
+		names = append(names, root.Name)
+	}
+	*g.runs = append(*g.runs, names)
+
+	if g.scope == CachePerRun {
+		return ctx.WriteYAML("all.yaml", string(header), []interface{}{map[string]interface{}{"roots": names}})
+	}
+	for _, root := range ctx.Roots {
+		out, err := ctx.Open(root, root.Name+".txt")
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(append(header, root.Name...)); err != nil {
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestIncrementalCache(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		g.Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(gomega.Succeed())
+		g.Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(gomega.Succeed())
+	}
+	writeFile("go.mod", "module example.com/cached\n\ngo 1.19\n")
+	writeFile("a/a.go", "package a\n\ntype A struct{}\n")
+	writeFile("b/b.go", "package b\n\nimport _ \"example.com/cached/c\"\n\ntype B struct{}\n")
+	writeFile("c/c.go", "package c\n\ntype C struct{}\n")
+	writeFile("header.txt", "# header\n")
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	var perRunRuns, perPackageRuns [][]string
+	var perRun, perPackage Generator = countingGenerator{scope: CachePerRun, runs: &perRunRuns},
+		countingGenerator{scope: CachePerPackage, runs: &perPackageRuns}
+
+	// run loads the packages afresh (as a new invocation would), and runs the
+	// generators, returning what was written.
+	run := func() (string, []string) {
+		rt, err := Generators{&perRun, &perPackage}.ForRoots("./a", "./b")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		out := &recordingOutput{}
+		var errs bytes.Buffer
+		rt.OutputRules = OutputRules{Default: out}
+		rt.ErrorWriter = &errs
+		rt.CacheDir = filepath.Join(dir, "cache")
+		g.Expect(rt.Run()).To(gomega.BeFalse(), errs.String())
+		return out.buf.String(), out.paths
+	}
+
+	// running for the first time
+	firstOut, firstPaths := run()
+	g.Expect(firstPaths).To(gomega.Equal([]string{"all.yaml", "a.txt", "b.txt"}))
+	g.Expect(perRunRuns).To(gomega.Equal([][]string{{"a", "b"}}))
+	g.Expect(perPackageRuns).To(gomega.Equal([][]string{{"a", "b"}}))
+
+	// running again without changes
+	out, paths := run()
+	g.Expect(out).To(gomega.Equal(firstOut))
+	g.Expect(paths).To(gomega.Equal(firstPaths))
+	g.Expect(perRunRuns).To(gomega.HaveLen(1))
+	g.Expect(perPackageRuns).To(gomega.HaveLen(1))
+
+	// changing a dependency of one package
+	writeFile("c/c.go", "package c\n\ntype C struct{ Changed bool }\n")
+	out, paths = run()
+	g.Expect(out).To(gomega.Equal(firstOut))
+	g.Expect(paths).To(gomega.Equal(firstPaths))
+	g.Expect(perRunRuns).To(gomega.Equal([][]string{{"a", "b"}, {"a", "b"}}))
+	g.Expect(perPackageRuns).To(gomega.Equal([][]string{{"a", "b"}, {"b"}}))
+
+	// changing an input file
+	writeFile("header.txt", "# new header\n")
+	out, _ = run()
+	g.Expect(strings.Count(out, "# new header")).To(gomega.Equal(3))
+	g.Expect(perRunRuns).To(gomega.HaveLen(3))
+	g.Expect(perPackageRuns).To(gomega.Equal([][]string{{"a", "b"}, {"b"}, {"a", "b"}}))
+}
+
+func TestIncrementalCacheValues(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		g.Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(gomega.Succeed())
+		g.Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(gomega.Succeed())
+	}
+	writeFile("go.mod", "module example.com/cached\n\ngo 1.19\n")
+	writeFile("a/a.go", "package a\n\nimport _ \"example.com/cached/b\"\n")
+	writeFile("b/b.go", "package b\n\ntype B struct{}\n")
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	// load loads the package afresh, as a new invocation would, with a
+	// fresh cache.
+	load := func() (*incrementalCache, *loader.Package) {
+		roots, err := loader.LoadRoots("./a")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return &incrementalCache{dir: filepath.Join(dir, "cache")}, roots[0]
+	}
+
+	var value []string
+	cache, pkg := load()
+	cache.Save(pkg, "value", []string{"saved"})
+	g.Expect(cache.Load(pkg, "value", &value)).To(gomega.BeFalse(), "values should only be stored once the run is done")
+	g.Expect(cache.storeValues()).To(gomega.Succeed())
+
+	cache, pkg = load()
+	g.Expect(cache.Load(pkg, "value", &value)).To(gomega.BeTrue())
+	g.Expect(value).To(gomega.Equal([]string{"saved"}))
+	g.Expect(cache.Load(pkg, "other value", &value)).To(gomega.BeFalse())
+
+	// changing a dependency
+	writeFile("b/b.go", "package b\n\ntype B struct{ Changed bool }\n")
+	cache, pkg = load()
+	g.Expect(cache.Load(pkg, "value", &value)).To(gomega.BeFalse())
+}
+
+func TestBuildIdentity(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// test binaries aren't stamped with VCS information, so they're
+	// identified by their contents
+	build, err := buildIdentity()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(build).To(gomega.HavePrefix("executable "))
+}
@@ -19,12 +19,16 @@ package genall
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"sigs.k8s.io/controller-tools/pkg/markers"
 )
 
 var (
-	InputPathsMarker = markers.Must(markers.MakeDefinition("paths", markers.DescribesPackage, InputPaths(nil)))
+	InputPathsMarker   = markers.Must(markers.MakeDefinition("paths", markers.DescribesPackage, InputPaths(nil)))
+	CacheDirMarker     = markers.Must(markers.MakeDefinition("cache:dir", markers.DescribesPackage, CacheDir("")))
+	PruneMarker        = markers.Must(markers.MakeDefinition("prune", markers.DescribesPackage, Prune{}))
+	CheckMarkersMarker = markers.Must(markers.MakeDefinition("markers:check", markers.DescribesPackage, CheckMarkers{}))
 )
 
 // +controllertools:marker:generateHelp:category=""
@@ -35,7 +39,7 @@ var (
 type InputPaths []string
 
 // RegisterOptionsMarkers registers "mandatory" options markers for FromOptions into the given registry.
-// At this point, that's just InputPaths.
+// At this point, that's InputPaths, CacheDir, Prune and CheckMarkers.
 func RegisterOptionsMarkers(into *markers.Registry) error {
 	if err := into.Register(InputPathsMarker); err != nil {
 		return err
@@ -44,9 +48,54 @@ func RegisterOptionsMarkers(into *markers.Registry) error {
 	if helpGiver, hasHelp := ((interface{})(InputPaths(nil))).(HasHelp); hasHelp {
 		into.AddHelp(InputPathsMarker, helpGiver.Help())
 	}
+	if err := into.Register(CacheDirMarker); err != nil {
+		return err
+	}
+	if helpGiver, hasHelp := ((interface{})(CacheDir(""))).(HasHelp); hasHelp {
+		into.AddHelp(CacheDirMarker, helpGiver.Help())
+	}
+	if err := into.Register(PruneMarker); err != nil {
+		return err
+	}
+	if helpGiver, hasHelp := ((interface{})(Prune{})).(HasHelp); hasHelp {
+		into.AddHelp(PruneMarker, helpGiver.Help())
+	}
+	if err := into.Register(CheckMarkersMarker); err != nil {
+		return err
+	}
+	if helpGiver, hasHelp := ((interface{})(CheckMarkers{})).(HasHelp); hasHelp {
+		into.AddHelp(CheckMarkersMarker, helpGiver.Help())
+	}
 	return nil
 }
 
+// GeneratorFactory makes a Generator from the parsed value of an options
+// marker.
+type GeneratorFactory func(options interface{}) Generator
+
+// generatorFactories holds the GeneratorFactory for each options marker
+// definition whose values aren't Generators themselves, by definition.
+var generatorFactories sync.Map
+
+// RegisterGeneratorFactory marks the given options marker definition as one
+// for a generator whose options aren't a Go type known ahead of time (like a
+// plugin), so that FromOptions makes the generator from the parsed options
+// using the given factory.  The definition still needs to be registered in
+// the options registry.
+func RegisterGeneratorFactory(def *markers.Definition, factory GeneratorFactory) {
+	generatorFactories.Store(def, factory)
+}
+
+// generatorFactoryFor returns the GeneratorFactory registered for the given
+// definition, if any.
+func generatorFactoryFor(def *markers.Definition) (GeneratorFactory, bool) {
+	factory, registered := generatorFactories.Load(def)
+	if !registered {
+		return nil, false
+	}
+	return factory.(GeneratorFactory), true
+}
+
 // RegistryFromOptions produces just the marker registry that would be used by FromOptions, without
 // attempting to produce a full Runtime.  This can be useful if you want to display help without
 // trying to load roots.
@@ -68,6 +117,8 @@ func RegistryFromOptions(optionsRegistry *markers.Registry, options []string) (*
 // a) Generators
 // b) OutputRules
 // c) InputPaths
+// d) CacheDir
+// e) Prune
 //
 // The paths specified in InputPaths are loaded as package roots, and the combined with
 // the generators and the specified output rules to produce a runtime that can be run or
@@ -85,6 +136,15 @@ func FromOptions(optionsRegistry *markers.Registry, options []string) (*Runtime,
 	if err != nil {
 		return nil, err
 	}
+	genRuntime.CacheDir = protoRt.CacheDir
+	genRuntime.GeneratorNames = make(map[*Generator]string, len(protoRt.GeneratorsByName))
+	for name, gen := range protoRt.GeneratorsByName {
+		genRuntime.GeneratorNames[gen] = name
+	}
+	genRuntime.Prune = protoRt.Prune
+	if protoRt.CheckMarkers != nil {
+		genRuntime.Collector.CheckUnknown = protoRt.CheckMarkers.unknownMarkerCheck(optionsRegistry)
+	}
 
 	// attempt to figure out what the user wants without a lot of verbose specificity:
 	// if the user specifies a default rule, assume that they probably want to fall back
@@ -113,6 +173,9 @@ func protoFromOptions(optionsRegistry *markers.Registry, options []string) (prot
 		ByGenerator: make(map[*Generator]OutputRule),
 	}
 	var paths []string
+	var cacheDir string
+	var prune *Prune
+	var checkMarkers *CheckMarkers
 
 	// collect the generators first, so that we can key the output on the actual
 	// generator, which matters if there's settings in the gen object and it's not a pointer.
@@ -133,6 +196,10 @@ func protoFromOptions(optionsRegistry *markers.Registry, options []string) (prot
 			return protoRuntime{}, fmt.Errorf("unable to parse option %q: %w", rawOpt[1:], err)
 		}
 
+		if factory, isGenerator := generatorFactoryFor(defn); isGenerator {
+			val = factory(val)
+		}
+
 		switch val := val.(type) {
 		case Generator:
 			gens = append(gens, &val)
@@ -149,6 +216,12 @@ func protoFromOptions(optionsRegistry *markers.Registry, options []string) (prot
 			continue
 		case InputPaths:
 			paths = append(paths, val...)
+		case CacheDir:
+			cacheDir = string(val)
+		case Prune:
+			prune = &val
+		case CheckMarkers:
+			checkMarkers = &val
 		default:
 			return protoRuntime{}, fmt.Errorf("unknown option marker %q", defn.Name)
 		}
@@ -169,6 +242,9 @@ func protoFromOptions(optionsRegistry *markers.Registry, options []string) (prot
 		Generators:       Generators(gens),
 		OutputRules:      rules,
 		GeneratorsByName: gensByName,
+		CacheDir:         cacheDir,
+		Prune:            prune,
+		CheckMarkers:     checkMarkers,
 	}, nil
 }
 
@@ -179,6 +255,9 @@ type protoRuntime struct {
 	Generators       Generators
 	OutputRules      OutputRules
 	GeneratorsByName map[string]*Generator
+	CacheDir         string
+	Prune            *Prune
+	CheckMarkers     *CheckMarkers
 }
 
 // splitOutputRuleOption splits a marker name of "output:rule:gen" or "output:rule"
@@ -17,11 +17,13 @@ limitations under the License.
 package genall
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"sigs.k8s.io/controller-tools/pkg/loader"
 )
@@ -158,3 +160,64 @@ func (o OutputArtifacts) Open(pkg *loader.Package, itemPath string) (io.WriteClo
 	outPath := filepath.Join(outDir, itemPath)
 	return os.Create(outPath)
 }
+
+// bufferedOutput holds artifacts in memory, so that generators running in
+// parallel can have their output written out in a deterministic order once
+// they're all done.
+type bufferedOutput struct {
+	artifacts []*bufferedArtifact
+	mu        sync.Mutex
+}
+
+// bufferedArtifact is a single artifact held by bufferedOutput.
+type bufferedArtifact struct {
+	pkg      *loader.Package
+	itemPath string
+	contents bytes.Buffer
+}
+
+func (o *bufferedOutput) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	artifact := &bufferedArtifact{pkg: pkg, itemPath: itemPath}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.artifacts = append(o.artifacts, artifact)
+	return nopCloser{&artifact.contents}, nil
+}
+
+// toArtifacts returns the buffered artifacts, in the order they were opened.
+func (o *bufferedOutput) toArtifacts() []Artifact {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	artifacts := make([]Artifact, len(o.artifacts))
+	for i, artifact := range o.artifacts {
+		artifacts[i] = Artifact{Package: artifact.pkg, ItemPath: artifact.itemPath, Contents: artifact.contents.Bytes()}
+	}
+	return artifacts
+}
+
+// writeTo writes out each buffered artifact using the given rule, in the
+// order they were opened.  It carries on past failures (so that, e.g., every
+// stale file is reported when verifying), returning all errors encountered.
+func (o *bufferedOutput) writeTo(rule OutputRule) []error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var errs []error
+	for _, artifact := range o.artifacts {
+		out, err := rule.Open(artifact.pkg, artifact.itemPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, writeErr := out.Write(artifact.contents.Bytes())
+		if err := out.Close(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		if writeErr != nil {
+			errs = append(errs, writeErr)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// ManifestFileName is the name of the file, in each output directory, that
+// records which files were generated there (see Prune).  It deliberately
+// doesn't have a .json extension, so that tools like `kubectl apply -f` skip it.
+const ManifestFileName = ".controller-gen.manifest"
+
+// +controllertools:marker:generateHelp:category=""
+
+// Prune removes files generated by earlier runs that are no longer generated.
+//
+// The files each generator writes to an output directory (like with
+// output:dir or output:artifacts) are recorded in a manifest in that
+// directory.  On later runs, files a generator previously wrote but didn't
+// write this time are deleted, as long as they haven't been modified since.
+// Files that aren't in the manifest, like hand-written ones, are never
+// touched.  Nothing is pruned if there were any errors.
+type Prune struct {
+	// DryRun lists the files that would be removed, instead of removing them.
+	DryRun bool `marker:",optional"`
+}
+
+// DirectoryOutputRule is implemented by OutputRules that write artifacts to
+// files in an output directory, so that files they wrote in earlier runs can
+// be pruned (see Prune).
+type DirectoryOutputRule interface {
+	OutputRule
+	// OutputDir returns the directory that artifacts for the given package
+	// (or non-package artifacts, if nil) are written to, with the artifact's
+	// path being relative to that directory.  An empty result means the
+	// artifacts aren't written to an output directory (e.g. code written
+	// alongside existing source), and shouldn't be pruned.
+	OutputDir(pkg *loader.Package) string
+}
+
+func (o OutputToDirectory) OutputDir(_ *loader.Package) string {
+	return string(o)
+}
+
+func (o OutputArtifacts) OutputDir(pkg *loader.Package) string {
+	if pkg == nil {
+		return string(o.Config)
+	}
+	return string(o.Code)
+}
+
+// outputManifest is the contents of a ManifestFileName file.
+type outputManifest struct {
+	// Generators maps generators (see pruneKey) to the files
+	// (slash-separated, and relative to the output directory) they
+	// generated, and a hash of each file's contents.
+	Generators map[string]map[string]string `json:"generators"`
+}
+
+// pruner tracks the files written to output directories during a run, and
+// removes ones written by earlier runs that are now stale.
+type pruner struct {
+	Prune
+	diags *diagnostics
+
+	// produced maps output directories to generators (see pruneKey) to
+	// the files (and their hashes) written there during this run.
+	produced map[string]map[string]map[string]string
+}
+
+// pruneKey identifies the given generator in manifests.  Generators are
+// identified by the name they were specified with if it's known, since
+// different generators may have the same type (like plugins), and by
+// their type otherwise.
+func (r *Runtime) pruneKey(gen *Generator) string {
+	if name := r.GeneratorNames[gen]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("%T", *gen)
+}
+
+// record notes the artifacts the generator with the given key produced
+// using the given output rule.
+func (p *pruner) record(genKey string, rule OutputRule, output *bufferedOutput) {
+	dirRule, isDirRule := rule.(DirectoryOutputRule)
+	if !isDirRule {
+		return
+	}
+	if p.produced == nil {
+		p.produced = make(map[string]map[string]map[string]string)
+	}
+	filesIn := func(dir string) map[string]string {
+		byGen, known := p.produced[dir]
+		if !known {
+			byGen = make(map[string]map[string]string)
+			p.produced[dir] = byGen
+		}
+		files, known := byGen[genKey]
+		if !known {
+			files = make(map[string]string)
+			byGen[genKey] = files
+		}
+		return files
+	}
+
+	// make sure generators that wrote nothing at all still get their
+	// earlier output pruned
+	if dir := dirRule.OutputDir(nil); dir != "" {
+		filesIn(dir)
+	}
+	for _, artifact := range output.toArtifacts() {
+		dir := dirRule.OutputDir(artifact.Package)
+		if dir == "" {
+			continue
+		}
+		filesIn(dir)[filepath.ToSlash(artifact.ItemPath)] = contentHash(artifact.Contents)
+	}
+}
+
+// prune removes stale files from each output directory (or lists them, if
+// this is a dry run), and updates the directories' manifests.
+func (p *pruner) prune() []error {
+	dirs := make([]string, 0, len(p.produced))
+	for dir := range p.produced {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var errs []error
+	for _, dir := range dirs {
+		if err := p.pruneDir(dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// pruneDir prunes a single output directory.
+func (p *pruner) pruneDir(dir string) error {
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	var manifest outputManifest
+	if rawManifest, err := os.ReadFile(manifestPath); err == nil {
+		if err := json.Unmarshal(rawManifest, &manifest); err != nil {
+			return fmt.Errorf("unable to read %s: %w", manifestPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if manifest.Generators == nil {
+		manifest.Generators = make(map[string]map[string]string)
+	}
+
+	// don't remove files that another generator wrote this time around
+	current := make(map[string]struct{})
+	for _, files := range p.produced[dir] {
+		for file := range files {
+			current[file] = struct{}{}
+		}
+	}
+
+	genKeys := make([]string, 0, len(p.produced[dir]))
+	for genKey := range p.produced[dir] {
+		genKeys = append(genKeys, genKey)
+	}
+	sort.Strings(genKeys)
+	for _, genKey := range genKeys {
+		files := p.produced[dir][genKey]
+		previous := manifest.Generators[genKey]
+		stale := make([]string, 0, len(previous))
+		for file := range previous {
+			if _, isCurrent := current[file]; !isCurrent {
+				stale = append(stale, file)
+			}
+		}
+		sort.Strings(stale)
+
+		for _, file := range stale {
+			path := filepath.Join(dir, filepath.FromSlash(file))
+			contents, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if contentHash(contents) != previous[file] {
+				// it's been modified by hand, so it's not ours any more
+				continue
+			}
+			if p.DryRun {
+				p.diags.warning("", fmt.Sprintf("would remove stale generated file %s", path))
+				// keep track of it so that a later run can remove it
+				files[file] = previous[file]
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		manifest.Generators[genKey] = files
+	}
+
+	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath, append(rawManifest, '\n'), 0644)
+}
+
+// contentHash returns a hex-encoded hash of the given file contents.
+func contentHash(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
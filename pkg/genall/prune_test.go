@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestPrune(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+	inDir := func(path string) string { return filepath.Join(dir, path) }
+
+	run := func(prune *Prune, gen filesGenerator) string {
+		var asGen Generator = gen
+		var errs bytes.Buffer
+		rt := &Runtime{
+			Generators:  Generators{&asGen},
+			OutputRules: OutputRules{Default: OutputArtifacts{Config: OutputToDirectory(dir)}},
+			ErrorWriter: &errs,
+			Prune:       prune,
+		}
+		g.Expect(rt.Run()).To(gomega.BeFalse(), errs.String())
+		return errs.String()
+	}
+
+	g.Expect(os.WriteFile(inDir("handwritten.yaml"), []byte("mine"), 0644)).To(gomega.Succeed())
+	run(&Prune{}, filesGenerator{"a.yaml": "a", "b.yaml": "b", "c.yaml": "c"})
+	g.Expect(inDir(ManifestFileName)).To(gomega.BeAnExistingFile())
+
+	// modified files are left alone
+	g.Expect(os.WriteFile(inDir("c.yaml"), []byte("edited"), 0644)).To(gomega.Succeed())
+
+	// dry runs only list stale files...
+	g.Expect(run(&Prune{DryRun: true}, filesGenerator{"a.yaml": "a"})).To(gomega.Equal(
+		"would remove stale generated file " + inDir("b.yaml") + "\n"))
+	g.Expect(inDir("b.yaml")).To(gomega.BeAnExistingFile())
+
+	// ...which are still removed by later runs
+	g.Expect(run(&Prune{}, filesGenerator{"a.yaml": "a"})).To(gomega.BeEmpty())
+	g.Expect(inDir("a.yaml")).To(gomega.BeAnExistingFile())
+	g.Expect(inDir("b.yaml")).NotTo(gomega.BeAnExistingFile())
+	g.Expect(inDir("c.yaml")).To(gomega.BeAnExistingFile())
+	g.Expect(inDir("handwritten.yaml")).To(gomega.BeAnExistingFile())
+
+	// nothing is removed unless asked for
+	run(nil, filesGenerator{})
+	g.Expect(inDir("a.yaml")).To(gomega.BeAnExistingFile())
+
+	// generators that no longer write anything still get pruned
+	run(&Prune{}, filesGenerator{})
+	g.Expect(inDir("a.yaml")).NotTo(gomega.BeAnExistingFile())
+	g.Expect(inDir("c.yaml")).To(gomega.BeAnExistingFile())
+}
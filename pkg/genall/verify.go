@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// +controllertools:marker:generateHelp:category=""
+
+// VerifyOutput checks that artifacts match the files already on disk, without
+// writing anything.
+//
+// Each out-of-date or missing file is reported as an error, along with a
+// unified diff of the changes generation would make.  Non-package associated
+// artifacts are compared with files in the Dir directory, while
+// package-associated ones are compared with files in their package's source
+// files' directory, unless an alternate path is specified in Code.
+type VerifyOutput struct {
+	// Dir points to the directory containing configuration to check.
+	Dir string `marker:",optional"`
+	// Code overrides the directory containing code to check (defaults to where the existing code lives).
+	Code string `marker:",optional"`
+}
+
+func (o VerifyOutput) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	dir := o.Dir
+	if pkg != nil {
+		switch {
+		case o.Code != "":
+			dir = o.Code
+		case len(pkg.CompiledGoFiles) == 0:
+			return nil, fmt.Errorf("cannot output to a package with no path on disk")
+		default:
+			dir = filepath.Dir(pkg.CompiledGoFiles[0])
+		}
+	}
+	return &verifyingWriter{path: filepath.Join(dir, itemPath)}, nil
+}
+
+// verifyingWriter collects an artifact, comparing it with the existing file
+// when closed.
+type verifyingWriter struct {
+	bytes.Buffer
+	path string
+}
+
+func (w *verifyingWriter) Close() error {
+	generated := w.Bytes()
+	existing, err := os.ReadFile(w.path)
+	oldName := w.path
+	switch {
+	case os.IsNotExist(err):
+		oldName = os.DevNull
+	case err != nil:
+		return err
+	case bytes.Equal(existing, generated):
+		return nil
+	}
+
+	// trim the final newline, since errors get printed on their own line
+	diff := strings.TrimSuffix(unifiedDiff(oldName, w.path, existing, generated), "\n")
+	switch {
+	case oldName == os.DevNull:
+		return fmt.Errorf("%s is missing:\n%s", w.path, diff)
+	case diff == "":
+		// the lines match, so the only difference is a trailing newline
+		return fmt.Errorf("%s is out of date (differs only in trailing newlines)", w.path)
+	default:
+		return fmt.Errorf("%s is out of date:\n%s", w.path, diff)
+	}
+}
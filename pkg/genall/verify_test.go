@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// filesGenerator writes out the given artifacts, none of which are
+// package-associated.
+type filesGenerator map[string]string
+
+func (g filesGenerator) RegisterMarkers(*markers.Registry) error { return nil }
+
+func (g filesGenerator) Generate(ctx *GenerationContext) error {
+	for _, name := range []string{"a.yaml", "b.yaml", "c.yaml"} {
+		contents, present := g[name]
+		if !present {
+			continue
+		}
+		if err := ctx.WriteYAML(name, "", []interface{}{map[string]string{"value": contents}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		line := strings.Repeat("x", i)
+		oldLines = append(oldLines, line)
+		switch i {
+		case 2:
+			newLines = append(newLines, "changed")
+		case 15:
+			// removed
+		default:
+			newLines = append(newLines, line)
+		}
+	}
+	newLines = append(newLines, "added")
+
+	diff := unifiedDiff("old", "new",
+		[]byte(strings.Join(oldLines, "\n")+"\n"), []byte(strings.Join(newLines, "\n")+"\n"))
+	g.Expect(diff).To(gomega.Equal(`--- old
++++ new
+@@ -1,5 +1,5 @@
+ x
+-xx
++changed
+ xxx
+ xxxx
+ xxxxx
+@@ -12,9 +12,9 @@
+ xxxxxxxxxxxx
+ xxxxxxxxxxxxx
+ xxxxxxxxxxxxxx
+-xxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxxxxxx
++added
+`))
+
+	g.Expect(unifiedDiff("old", "new", []byte("a\n"), []byte("a\n"))).To(gomega.BeEmpty())
+	g.Expect(unifiedDiff("/dev/null", "new", nil, []byte("a\nb\n"))).To(gomega.Equal("--- /dev/null\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n"))
+}
+
+func TestDiffLines(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// the script should always reproduce both sides
+	rnd := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rnd.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 200; i++ {
+		a, b := randomLines(), randomLines()
+		gotA, gotB := []string{}, []string{}
+		for _, line := range diffLines(a, b) {
+			if line.kind != '+' {
+				gotA = append(gotA, line.text)
+			}
+			if line.kind != '-' {
+				gotB = append(gotB, line.text)
+			}
+		}
+		g.Expect(gotA).To(gomega.Equal(a))
+		g.Expect(gotB).To(gomega.Equal(b))
+	}
+}
+
+func TestVerifyOutput(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := t.TempDir()
+
+	var gen Generator = filesGenerator{"a.yaml": "same", "b.yaml": "new", "c.yaml": "missing"}
+	rt := &Runtime{
+		Generators:  Generators{&gen},
+		OutputRules: OutputRules{Default: OutputToDirectory(dir)},
+		ErrorWriter: &bytes.Buffer{},
+	}
+	g.Expect(rt.Run()).To(gomega.BeFalse())
+	g.Expect(os.Remove(filepath.Join(dir, "c.yaml"))).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("---\nvalue: old\n"), 0644)).To(gomega.Succeed())
+
+	inDir := func(path string) string { return filepath.Join(dir, path) }
+	var errs bytes.Buffer
+	rt.OutputRules = OutputRules{Default: VerifyOutput{Dir: dir}}
+	rt.ErrorWriter = &errs
+	g.Expect(rt.Run()).To(gomega.BeTrue())
+	g.Expect(errs.String()).To(gomega.Equal(inDir("b.yaml") + " is out of date:\n" +
+		"--- " + inDir("b.yaml") + "\n+++ " + inDir("b.yaml") + "\n@@ -1,2 +1,2 @@\n ---\n-value: old\n+value: new\n" +
+		inDir("c.yaml") + " is missing:\n" +
+		"--- /dev/null\n+++ " + inDir("c.yaml") + "\n@@ -0,0 +1,2 @@\n+---\n+value: missing\n"))
+
+	// nothing should have been written
+	contents, err := os.ReadFile(inDir("b.yaml"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(contents)).To(gomega.Equal("---\nvalue: old\n"))
+	g.Expect(inDir("c.yaml")).NotTo(gomega.BeAnExistingFile())
+
+	// package-associated artifacts (like generated code) are compared with
+	// files next to the package's source, unless Code is set
+	g.Expect(os.WriteFile(inDir("zz_generated.go"), []byte("package foo\n"), 0644)).To(gomega.Succeed())
+	pkg := &loader.Package{Package: &packages.Package{CompiledGoFiles: []string{inDir("types.go")}}}
+	for _, rule := range []VerifyOutput{{}, {Dir: "/nonexistent", Code: dir}} {
+		out, err := rule.Open(pkg, "zz_generated.go")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		_, err = out.Write([]byte("package foo\n"))
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(out.Close()).To(gomega.Succeed())
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"context"
+	"fmt"
+	"go/build/constraint"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// watchDebounce is how long to wait for further changes after seeing one,
+// since editors (and tools like git) often write several files at once.
+var watchDebounce = 200 * time.Millisecond
+
+// Watch runs the generators, then watches the Go files in the root packages'
+// directories, running the generators again whenever they change, until the
+// given context is done.  Errors from each run are printed, rather than
+// stopping the watch.
+//
+// Changed packages, and roots that import them, are reloaded, while other
+// packages (along with their parsed syntax and type information) are reused.
+// Markers and anything shared between generators (like the CRD parser) are
+// collected afresh for every root on each change, unless they're persisted
+// with CacheDir.
+// Generators that work package-by-package (see Cacheable) only run against
+// the reloaded packages, while other generators run against all roots.
+// Creating a directory in a watched directory reloads everything from Paths,
+// to pick up new packages.
+//
+// Watching requires text diagnostics, since structured formats write a
+// single report per run, and those can't be combined with status updates.
+func (r *Runtime) Watch(ctx context.Context) error {
+	if r.ErrorWriter == nil {
+		r.ErrorWriter = os.Stderr
+	}
+	if r.DiagnosticsFormat != DiagnosticsText {
+		return fmt.Errorf("watching requires text diagnostics, not %s", r.DiagnosticsFormat)
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsWatcher.Close()
+	watcher := &sourceWatcher{
+		watcher: fsWatcher,
+		watched: make(map[string]struct{}),
+		hashes:  make(map[string]string),
+	}
+	if err := watcher.watch(r.Roots); err != nil {
+		return err
+	}
+
+	baseErrors := make(map[*packages.Package]int)
+	countErrors(r.Roots, baseErrors)
+	r.Run()
+	fmt.Fprintln(r.ErrorWriter, "watching for changes...")
+
+	for {
+		changed, newDirs, err := watcher.wait(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var reloaded []*loader.Package
+		if newDirs && len(r.Paths) > 0 {
+			roots, err := loader.LoadRoots(r.Paths...)
+			if err != nil {
+				fmt.Fprintln(r.ErrorWriter, err)
+				continue
+			}
+			r.Roots, reloaded = roots, roots
+			r.rootsFor = nil
+		} else {
+			roots, reloadedRoots, err := loader.Reload(r.Roots, changed...)
+			if err != nil {
+				fmt.Fprintln(r.ErrorWriter, err)
+				continue
+			}
+			if len(reloadedRoots) == 0 {
+				continue
+			}
+			r.Roots, reloaded = roots, reloadedRoots
+			r.rootsFor = r.perPackageRoots(reloaded)
+		}
+		if err := watcher.watch(r.Roots); err != nil {
+			return err
+		}
+
+		// clear out errors from the last run, so they're not reported twice
+		countErrors(reloaded, baseErrors)
+		for pkg, numErrors := range baseErrors {
+			pkg.Errors = pkg.Errors[:numErrors]
+		}
+		// markers are collected by package ID, so reloaded packages would
+		// otherwise see the markers (and syntax) from before the change.
+		r.Collector = &markers.Collector{Registry: r.Collector.Registry, CheckUnknown: r.Collector.CheckUnknown}
+		r.Cache = &Cache{}
+
+		ids := make([]string, len(reloaded))
+		for i, pkg := range reloaded {
+			ids[i] = pkg.ID
+		}
+		fmt.Fprintf(r.ErrorWriter, "regenerating for changes in %s\n", strings.Join(ids, ", "))
+		r.Run()
+	}
+}
+
+// perPackageRoots restricts generators whose output is produced package by
+// package to the given roots.  Generators using bundle output rules aren't
+// restricted, since they need everything to write out a complete bundle.
+func (r *Runtime) perPackageRoots(roots []*loader.Package) map[*Generator][]*loader.Package {
+	rootsFor := make(map[*Generator][]*loader.Package)
+	for _, gen := range r.Generators {
+		scoped, isCacheable := (*gen).(Cacheable)
+		if !isCacheable || scoped.CacheScope() != CachePerPackage {
+			continue
+		}
+		if _, isBundle := r.OutputRules.ForGenerator(gen).(BundleOutputRule); isBundle {
+			continue
+		}
+		rootsFor[gen] = roots
+	}
+	return rootsFor
+}
+
+// countErrors records the number of errors each package reachable from the
+// given roots has before any generators run, so that errors added by
+// generators can be cleared out before running them again.
+func countErrors(roots []*loader.Package, counts map[*packages.Package]int) {
+	rawRoots := make([]*packages.Package, len(roots))
+	for i, root := range roots {
+		// parse errors only get reported once, so make sure they're counted
+		root.NeedSyntax()
+		rawRoots[i] = root.Package
+	}
+	packages.Visit(rawRoots, func(pkg *packages.Package) bool {
+		if _, seen := counts[pkg]; seen {
+			return false
+		}
+		counts[pkg] = len(pkg.Errors)
+		return true
+	}, nil)
+}
+
+// sourceWatcher watches the directories of root packages for changes to Go
+// source files.
+type sourceWatcher struct {
+	watcher *fsnotify.Watcher
+	watched map[string]struct{}
+	// hashes holds the content hashes of the Go files in watched
+	// directories, so that writes that don't change anything (like
+	// generated code being written out again) can be ignored.
+	hashes map[string]string
+}
+
+// watch starts watching the directories of the given roots, if it isn't
+// already.
+func (w *sourceWatcher) watch(roots []*loader.Package) error {
+	for _, root := range roots {
+		dir := root.Dir()
+		if _, watched := w.watched[dir]; dir == "" || watched {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			return err
+		}
+		w.watched[dir] = struct{}{}
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if contents, err := os.ReadFile(file); err == nil {
+				w.hashes[file] = contentHash(contents)
+			}
+		}
+	}
+	return nil
+}
+
+// wait waits for Go files to change, returning the changed files (sorted),
+// and whether or not any directories were created.
+func (w *sourceWatcher) wait(ctx context.Context) ([]string, bool, error) {
+	touched := make(map[string]struct{})
+	newDirs := false
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case err := <-w.watcher.Errors:
+			return nil, false, err
+		case event := <-w.watcher.Events:
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if event.Op&fsnotify.Create != 0 {
+					newDirs = true
+				}
+			} else if isSourceFile(event.Name) {
+				// the contents are only checked once things settle, since
+				// files are often truncated before being written
+				touched[event.Name] = struct{}{}
+			}
+			if (len(touched) > 0 || newDirs) && settled == nil {
+				settled = time.After(watchDebounce)
+			}
+		case <-settled:
+			files := make([]string, 0, len(touched))
+			for file := range touched {
+				if w.sourceChanged(file) {
+					files = append(files, file)
+				}
+			}
+			if len(files) == 0 && !newDirs {
+				touched, settled = make(map[string]struct{}), nil
+				continue
+			}
+			sort.Strings(files)
+			return files, newDirs, nil
+		}
+	}
+}
+
+// isSourceFile checks if the given file is a (non-test) Go source file.
+func isSourceFile(file string) bool {
+	return filepath.Ext(file) == ".go" && !strings.HasSuffix(file, "_test.go")
+}
+
+// sourceChanged checks if the contents of the given Go source file have
+// changed since it was last seen.
+func (w *sourceWatcher) sourceChanged(file string) bool {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		// removed (or at least unreadable)
+		_, known := w.hashes[file]
+		delete(w.hashes, file)
+		return known
+	}
+	if ignoredWhenLoading(contents) {
+		// generated code, which is what we just wrote out
+		return false
+	}
+	hash := contentHash(contents)
+	if w.hashes[file] == hash {
+		return false
+	}
+	w.hashes[file] = hash
+	return true
+}
+
+// ignoredWhenLoading checks if the given Go source is excluded by the
+// ignore_autogenerated build tag that the loader sets, as generated code
+// (e.g. deepcopy implementations) is.
+func ignoredWhenLoading(contents []byte) bool {
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			return false
+		}
+		// only count it if it's the tag itself that excludes the file
+		withTag := expr.Eval(func(string) bool { return true })
+		withoutTag := expr.Eval(func(tag string) bool { return tag != "ignore_autogenerated" })
+		return !withTag && withoutTag
+	}
+	return false
+}
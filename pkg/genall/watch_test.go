@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// reportingGenerator sends the names of the roots it's run for.
+type reportingGenerator struct {
+	scope CacheScope
+	runs  chan<- []string
+}
+
+func (g reportingGenerator) RegisterMarkers(*markers.Registry) error { return nil }
+func (g reportingGenerator) CacheScope() CacheScope                  { return g.scope }
+
+func (g reportingGenerator) Generate(ctx *GenerationContext) error {
+	var names []string
+	for _, root := range ctx.Roots {
+		names = append(names, root.Name)
+	}
+	g.runs <- names
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		g.Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(gomega.Succeed())
+		g.Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(gomega.Succeed())
+	}
+	writeFile("go.mod", "module example.com/watched\n\ngo 1.19\n")
+	writeFile("a/a.go", "package a\n\ntype A struct{}\n")
+	writeFile("b/b.go", "package b\n\nimport _ \"example.com/watched/c\"\n\ntype B struct{}\n")
+	writeFile("c/c.go", "package c\n\ntype C struct{}\n")
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	perRunRuns, perPackageRuns := make(chan []string, 10), make(chan []string, 10)
+	var perRun, perPackage Generator = reportingGenerator{scope: CachePerRun, runs: perRunRuns},
+		reportingGenerator{scope: CachePerPackage, runs: perPackageRuns}
+	rt, err := Generators{&perRun, &perPackage}.ForRoots("./a", "./b", "./c")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	rt.ErrorWriter = ioutil.Discard
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- rt.Watch(ctx) }()
+
+	// everything runs at first
+	g.Eventually(perRunRuns, 10*time.Second).Should(gomega.Receive(gomega.Equal([]string{"a", "b", "c"})))
+	g.Eventually(perPackageRuns).Should(gomega.Receive(gomega.Equal([]string{"a", "b", "c"})))
+
+	// changing a package reruns per-package generators for it and its importers
+	writeFile("c/c.go", "package c\n\ntype C struct{ Changed bool }\n")
+	g.Eventually(perRunRuns, 10*time.Second).Should(gomega.Receive(gomega.Equal([]string{"a", "b", "c"})))
+	g.Eventually(perPackageRuns).Should(gomega.Receive(gomega.Equal([]string{"b", "c"})))
+
+	// writes that don't change anything are ignored
+	writeFile("c/c.go", "package c\n\ntype C struct{ Changed bool }\n")
+	writeFile("a/notes.txt", "not go code")
+	g.Consistently(perRunRuns, time.Second).ShouldNot(gomega.Receive())
+
+	cancel()
+	g.Eventually(done, 5*time.Second).Should(gomega.Receive(gomega.BeNil()))
+}
+
+func TestWatchWithCache(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		g.Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(gomega.Succeed())
+		g.Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(gomega.Succeed())
+	}
+	writeFile("go.mod", "module example.com/watched\n\ngo 1.19\n")
+	writeFile("a/a.go", "package a\n\ntype A struct{}\n")
+	writeFile("b/b.go", "package b\n\ntype B struct{}\n")
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	runs := make(chan []string, 10)
+	var perPackage Generator = reportingGenerator{scope: CachePerPackage, runs: runs}
+	rt, err := Generators{&perPackage}.ForRoots("./a", "./b")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	rt.ErrorWriter = ioutil.Discard
+	rt.CacheDir = t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- rt.Watch(ctx) }()
+
+	g.Eventually(runs, 10*time.Second).Should(gomega.Receive(gomega.Equal([]string{"a", "b"})))
+
+	// only the changed package is cached afresh, the rest were cached by the first run
+	writeFile("b/b.go", "package b\n\ntype B struct{ Changed bool }\n")
+	g.Eventually(runs, 10*time.Second).Should(gomega.Receive(gomega.Equal([]string{"b"})))
+
+	// and the cache is still usable afterwards
+	writeFile("a/a.go", "package a\n\ntype A struct{ Changed bool }\n")
+	g.Eventually(runs, 10*time.Second).Should(gomega.Receive(gomega.Equal([]string{"a"})))
+
+	cancel()
+	g.Eventually(done, 5*time.Second).Should(gomega.Receive(gomega.BeNil()))
+}
+
+func TestWatchStructuredDiagnostics(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var errs bytes.Buffer
+	rt := &Runtime{ErrorWriter: &errs, DiagnosticsFormat: DiagnosticsJSON}
+	g.Expect(rt.Watch(context.Background())).To(gomega.MatchError(gomega.ContainSubstring("requires text diagnostics")))
+	g.Expect(errs.String()).To(gomega.BeEmpty())
+}
@@ -25,6 +25,33 @@ import (
 	"sigs.k8s.io/controller-tools/pkg/markers"
 )
 
+func (CacheDir) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "specifies a directory in which to cache generator output between runs. ",
+			Details: "Generators whose options, input packages (and their dependencies), and input files haven't changed since an earlier successful run are skipped, and their earlier output is written instead.  The markers collected from each package, and the schemata computed for its types, are cached too, so generators that do run only recompute them for packages that changed. Entries are keyed by content (and the build of controller-gen), not paths or times, so the directory may be shared (e.g. in CI), and it's always safe to delete it.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{},
+	}
+}
+
+func (CheckMarkers) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "reports markers that look like they're meant for a generator, but aren't valid: ones with a typo in their name (suggesting what was probably meant), and ones used in the wrong place, like a field marker on a type. ",
+			Details: "Only markers that share a prefix with a known marker (like `kubebuilder:` or `groupName`) are checked.  Markers of any available generator are known, not just ones being run.  Markers belonging to other tools that use the same prefixes, like kubebuilder's scaffolding markers and the Kubernetes code generators' `k8s:` markers, are allowed.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Allow": {
+				Summary: "lists other markers that belong to other tools. ",
+				Details: "Each entry allows markers with that name, or whose names start with it followed by a `:`.",
+			},
+		},
+	}
+}
+
 func (InputPaths) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "",
@@ -36,6 +63,22 @@ func (InputPaths) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (OutputArchive) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "outputs all artifacts as a gzipped tarball, ordered by path. ",
+			Details: "Package-associated artifacts (like generated code) are nested under their package's import path.  Timestamps and ownership are left unset, so the same artifacts always produce the same archive.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Path": {
+				Summary: "is the .tar.gz file to write.",
+				Details: "",
+			},
+		},
+	}
+}
+
 func (OutputArtifacts) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "",
@@ -56,6 +99,70 @@ func (OutputArtifacts) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (OutputBundle) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "outputs all YAML artifacts as a single multi-document YAML file, ordered by path. ",
+			Details: "Kustomization files are left out of the bundle, and non-YAML artifacts (like generated code) cause an error.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Path": {
+				Summary: "is the file to write the bundle to (defaults to standard out).",
+				Details: "",
+			},
+		},
+	}
+}
+
+func (OutputHelmChart) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "outputs artifacts into the layout of a Helm chart. ",
+			Details: "CustomResourceDefinitions are written to the chart's crds directory as-is, while RBAC and webhook configuration objects are written to its templates directory, with their names, namespaces, service references and CA injection annotations replaced by template expressions.  Other objects are written to templates unchanged, and YAML that doesn't describe objects (like kustomizations) is written to the chart's root directory. Package-associated artifacts (like generated code) are written to their package's source files' directory.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Dir": {
+				Summary: "is the root directory of the chart.",
+				Details: "",
+			},
+			"NamePrefix": {
+				Summary: "is prepended to the names of RBAC and webhook objects (defaults to `{{ .Release.Name }}-`).",
+				Details: "",
+			},
+			"Namespace": {
+				Summary: "replaces the namespace of namespaced RBAC objects, service account subjects and webhook services (defaults to `{{ .Release.Namespace }}`).",
+				Details: "",
+			},
+			"Service": {
+				Summary: "replaces the name of the service webhooks are served by (defaults to `{{ .Release.Name }}-webhook-service`).",
+				Details: "",
+			},
+			"CAInjectFrom": {
+				Summary: "is the cert-manager.io/inject-ca-from annotation added to webhook configurations, used to fill in their caBundles (defaults to `{{ .Release.Namespace }}/{{ .Release.Name }}-serving-cert`).",
+				Details: "",
+			},
+		},
+	}
+}
+
+func (OutputJSONStream) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "outputs all artifacts as a stream of JSON records, one per line and ordered by path, for consumption by other tools. ",
+			Details: "Each record has a \"path\" field (with package-associated artifacts nested under their package's import path) and a \"contents\" field.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Path": {
+				Summary: "is the file to write the stream to (defaults to standard out).",
+				Details: "",
+			},
+		},
+	}
+}
+
 func (OutputToDirectory) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "",
@@ -67,6 +174,42 @@ func (OutputToDirectory) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (Prune) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "removes files generated by earlier runs that are no longer generated. ",
+			Details: "The files each generator writes to an output directory (like with output:dir or output:artifacts) are recorded in a manifest in that directory.  On later runs, files a generator previously wrote but didn't write this time are deleted, as long as they haven't been modified since. Files that aren't in the manifest, like hand-written ones, are never touched.  Nothing is pruned if there were any errors.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"DryRun": {
+				Summary: "lists the files that would be removed, instead of removing them.",
+				Details: "",
+			},
+		},
+	}
+}
+
+func (VerifyOutput) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "checks that artifacts match the files already on disk, without writing anything. ",
+			Details: "Each out-of-date or missing file is reported as an error, along with a unified diff of the changes generation would make.  Non-package associated artifacts are compared with files in the Dir directory, while package-associated ones are compared with files in their package's source files' directory, unless an alternate path is specified in Code.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Dir": {
+				Summary: "points to the directory containing configuration to check.",
+				Details: "",
+			},
+			"Code": {
+				Summary: "overrides the directory containing code to check (defaults to where the existing code lives).",
+				Details: "",
+			},
+		},
+	}
+}
+
 func (outputToNothing) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "",
@@ -0,0 +1,45 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSortedErrors(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	errs := []packages.Error{
+		{Pos: "/src/b.go:2:1", Msg: "b"},
+		{Pos: "/src/a.go:10:3", Msg: "a10"},
+		{Pos: "some/pkg:-", Msg: "unpositioned"},
+		{Pos: "/src/a.go:9:5", Msg: "a9 second"},
+		{Pos: "/src/a.go:9:5", Msg: "a9 first"},
+		{Pos: "/src/a.go:9", Msg: "a9 no column"},
+	}
+	var msgs []string
+	for _, err := range sortedErrors(errs) {
+		msgs = append(msgs, err.Msg)
+	}
+	g.Expect(msgs).To(gomega.Equal([]string{"a9 no column", "a9 first", "a9 second", "a10", "b", "unpositioned"}))
+
+	// the original is left alone
+	g.Expect(errs[0].Msg).To(gomega.Equal("b"))
+}
@@ -27,6 +27,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"golang.org/x/tools/go/packages"
@@ -42,6 +45,10 @@ import (
 // packages and traversing through all imports.  It will skip
 // any errors of the kinds specified in filterKinds.  It will
 // return true if any errors were printed.
+//
+// Errors for each package are printed in order of position,
+// so that the output is the same no matter what order they
+// were added in (e.g. by generators running in parallel).
 func PrintErrors(pkgs []*Package, filterKinds ...packages.ErrorKind) bool {
 	pkgsRaw := make([]*packages.Package, len(pkgs))
 	for i, pkg := range pkgs {
@@ -53,7 +60,7 @@ func PrintErrors(pkgs []*Package, filterKinds ...packages.ErrorKind) bool {
 	}
 	hadErrors := false
 	packages.Visit(pkgsRaw, nil, func(pkgRaw *packages.Package) {
-		for _, err := range pkgRaw.Errors {
+		for _, err := range sortedErrors(pkgRaw.Errors) {
 			if _, skip := toSkip[err.Kind]; skip {
 				continue
 			}
@@ -64,12 +71,67 @@ func PrintErrors(pkgs []*Package, filterKinds ...packages.ErrorKind) bool {
 	return hadErrors
 }
 
+// sortedErrors returns a copy of the given errors, sorted by position (file,
+// then line, then column), and then by message.
+func sortedErrors(errs []packages.Error) []packages.Error {
+	sorted := append([]packages.Error(nil), errs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		fileI, lineI, colI := splitErrorPos(sorted[i].Pos)
+		fileJ, lineJ, colJ := splitErrorPos(sorted[j].Pos)
+		switch {
+		case fileI != fileJ:
+			return fileI < fileJ
+		case lineI != lineJ:
+			return lineI < lineJ
+		case colI != colJ:
+			return colI < colJ
+		default:
+			return sorted[i].Msg < sorted[j].Msg
+		}
+	})
+	return sorted
+}
+
+// ErrorPosition returns the file, line and column of the given error, as
+// reported in its position.  Missing line and column numbers are returned as
+// zero.
+func ErrorPosition(err packages.Error) (file string, line, col int) {
+	return splitErrorPos(err.Pos)
+}
+
+// splitErrorPos splits the position of a packages.Error (`file:line:col`,
+// `file:line`, `file`, or `-`) into its parts.  Missing (or unparsable) line
+// and column numbers are returned as zero.
+func splitErrorPos(pos string) (file string, line, col int) {
+	parts := strings.Split(pos, ":")
+	nums := make([]int, 0, 2)
+	for len(parts) > 1 && len(nums) < 2 {
+		num, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			break
+		}
+		nums = append([]int{num}, nums...)
+		parts = parts[:len(parts)-1]
+	}
+	file = strings.Join(parts, ":")
+	switch len(nums) {
+	case 2:
+		line, col = nums[0], nums[1]
+	case 1:
+		line = nums[0]
+	}
+	return file, line, col
+}
+
 // Package is a single, unique Go package that can be
 // lazily parsed and type-checked.  Packages should not
 // be constructed directly -- instead, use LoadRoots.
 // For a given call to LoadRoots, only a single instance
 // of each package exists, and thus they may be used as keys
 // and for comparison.
+//
+// The methods on Package are safe to call concurrently, so packages may be
+// shared between generators running in parallel.
 type Package struct {
 	*packages.Package
 
@@ -77,11 +139,23 @@ type Package struct {
 
 	loader *loader
 	sync.Mutex
+
+	// importsMu, syntaxMu, and typesMu guard lazily loading imports,
+	// syntax, and type-checking information, respectively.  They're
+	// separate from the embedded mutex, which is held while type-checking.
+	importsMu sync.Mutex
+	syntaxMu  sync.Mutex
+	typesMu   sync.Mutex
+	// errorsMu guards Errors.
+	errorsMu sync.Mutex
 }
 
 // Imports returns the imports for the given package, indexed by
 // package path (*not* name in any particular file).
 func (p *Package) Imports() map[string]*Package {
+	p.importsMu.Lock()
+	defer p.importsMu.Unlock()
+
 	if p.imports == nil {
 		p.imports = p.loader.packagesFor(p.Package.Imports)
 	}
@@ -92,6 +166,9 @@ func (p *Package) Imports() map[string]*Package {
 // NeedTypesInfo indicates that type-checking information is needed for this package.
 // Actual type-checking information can be accessed via the Types and TypesInfo fields.
 func (p *Package) NeedTypesInfo() {
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
+
 	if p.TypesInfo != nil {
 		return
 	}
@@ -102,6 +179,9 @@ func (p *Package) NeedTypesInfo() {
 // NeedSyntax indicates that a parsed AST is needed for this package.
 // Actual ASTs can be accessed via the Syntax field.
 func (p *Package) NeedSyntax() {
+	p.syntaxMu.Lock()
+	defer p.syntaxMu.Unlock()
+
 	if p.Syntax != nil {
 		return
 	}
@@ -134,6 +214,14 @@ func (p *Package) NeedSyntax() {
 
 // AddError adds an error to the errors associated with the given package.
 func (p *Package) AddError(err error) {
+	p.errorsMu.Lock()
+	defer p.errorsMu.Unlock()
+
+	p.addError(err)
+}
+
+// addError is the implementation of AddError, without locking.
+func (p *Package) addError(err error) {
 	switch typedErr := err.(type) {
 	case *os.PathError:
 		// file-reading errors
@@ -160,7 +248,7 @@ func (p *Package) AddError(err error) {
 		})
 	case ErrList:
 		for _, subErr := range typedErr {
-			p.AddError(subErr)
+			p.addError(subErr)
 		}
 	case PositionedError:
 		p.Errors = append(p.Errors, packages.Error{
@@ -319,7 +407,7 @@ func (l *loader) parseFile(filename string, src []byte) (*ast.File, error) {
 // LoadRoots loads the given "root" packages by path, transitively loading
 // and all imports as well.
 //
-// Loaded packages will have type size, imports, and exports file information
+// Loaded packages will have type size, imports, module, and exports file information
 // populated.  Additional information, like ASTs and type-checking information,
 // can be accessed via methods on individual packages.
 func LoadRoots(roots ...string) ([]*Package, error) {
@@ -371,7 +459,7 @@ func LoadRootsWithConfig(cfg *packages.Config, roots ...string) ([]*Package, err
 		cfg:      cfg,
 		packages: make(map[*packages.Package]*Package),
 	}
-	l.cfg.Mode |= packages.LoadImports | packages.NeedTypesSizes
+	l.cfg.Mode |= packages.LoadImports | packages.NeedTypesSizes | packages.NeedModule
 	if l.cfg.Fset == nil {
 		l.cfg.Fset = token.NewFileSet()
 	}
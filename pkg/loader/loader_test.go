@@ -17,7 +17,12 @@ limitations under the License.
 package loader_test
 
 import (
+	"fmt"
+	"go/ast"
+	"go/types"
 	"os"
+	"strings"
+	"sync"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -189,3 +194,44 @@ var _ = Describe("Loader parsing root module", func() {
 		})
 	})
 })
+
+var _ = Describe("Loaded packages", func() {
+	It("should be safe to load syntax and type information for, and add errors to, concurrently", func() {
+		pkgs, err := loader.LoadRoots("../markers")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+		pkg := pkgs[0]
+
+		const workers = 8
+		syntaxes := make([][]*ast.File, workers)
+		typesInfos := make([]*types.Info, workers)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer GinkgoRecover()
+				pkg.NeedTypesInfo()
+				syntaxes[i] = pkg.Syntax
+				typesInfos[i] = pkg.TypesInfo
+				pkg.AddError(fmt.Errorf("error from worker %d", i))
+			}(i)
+		}
+		wg.Wait()
+
+		By("checking that everyone saw the same syntax and type information")
+		for i := 0; i < workers; i++ {
+			Expect(syntaxes[i]).To(Equal(pkg.Syntax))
+			Expect(typesInfos[i]).To(BeIdenticalTo(pkg.TypesInfo))
+		}
+
+		By("checking that no errors were lost")
+		var workerErrs []string
+		for _, err := range pkg.Errors {
+			if strings.HasPrefix(err.Msg, "error from worker") {
+				workerErrs = append(workerErrs, err.Msg)
+			}
+		}
+		Expect(workerErrs).To(HaveLen(workers))
+	})
+})
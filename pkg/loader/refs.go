@@ -206,14 +206,13 @@ type TypeChecker struct {
 
 // Check type-checks the given package and all packages referenced by types
 // that pass through (have true returned by) any of the NodeFilters.
+// It's safe to call Check concurrently (e.g. from different generators).
 func (c *TypeChecker) Check(root *Package) {
+	c.Lock()
 	c.init()
+	c.Unlock()
 
-	// use a sub-checker with the appropriate settings
-	(&TypeChecker{
-		NodeFilters:     c.NodeFilters,
-		checkedPackages: c.checkedPackages,
-	}).check(root)
+	c.check(root)
 }
 
 func (c *TypeChecker) isNodeInteresting(node ast.Node) bool {
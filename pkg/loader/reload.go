@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Dir returns the directory containing the package's source files, or an
+// empty string if it has none.
+func (p *Package) Dir() string {
+	files := p.GoFiles
+	if len(files) == 0 {
+		files = p.CompiledGoFiles
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	return filepath.Dir(files[0])
+}
+
+// Reload loads the root packages in the directories of the given changed
+// files again, along with any roots that import them (directly or not).
+// It returns the updated roots, in the same order as before, and the
+// packages that were reloaded.
+//
+// The remaining roots are reused as-is, along with anything already parsed
+// or type-checked for them, and reloaded packages refer to them when
+// importing them.  Changed files that aren't in any root's directory are
+// ignored -- finding new packages requires calling LoadRoots again.
+func Reload(roots []*Package, changedFiles ...string) ([]*Package, []*Package, error) {
+	changedDirs := make(map[string]struct{}, len(changedFiles))
+	for _, file := range changedFiles {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		changedDirs[filepath.Dir(file)] = struct{}{}
+	}
+	changedIDs := make(map[string]struct{})
+	for _, root := range roots {
+		if _, changed := changedDirs[root.Dir()]; changed {
+			changedIDs[root.ID] = struct{}{}
+		}
+	}
+	if len(changedIDs) == 0 {
+		return roots, nil, nil
+	}
+
+	// find everything that (transitively) imports a changed package
+	importsChanged := make(map[*packages.Package]bool)
+	var visit func(pkg *packages.Package) bool
+	visit = func(pkg *packages.Package) bool {
+		if result, seen := importsChanged[pkg]; seen {
+			return result
+		}
+		importsChanged[pkg] = false // break cycles
+		_, result := changedIDs[pkg.ID]
+		for _, imported := range pkg.Imports {
+			if visit(imported) {
+				result = true
+			}
+		}
+		importsChanged[pkg] = result
+		return result
+	}
+	var toReload []string
+	for _, root := range roots {
+		if visit(root.Package) {
+			toReload = append(toReload, root.Dir())
+		}
+	}
+
+	// load with the same settings as the original roots, sharing the fileset
+	// so that positions from reused and reloaded packages can be mixed.
+	cfg := *roots[0].loader.cfg
+	// LoadRootsWithConfig adds its own build tags back in
+	cfg.BuildFlags = append([]string(nil), cfg.BuildFlags[2:]...)
+	cfg.Dir = ""
+	loaded, err := LoadRootsWithConfig(&cfg, toReload...)
+	if err != nil {
+		return nil, nil, err
+	}
+	loadedByID := make(map[string]*Package, len(loaded))
+	for _, pkg := range loaded {
+		loadedByID[pkg.ID] = pkg
+	}
+
+	newRoots := make([]*Package, 0, len(roots))
+	var reloaded []*Package
+	for _, root := range roots {
+		if !importsChanged[root.Package] {
+			newRoots = append(newRoots, root)
+			continue
+		}
+		// packages that no longer exist are dropped
+		if pkg, stillExists := loadedByID[root.ID]; stillExists {
+			newRoots = append(newRoots, pkg)
+			reloaded = append(reloaded, pkg)
+		}
+	}
+	for _, pkg := range reloaded {
+		visitImports(newRoots, pkg, nil)
+	}
+	return newRoots, reloaded, nil
+}
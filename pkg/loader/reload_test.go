@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestReload(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		g.Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(gomega.Succeed())
+		g.Expect(os.WriteFile(path, []byte(contents), 0644)).To(gomega.Succeed())
+	}
+	writeFile("go.mod", "module example.com/reload\n\ngo 1.19\n")
+	writeFile("a/a.go", "package a\n\nimport \"example.com/reload/c\"\n\ntype A struct{ C c.C }\n")
+	writeFile("b/b.go", "package b\n\nimport \"example.com/reload/c\"\n\ntype B struct{ C c.C }\n")
+	writeFile("c/c.go", "package c\n\ntype C struct{}\n")
+	writeFile("d/d.go", "package d\n\ntype D struct{}\n")
+
+	roots, err := LoadRoots(filepath.Join(dir, "..."))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(roots).To(gomega.HaveLen(4))
+	byName := func(roots []*Package) map[string]*Package {
+		out := make(map[string]*Package, len(roots))
+		for _, root := range roots {
+			out[root.Name] = root
+		}
+		return out
+	}
+	d := byName(roots)["d"]
+	d.NeedTypesInfo()
+
+	// nothing in a root directory changed
+	newRoots, reloaded, err := Reload(roots, filepath.Join(dir, "go.mod"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(newRoots).To(gomega.Equal(roots))
+	g.Expect(reloaded).To(gomega.BeEmpty())
+
+	// reload a changed package, and everything that imports it
+	writeFile("c/c.go", "package c\n\ntype C struct{ Changed bool }\n")
+	writeFile("d/d.go", "package d\n\nimport \"example.com/reload/b\"\n\ntype D struct{ B b.B }\n")
+	newRoots, reloaded, err = Reload(roots, filepath.Join(dir, "c", "c.go"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(newRoots).To(gomega.HaveLen(4))
+	var reloadedNames []string
+	for _, pkg := range reloaded {
+		reloadedNames = append(reloadedNames, pkg.Name)
+		g.Expect(roots).NotTo(gomega.ContainElement(gomega.BeIdenticalTo(pkg)))
+	}
+	g.Expect(reloadedNames).To(gomega.ConsistOf("a", "b", "c"))
+	for i, root := range roots {
+		g.Expect(newRoots[i].ID).To(gomega.Equal(root.ID), "roots should stay in the same order")
+	}
+
+	// d didn't change (as far as we know), so it's reused as-is
+	newByName := byName(newRoots)
+	g.Expect(newByName["d"]).To(gomega.BeIdenticalTo(d))
+	g.Expect(d.Types.Scope().Lookup("D")).NotTo(gomega.BeNil())
+
+	// reloaded packages import the other roots, and see the changes
+	newC := newByName["c"]
+	g.Expect(newByName["a"].Imports()[newC.ID]).To(gomega.BeIdenticalTo(newC))
+	newC.NeedTypesInfo()
+	g.Expect(newC.Types.Scope().Lookup("C").Type().Underlying().String()).To(gomega.Equal("struct{Changed bool}"))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lsp
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/url"
+	"path/filepath"
+	"reflect"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// findType finds where the given marker type is declared, resolving its
+// package from the given directory (so that the copy of controller-tools
+// that the project uses is preferred).  It returns nil if the type can't be
+// found.
+func findType(typ reflect.Type, dir string) *location {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.PkgPath() == "" || typ.Name() == "" {
+		return nil
+	}
+
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles, Dir: dir}, typ.PkgPath())
+	if err != nil || len(pkgs) != 1 {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	for _, path := range pkgs[0].GoFiles {
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, isGenDecl := decl.(*ast.GenDecl)
+			if !isGenDecl || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Name.Name != typ.Name() {
+					continue
+				}
+				start, end := fset.Position(typeSpec.Name.Pos()), fset.Position(typeSpec.Name.End())
+				return &location{
+					URI: pathToURI(path),
+					Range: textRange{
+						// columns from go/token are bytes, but type names
+						// are nearly always ASCII, so close enough
+						Start: position{Line: start.Line - 1, Character: start.Column - 1},
+						End:   position{Line: end.Line - 1, Character: end.Column - 1},
+					},
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pathToURI converts an absolute path into a file URI.
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// dirOf returns the directory containing the document with the given URI, or
+// the empty string (meaning the working directory) if it isn't a file.
+func dirOf(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return ""
+	}
+	return filepath.Dir(filepath.FromSlash(parsed.Path))
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lsp implements a language server for markers, speaking the
+// Language Server Protocol over a pair of streams (usually stdin and stdout),
+// so that it works with any editor that supports LSP.
+//
+// Given a marker Registry (and the help registered with it), the server
+// provides:
+//
+//   - completion of marker names, and of argument names for markers with
+//     named arguments
+//   - hover documentation for markers
+//   - diagnostics for markers that fail to parse
+//   - go-to-definition from a marker to the Go type that defines it
+//
+// The server only looks at the text of open documents, and doesn't load or
+// type-check packages, so it only knows which markers a comment might be,
+// not which node it'll be associated with.  Markers that aren't in the
+// registry are left alone, since they probably belong to other tools.
+package lsp
@@ -0,0 +1,284 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lsp
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/genall/help"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// markerComment is a comment containing a marker in a document.
+type markerComment struct {
+	// line is the (zero-based) line the comment is on.
+	line int
+	// start and end are the byte offsets in the line of the start of the
+	// marker (the `+`) and the end of its text.
+	start, end int
+	// text is the marker itself, starting with the `+`, as passed to
+	// Registry.Lookup and Definition.Parse.
+	text string
+}
+
+// findMarkers finds the marker comments in the given Go source.  Anything
+// that can't be scanned is skipped, since documents being edited often
+// aren't valid Go.
+func findMarkers(src string) []markerComment {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), func(token.Position, string) {}, scanner.ScanComments)
+
+	var res []markerComment
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return res
+		}
+		if tok != token.COMMENT || !strings.HasPrefix(lit, "//") {
+			continue
+		}
+		body := strings.TrimLeft(lit[2:], " \t")
+		if !strings.HasPrefix(body, "+") {
+			continue
+		}
+		position := file.Position(pos)
+		start := position.Column - 1 + len(lit) - len(body)
+		text := strings.TrimRight(body, " \t\r")
+		res = append(res, markerComment{
+			line:  position.Line - 1,
+			start: start,
+			end:   start + len(text),
+			text:  text,
+		})
+	}
+}
+
+// markerOnLine returns the marker on the given line containing the given
+// byte offset, if any.
+func markerOnLine(found []markerComment, line, offset int) *markerComment {
+	for i, marker := range found {
+		if marker.line == line && marker.start <= offset && offset <= marker.end {
+			return &found[i]
+		}
+	}
+	return nil
+}
+
+// allTargets are all the kinds of nodes a marker can describe.
+var allTargets = []markers.TargetType{markers.DescribesPackage, markers.DescribesType, markers.DescribesField}
+
+// definitionsFor returns the definitions the given marker could have (one
+// per target it's registered for).
+func definitionsFor(reg *markers.Registry, markerText string) []*markers.Definition {
+	var defs []*markers.Definition
+	for _, target := range allTargets {
+		if def := reg.Lookup(markerText, target); def != nil {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// parseError returns the error from parsing the given marker, if it's a
+// known marker that can't be parsed for any of the targets it's registered
+// for.
+func parseError(reg *markers.Registry, markerText string) error {
+	var firstErr error
+	for _, def := range definitionsFor(reg, markerText) {
+		_, err := def.Parse(markerText)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// markerDocs returns Markdown documentation for the given definitions of a
+// marker.
+func markerDocs(reg *markers.Registry, defs []*markers.Definition) string {
+	targets := make([]string, len(defs))
+	for i, def := range defs {
+		targets[i] = def.Target.String()
+	}
+	doc := help.ForDefinition(defs[0], reg.HelpFor(defs[0]))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "`+%s` (%s)", doc.Name, strings.Join(targets, ", "))
+	if doc.Summary != "" {
+		fmt.Fprintf(&out, "\n\n%s", doc.Summary)
+	}
+	if doc.Details != "" {
+		fmt.Fprintf(&out, "\n\n%s", doc.Details)
+	}
+	if doc.DeprecatedInFavorOf != nil {
+		if *doc.DeprecatedInFavorOf != "" {
+			fmt.Fprintf(&out, "\n\n**Deprecated**: use `+%s` instead.", *doc.DeprecatedInFavorOf)
+		} else {
+			out.WriteString("\n\n**Deprecated**.")
+		}
+	}
+
+	switch {
+	case doc.Empty():
+	case doc.AnonymousField():
+		fmt.Fprintf(&out, "\n\nValue: `%s`", doc.Fields[0].TypeString())
+	default:
+		out.WriteString("\n\nArguments:\n")
+		for _, field := range doc.Fields {
+			fmt.Fprintf(&out, "\n- `%s` `%s`", field.Name, field.TypeString())
+			if field.Optional {
+				out.WriteString(" (optional)")
+			}
+			if field.Summary != "" {
+				fmt.Fprintf(&out, ": %s", field.Summary)
+			}
+		}
+	}
+	return out.String()
+}
+
+// completionContext describes what's being typed in a marker.
+type completionContext struct {
+	// def is the marker whose arguments are being typed, if any.
+	def *markers.Definition
+	// prefix is the part of the marker name or argument name typed so far,
+	// and prefixStart is its offset in the marker text.
+	prefix      string
+	prefixStart int
+	// usedArgs are the arguments that have already been given.
+	usedArgs map[string]struct{}
+}
+
+// completionContextFor figures out what's being typed in the given marker
+// text (starting with the `+`, and ending at the cursor).  It returns false
+// if nothing can be completed, like in the middle of an argument's value.
+func completionContextFor(reg *markers.Registry, typed string) (completionContext, bool) {
+	name := typed[1:]
+
+	// arguments of markers with named arguments come after a `:`
+	var def *markers.Definition
+	for _, candidate := range reg.AllDefinitions() {
+		if candidate.AnonymousField() || candidate.Empty() || !strings.HasPrefix(name, candidate.Name+":") {
+			continue
+		}
+		if def == nil || len(candidate.Name) > len(def.Name) {
+			def = candidate
+		}
+	}
+	if def == nil {
+		if strings.ContainsAny(name, "=,") {
+			// a value, which we don't complete
+			return completionContext{}, false
+		}
+		return completionContext{prefix: name, prefixStart: 1}, true
+	}
+
+	argsStart := 1 + len(def.Name) + 1
+	args := typed[argsStart:]
+
+	// find the start of the argument being typed, skipping over commas in
+	// strings, slices and maps
+	fragmentStart := 0
+	var quote rune
+	depth := 0
+	for i, r := range args {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			fragmentStart = i + 1
+		}
+	}
+	fragment := args[fragmentStart:]
+	if quote != 0 || depth > 0 || strings.Contains(fragment, "=") {
+		// a value, which we don't complete
+		return completionContext{}, false
+	}
+
+	used := make(map[string]struct{})
+	if fragmentStart > 0 {
+		for _, arg := range strings.Split(args[:fragmentStart-1], ",") {
+			used[strings.SplitN(arg, "=", 2)[0]] = struct{}{}
+		}
+	}
+	return completionContext{def: def, prefix: fragment, prefixStart: argsStart + fragmentStart, usedArgs: used}, true
+}
+
+// completions returns the possible completions in the given context, each
+// with the text to insert in place of the typed prefix.
+func completions(reg *markers.Registry, ctx completionContext) []completionItem {
+	var items []completionItem
+	if ctx.def != nil {
+		doc := help.ForDefinition(ctx.def, reg.HelpFor(ctx.def))
+		for _, field := range doc.Fields {
+			if _, used := ctx.usedArgs[field.Name]; used || !strings.HasPrefix(field.Name, ctx.prefix) {
+				continue
+			}
+			item := completionItem{
+				Label:    field.Name,
+				Kind:     completionKindProperty,
+				Detail:   field.TypeString(),
+				TextEdit: &textEdit{NewText: field.Name + "="},
+			}
+			if field.Summary != "" {
+				item.Documentation = &markupContent{Kind: markupKindMarkdown, Value: field.Summary}
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+
+	defsByName := make(map[string][]*markers.Definition)
+	for _, def := range reg.AllDefinitions() {
+		if strings.HasPrefix(def.Name, ctx.prefix) {
+			defsByName[def.Name] = append(defsByName[def.Name], def)
+		}
+	}
+	for name, defs := range defsByName {
+		// keep the targets in a consistent order
+		sort.Slice(defs, func(i, j int) bool { return defs[i].Target < defs[j].Target })
+		item := completionItem{
+			Label:         name,
+			Kind:          completionKindKeyword,
+			Documentation: &markupContent{Kind: markupKindMarkdown, Value: markerDocs(reg, defs)},
+			TextEdit:      &textEdit{NewText: name},
+		}
+		if defHelp := reg.HelpFor(defs[0]); defHelp != nil {
+			item.Detail = defHelp.Summary
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
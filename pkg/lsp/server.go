@@ -0,0 +1,344 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/version"
+)
+
+// Server is a language server for the markers in a Registry.
+type Server struct {
+	// Registry holds the markers (and their help) that the server knows
+	// about.
+	Registry *markers.Registry
+
+	conn *conn
+	// docs holds the text of each open document, by URI.
+	docs map[string]string
+	// typeLocations caches where the Go types for markers are defined (or
+	// nil, if they couldn't be found).
+	typeLocations map[reflect.Type]*location
+}
+
+// handler handles a request, returning its result.
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+// handlers are the handlers for each method we understand.  Notifications
+// return nil results, which are ignored.
+var handlers = map[string]handler{
+	"initialize":              (*Server).initialize,
+	"initialized":             ignore,
+	"shutdown":                ignore,
+	"textDocument/didOpen":    (*Server).didOpen,
+	"textDocument/didChange":  (*Server).didChange,
+	"textDocument/didClose":   (*Server).didClose,
+	"textDocument/didSave":    ignore,
+	"textDocument/completion": (*Server).completion,
+	"textDocument/hover":      (*Server).hover,
+	"textDocument/definition": (*Server).definition,
+}
+
+func ignore(*Server, json.RawMessage) (interface{}, error) { return nil, nil }
+
+// invalidParamsError is returned by handlers when the request parameters
+// can't be decoded.
+type invalidParamsError struct{ error }
+
+// decodeParams decodes request parameters into the given value.
+func decodeParams(params json.RawMessage, into interface{}) error {
+	if err := json.Unmarshal(params, into); err != nil {
+		return invalidParamsError{err}
+	}
+	return nil
+}
+
+// Serve handles the messages read from in, writing responses (and
+// notifications) to out, until the client asks the server to exit, or in is
+// closed.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.conn = &conn{in: bufio.NewReader(in), out: out}
+	s.docs = make(map[string]string)
+	s.typeLocations = make(map[reflect.Type]*location)
+
+	for {
+		body, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.replyError(nil, codeParseError, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		handle, known := handlers[req.Method]
+		isNotification := len(req.ID) == 0
+		switch {
+		case !known && isNotification:
+			// notifications we don't understand are safe to ignore
+		case !known:
+			err = s.replyError(req.ID, codeMethodNotFound, fmt.Errorf("unknown method %q", req.Method))
+		default:
+			result, handleErr := handle(s, req.Params)
+			switch {
+			case isNotification:
+			case handleErr != nil:
+				code := codeInvalidParams
+				if _, isParamsErr := handleErr.(invalidParamsError); !isParamsErr {
+					code = codeInternalError
+				}
+				err = s.replyError(req.ID, code, handleErr)
+			default:
+				err = s.conn.write(response{JSONRPC: "2.0", ID: req.ID, Result: result})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// replyError responds to the request with the given ID with an error.
+func (s *Server) replyError(id json.RawMessage, code int, err error) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return s.conn.write(errorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   responseError{Code: code, Message: err.Error()},
+	})
+}
+
+func (s *Server) initialize(json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync": textDocumentSyncFull,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{"+", ":", ","},
+			},
+			"hoverProvider":      true,
+			"definitionProvider": true,
+		},
+		"serverInfo": map[string]string{
+			"name":    "controller-gen",
+			"version": version.Version(),
+		},
+	}, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var opened didOpenParams
+	if err := decodeParams(params, &opened); err != nil {
+		return nil, err
+	}
+	s.docs[opened.TextDocument.URI] = opened.TextDocument.Text
+	return nil, s.publishDiagnostics(opened.TextDocument.URI)
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var changed didChangeParams
+	if err := decodeParams(params, &changed); err != nil {
+		return nil, err
+	}
+	if len(changed.ContentChanges) == 0 {
+		return nil, nil
+	}
+	s.docs[changed.TextDocument.URI] = changed.ContentChanges[len(changed.ContentChanges)-1].Text
+	return nil, s.publishDiagnostics(changed.TextDocument.URI)
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var closed didCloseParams
+	if err := decodeParams(params, &closed); err != nil {
+		return nil, err
+	}
+	delete(s.docs, closed.TextDocument.URI)
+	// clear out anything we reported for it
+	return nil, s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: closed.TextDocument.URI, Diagnostics: []diagnostic{}},
+	})
+}
+
+// publishDiagnostics reports the markers in the given document that can't
+// be parsed.
+func (s *Server) publishDiagnostics(uri string) error {
+	text := s.docs[uri]
+	lines := strings.Split(text, "\n")
+	diags := []diagnostic{}
+	for _, marker := range findMarkers(text) {
+		err := parseError(s.Registry, marker.text)
+		if err == nil {
+			continue
+		}
+		diags = append(diags, diagnostic{
+			Range:    markerRange(lines, marker),
+			Severity: diagnosticSeverityError,
+			Source:   "controller-gen",
+			Message:  err.Error(),
+		})
+	}
+	return s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+}
+
+// markerAt returns the marker at the given position in the given document,
+// along with the document's lines and the byte offset of the position in
+// its line.
+func (s *Server) markerAt(params json.RawMessage) (*markerComment, []string, int, error) {
+	var at textDocumentPositionParams
+	if err := decodeParams(params, &at); err != nil {
+		return nil, nil, 0, err
+	}
+	text, open := s.docs[at.TextDocument.URI]
+	if !open {
+		return nil, nil, 0, nil
+	}
+	lines := strings.Split(text, "\n")
+	if at.Position.Line >= len(lines) {
+		return nil, lines, 0, nil
+	}
+	offset := byteOffset(lines[at.Position.Line], at.Position.Character)
+	return markerOnLine(findMarkers(text), at.Position.Line, offset), lines, offset, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	marker, lines, offset, err := s.markerAt(params)
+	if err != nil || marker == nil || offset == marker.start {
+		return nil, err
+	}
+	typed := lines[marker.line][marker.start:offset]
+	ctx, canComplete := completionContextFor(s.Registry, typed)
+	if !canComplete {
+		return nil, nil
+	}
+
+	items := completions(s.Registry, ctx)
+	line := lines[marker.line]
+	replace := textRange{
+		Start: position{Line: marker.line, Character: utf16Offset(line, marker.start+ctx.prefixStart)},
+		End:   position{Line: marker.line, Character: utf16Offset(line, offset)},
+	}
+	for i := range items {
+		items[i].TextEdit.Range = replace
+	}
+	return completionList{Items: append([]completionItem{}, items...)}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	marker, lines, _, err := s.markerAt(params)
+	if err != nil || marker == nil {
+		return nil, err
+	}
+	defs := definitionsFor(s.Registry, marker.text)
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	markerRange := markerRange(lines, *marker)
+	return hover{
+		Contents: markupContent{Kind: markupKindMarkdown, Value: markerDocs(s.Registry, defs)},
+		Range:    &markerRange,
+	}, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	marker, _, _, err := s.markerAt(params)
+	if err != nil || marker == nil {
+		return nil, err
+	}
+	defs := definitionsFor(s.Registry, marker.text)
+	if len(defs) == 0 {
+		return nil, nil
+	}
+
+	var at textDocumentPositionParams
+	if err := decodeParams(params, &at); err != nil {
+		return nil, err
+	}
+	outputType := defs[0].Output
+	loc, cached := s.typeLocations[outputType]
+	if !cached {
+		loc = findType(outputType, dirOf(at.TextDocument.URI))
+		s.typeLocations[outputType] = loc
+	}
+	if loc == nil {
+		return nil, nil
+	}
+	return *loc, nil
+}
+
+// markerRange returns the range of the given marker.
+func markerRange(lines []string, marker markerComment) textRange {
+	line := lines[marker.line]
+	return textRange{
+		Start: position{Line: marker.line, Character: utf16Offset(line, marker.start)},
+		End:   position{Line: marker.line, Character: utf16Offset(line, marker.end)},
+	}
+}
+
+// byteOffset converts an offset in UTF-16 code units (as used by LSP) in the
+// given line into a byte offset.
+func byteOffset(line string, utf16Col int) int {
+	col := 0
+	for i, r := range line {
+		if col >= utf16Col {
+			return i
+		}
+		col += utf16Len(r)
+	}
+	return len(line)
+}
+
+// utf16Offset converts a byte offset in the given line into an offset in
+// UTF-16 code units.
+func utf16Offset(line string, byteCol int) int {
+	col := 0
+	for _, r := range line[:byteCol] {
+		col += utf16Len(r)
+	}
+	return col
+}
+
+// utf16Len returns the number of UTF-16 code units needed for the given rune.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// testClient talks to a server over a pair of pipes.
+type testClient struct {
+	g      *gomega.WithT
+	conn   *conn
+	nextID int
+}
+
+// notify sends a notification.
+func (c *testClient) notify(method string, params interface{}) {
+	c.g.Expect(c.conn.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})).To(gomega.Succeed())
+}
+
+// call sends a request, and decodes the result of its response into result.
+func (c *testClient) call(method string, params interface{}, result interface{}) {
+	c.nextID++
+	c.g.Expect(c.conn.write(map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})).To(gomega.Succeed())
+
+	var resp struct {
+		ID     int              `json:"id"`
+		Result json.RawMessage  `json:"result"`
+		Error  *json.RawMessage `json:"error"`
+	}
+	c.g.Expect(json.Unmarshal(c.read(), &resp)).To(gomega.Succeed())
+	c.g.Expect(resp.ID).To(gomega.Equal(c.nextID))
+	c.g.Expect(resp.Error).To(gomega.BeNil())
+	c.g.Expect(json.Unmarshal(resp.Result, result)).To(gomega.Succeed())
+}
+
+// diagnostics reads the next notification, which should be published
+// diagnostics.
+func (c *testClient) diagnostics() []diagnostic {
+	var msg struct {
+		Method string                   `json:"method"`
+		Params publishDiagnosticsParams `json:"params"`
+	}
+	c.g.Expect(json.Unmarshal(c.read(), &msg)).To(gomega.Succeed())
+	c.g.Expect(msg.Method).To(gomega.Equal("textDocument/publishDiagnostics"))
+	return msg.Params.Diagnostics
+}
+
+func (c *testClient) read() []byte {
+	body, err := c.conn.read()
+	c.g.Expect(err).NotTo(gomega.HaveOccurred())
+	return body
+}
+
+// open opens a document with the given text, returning its diagnostics.
+func (c *testClient) open(uri, text string) []diagnostic {
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "go", "version": 1, "text": text},
+	})
+	return c.diagnostics()
+}
+
+// at returns the position parameters for the given line and column.
+func at(uri string, line, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: line, Character: character},
+	}
+}
+
+func startServer(t *testing.T, reg *markers.Registry) *testClient {
+	g := gomega.NewWithT(t)
+	clientIn, srvOut := io.Pipe()
+	srvIn, clientOut := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- (&Server{Registry: reg}).Serve(srvIn, srvOut)
+		srvOut.Close()
+	}()
+
+	client := &testClient{g: g, conn: &conn{in: bufio.NewReader(clientIn), out: clientOut}}
+	t.Cleanup(func() {
+		client.notify("exit", nil)
+		g.Expect(<-done).To(gomega.Succeed())
+	})
+	return client
+}
+
+func TestServer(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	reg := &markers.Registry{}
+	g.Expect(genall.RegisterOptionsMarkers(reg)).To(gomega.Succeed())
+	client := startServer(t, reg)
+
+	var initResult struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	client.call("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &initResult)
+	g.Expect(initResult.Capabilities).To(gomega.HaveKeyWithValue("hoverProvider", true))
+	client.notify("initialized", map[string]interface{}{})
+
+	wd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	uri := pathToURI(filepath.Join(wd, "doc.go"))
+	lines := []string{
+		"package doc",
+		"",
+		"// +pr",
+		"// +prune:",
+		"// +prune:dryRun=maybe",
+		"// +paths=./a,",
+		"// +someone:else's=marker",
+	}
+
+	// reporting markers that can't be parsed
+	diags := client.open(uri, strings.Join(lines, "\n"))
+	g.Expect(diags).To(gomega.HaveLen(2))
+	g.Expect(diags[0].Message).To(gomega.ContainSubstring(`expected true or false, got "maybe"`))
+	g.Expect(diags[0].Range).To(gomega.Equal(textRange{Start: position{Line: 4, Character: 3}, End: position{Line: 4, Character: len(lines[4])}}))
+
+	// completing marker names
+	var list completionList
+	client.call("textDocument/completion", at(uri, 2, len(lines[2])), &list)
+	g.Expect(list.Items).To(gomega.HaveLen(1))
+	g.Expect(list.Items[0].Label).To(gomega.Equal("prune"))
+	g.Expect(list.Items[0].TextEdit).To(gomega.Equal(&textEdit{
+		Range:   textRange{Start: position{Line: 2, Character: 4}, End: position{Line: 2, Character: 6}},
+		NewText: "prune",
+	}))
+
+	// completing argument names
+	client.call("textDocument/completion", at(uri, 3, len(lines[3])), &list)
+	g.Expect(list.Items).To(gomega.HaveLen(1))
+	g.Expect(list.Items[0].TextEdit.NewText).To(gomega.Equal("dryRun="))
+
+	// not completing values (including ones in progress)
+	var noList *completionList
+	client.call("textDocument/completion", at(uri, 5, len(lines[5])), &noList)
+	g.Expect(noList).To(gomega.BeNil())
+
+	// documenting markers on hover
+	var hovered hover
+	client.call("textDocument/hover", at(uri, 4, 5), &hovered)
+	g.Expect(hovered.Contents.Value).To(gomega.HavePrefix("`+prune` (package)"))
+	g.Expect(hovered.Contents.Value).To(gomega.ContainSubstring("- `dryRun` `bool` (optional)"))
+
+	// ignoring unknown markers
+	var noHover *hover
+	client.call("textDocument/hover", at(uri, 6, 5), &noHover)
+	g.Expect(noHover).To(gomega.BeNil())
+
+	// finding the types that define markers
+	var loc location
+	client.call("textDocument/definition", at(uri, 4, 5), &loc)
+	g.Expect(loc.URI).To(gomega.HaveSuffix("/pkg/genall/prune.go"))
+	path := strings.TrimPrefix(loc.URI, "file://")
+	contents, err := os.ReadFile(path)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(strings.Split(string(contents), "\n")[loc.Range.Start.Line]).To(gomega.Equal("type Prune struct {"))
+	g.Expect(loc.Range.Start.Character).To(gomega.Equal(5))
+
+	// clearing diagnostics once fixed
+	lines[4] = "// +prune:dryRun=true"
+	lines[5] = "// +paths=./a"
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]string{{"text": strings.Join(lines, "\n")}},
+	})
+	g.Expect(client.diagnostics()).To(gomega.BeEmpty())
+
+	// rejecting unknown requests
+	g.Expect(client.conn.write(map[string]interface{}{"jsonrpc": "2.0", "id": 100, "method": "workspace/symbol"})).To(gomega.Succeed())
+	g.Expect(string(client.read())).To(gomega.ContainSubstring(fmt.Sprintf(`"code":%d`, codeMethodNotFound)))
+}
+
+func TestUTF16Offsets(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	line := "// é😀 +prune"
+	// é is 2 bytes and 1 unit, 😀 is 4 bytes and 2 units
+	g.Expect(utf16Offset(line, 10)).To(gomega.Equal(7))
+	g.Expect(byteOffset(line, 7)).To(gomega.Equal(10))
+	g.Expect(byteOffset(line, 100)).To(gomega.Equal(len(line)))
+}
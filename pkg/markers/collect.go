@@ -27,14 +27,29 @@ import (
 
 // Collector collects and parses marker comments defined in the registry
 // from package source code.  If no registry is provided, an empty one will
-// be initialized on the first call to MarkersInPackage.
+// be initialized on the first call to MarkersInPackage.  A Collector is safe
+// for concurrent use.
 type Collector struct {
 	*Registry
 
+	// CheckUnknown, if set, reports markers that aren't registered, but look
+	// like they were meant to be (see UnknownMarkerCheck).
+	CheckUnknown *UnknownMarkerCheck
+
+	// Store, if set, persists collected markers between runs, so that
+	// they're only parsed again for packages that have changed.
+	Store PackageStore
+
 	byPackage map[string]map[ast.Node]MarkerValues
-	mu        sync.Mutex
+	// commentsByPackage holds the comments each marker value was parsed from,
+	// in the same order as the values in byPackage.
+	commentsByPackage map[string]map[ast.Node]markerComments
+	mu                sync.Mutex
 }
 
+// markerComments are the comments that the values for each marker were parsed from.
+type markerComments map[string][]*ast.Comment
+
 // MarkerValues are all the values for some set of markers.
 type MarkerValues map[string][]interface{}
 
@@ -55,6 +70,9 @@ func (c *Collector) init() {
 	if c.byPackage == nil {
 		c.byPackage = make(map[string]map[ast.Node]MarkerValues)
 	}
+	if c.commentsByPackage == nil {
+		c.commentsByPackage = make(map[string]map[ast.Node]markerComments)
+	}
 }
 
 // MarkersInPackage computes the marker values by node for the given package.  Results
@@ -83,23 +101,50 @@ func (c *Collector) MarkersInPackage(pkg *loader.Package) (map[ast.Node]MarkerVa
 	c.mu.Unlock()
 
 	pkg.NeedSyntax()
-	nodeMarkersRaw := c.associatePkgMarkers(pkg)
-	markers, err := c.parseMarkersInPackage(nodeMarkersRaw)
-	if err != nil {
-		return nil, err
+	markers, comments, stored := c.loadStored(pkg)
+	if !stored {
+		nodeMarkersRaw := c.associatePkgMarkers(pkg)
+		var err error
+		markers, comments, err = c.parseMarkersInPackage(nodeMarkersRaw)
+		if err != nil {
+			return nil, err
+		}
+		c.saveStored(pkg, markers, comments)
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if existing, exist := c.byPackage[pkg.ID]; exist {
+		// someone else beat us to it -- make sure everyone sees the same values
+		return existing, nil
+	}
 	c.byPackage[pkg.ID] = markers
+	c.commentsByPackage[pkg.ID] = comments
 
 	return markers, nil
 }
 
-// parseMarkersInPackage parses the given raw marker comments into output values using the registry.
-func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerComment) (map[ast.Node]MarkerValues, error) {
+// MarkerComments returns the comments that the values of the given marker on the
+// given node were parsed from, in the same order as the values themselves (as returned
+// from MarkersInPackage).  This is useful for attaching errors about a particular
+// marker value to the marker itself.  Nil is returned if the package's markers could
+// not be collected, or if the node has no such marker.
+func (c *Collector) MarkerComments(pkg *loader.Package, node ast.Node, name string) []*ast.Comment {
+	if _, err := c.MarkersInPackage(pkg); err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commentsByPackage[pkg.ID][node][name]
+}
+
+// parseMarkersInPackage parses the given raw marker comments into output values using the registry,
+// also returning the comment that each value was parsed from.
+func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerComment) (map[ast.Node]MarkerValues, map[ast.Node]markerComments, error) {
 	var errors []error
 	nodeMarkerValues := make(map[ast.Node]MarkerValues)
+	nodeMarkerComments := make(map[ast.Node]markerComments)
 	for node, markersRaw := range nodeMarkersRaw {
 		var target TargetType
 		switch node.(type) {
@@ -111,10 +156,14 @@ func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerCo
 			target = DescribesType
 		}
 		markerVals := make(map[string][]interface{})
+		comments := make(markerComments)
 		for _, markerRaw := range markersRaw {
 			markerText := markerRaw.Text()
 			def := c.Registry.Lookup(markerText, target)
 			if def == nil {
+				if err := c.checkUnknown(markerText, target); err != nil {
+					errors = append(errors, loader.ErrFromNode(err, markerRaw))
+				}
 				continue
 			}
 			val, err := def.Parse(markerText)
@@ -123,11 +172,13 @@ func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerCo
 				continue
 			}
 			markerVals[def.Name] = append(markerVals[def.Name], val)
+			comments[def.Name] = append(comments[def.Name], markerRaw.Comment)
 		}
 		nodeMarkerValues[node] = markerVals
+		nodeMarkerComments[node] = comments
 	}
 
-	return nodeMarkerValues, loader.MaybeErrList(errors)
+	return nodeMarkerValues, nodeMarkerComments, loader.MaybeErrList(errors)
 }
 
 // associatePkgMarkers associates markers with AST nodes in the given package.
@@ -181,7 +232,10 @@ func (c *Collector) associateFileMarkers(file *ast.File) map[ast.Node][]markerCo
 				endOfMarkers++
 				continue
 			}
-			def := c.Registry.Lookup(markerText, DescribesPackage)
+			// markers that are known, but not registered, don't get parsed,
+			// but should still be treated the same when checking for unknown
+			// markers
+			def := c.lookupKnown(markerText, DescribesPackage)
 			if def == nil {
 				// assume type-level unless proven otherwise
 				markers[endOfMarkers] = marker
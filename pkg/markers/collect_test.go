@@ -17,6 +17,8 @@ limitations under the License.
 package markers_test
 
 import (
+	"go/ast"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -32,6 +34,8 @@ var _ = Describe("Collecting", func() {
 	var col *Collector
 	var markersByType map[string]MarkerValues
 	var docsByType map[string]string
+	var docLinesByType map[string][]string
+	var specsByType map[string]*ast.TypeSpec
 
 	var markersByField map[fieldPath]MarkerValues
 	var docsByField map[fieldPath]string
@@ -51,12 +55,16 @@ var _ = Describe("Collecting", func() {
 		By("gathering markers/docs by type/field name")
 		markersByType = make(map[string]MarkerValues)
 		docsByType = make(map[string]string)
+		docLinesByType = make(map[string][]string)
+		specsByType = make(map[string]*ast.TypeSpec)
 		markersByField = make(map[fieldPath]MarkerValues)
 		docsByField = make(map[fieldPath]string)
 
 		err := EachType(col, fakePkg, func(info *TypeInfo) {
 			markersByType[info.Name] = info.Markers
 			docsByType[info.Name] = info.Doc
+			docLinesByType[info.Name] = info.DocLines
+			specsByType[info.Name] = info.RawSpec
 
 			for _, field := range info.Fields {
 				fieldPath := fieldPath{typ: info.Name, field: field.Name}
@@ -135,6 +143,12 @@ var _ = Describe("Collecting", func() {
 				Expect(docsByType).To(HaveKeyWithValue("HasDocsWithSpaces2",
 					"This type of doc has spaces preserved in go-ast, but we'd like to trim them, especially when formatted like this."))
 			})
+
+			It("should have the raw doc lines that the doc was joined from", func() {
+				Expect(docLinesByType).To(HaveKey("HasDocsWithSpaces2"))
+				Expect(docLinesByType["HasDocsWithSpaces2"]).To(HaveLen(2))
+				Expect(JoinDocLines(docLinesByType["HasDocsWithSpaces2"])).To(Equal(docsByType["HasDocsWithSpaces2"]))
+			})
 		})
 
 		Context("without godoc", func() {
@@ -219,4 +233,21 @@ var _ = Describe("Collecting", func() {
 				HaveKeyWithValue("testing:fieldlvl", Not(ContainElement("not here after field")))))
 		})
 	})
+
+	Context("of marker comments", func() {
+		It("should return the comment each value was parsed from, in order", func() {
+			vals := markersByType["Foo"]["testing:typelvl"]
+			Expect(vals).NotTo(BeEmpty())
+
+			comments := col.MarkerComments(fakePkg, specsByType["Foo"], "testing:typelvl")
+			Expect(comments).To(HaveLen(len(vals)))
+			for i, val := range vals {
+				Expect(comments[i].Text).To(ContainSubstring(val.(string)))
+			}
+		})
+
+		It("should return nothing for markers that aren't present", func() {
+			Expect(col.MarkerComments(fakePkg, specsByType["Foo"], "testing:fieldlvl")).To(BeEmpty())
+		})
+	})
 })
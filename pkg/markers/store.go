@@ -0,0 +1,333 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// PackageStore persists values computed from packages between runs (e.g.
+// collected markers).  Values are stored by package and name, and are only
+// loaded back if the package (and everything it imports) hasn't changed
+// since they were saved.
+type PackageStore interface {
+	// Load loads the value saved with the given name for the given package
+	// into out, returning false if there isn't one.
+	Load(pkg *loader.Package, name string, out interface{}) bool
+	// Save saves the given value with the given name for the given package.
+	// Saved values may not be persisted until the current run is finished.
+	Save(pkg *loader.Package, name string, value interface{})
+}
+
+// storedMarkers are the markers collected from a package, as persisted in
+// a PackageStore.  Nodes are identified by position, since the syntax is
+// parsed afresh each run.
+type storedMarkers struct {
+	Nodes []storedNode `json:"nodes"`
+}
+
+// storedNode holds the markers on a single node.
+type storedNode struct {
+	// File is the base name of the file the node is in.
+	File string `json:"file"`
+	// Target is the kind of node (package-level markers are on files).
+	Target TargetType `json:"target"`
+	// Offset is the position of the node relative to its file's package
+	// clause.
+	Offset int `json:"offset"`
+
+	Markers []storedMarker `json:"markers,omitempty"`
+}
+
+// storedMarker is a single marker value.
+type storedMarker struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+	// Comment is the position of the comment the value was parsed from,
+	// relative to its file's package clause.
+	Comment int `json:"comment"`
+}
+
+// storedNodeKey identifies a node or comment in a package.
+type storedNodeKey struct {
+	file   string
+	target TargetType
+	offset int
+}
+
+// storeName is the name that collected markers are saved with.  It covers
+// everything but the package contents that affects which markers are
+// collected, and how they're parsed.
+func (c *Collector) storeName() string {
+	h := sha256.New()
+	registryFingerprint(h, c.Registry)
+	if c.CheckUnknown != nil {
+		fmt.Fprintf(h, "check unknown, allowing %q\n", c.CheckUnknown.Allow)
+		if c.CheckUnknown.Known != nil {
+			registryFingerprint(h, c.CheckUnknown.Known)
+		}
+	}
+	return "markers " + hex.EncodeToString(h.Sum(nil))
+}
+
+// registryFingerprint writes out the definitions in the given registry, in
+// a stable order.
+func registryFingerprint(out io.Writer, reg *Registry) {
+	defs := reg.AllDefinitions()
+	lines := make([]string, len(defs))
+	for i, def := range defs {
+		lines[i] = fmt.Sprintf("%s %s %s.%s", def.Target, def.Name, def.Output.PkgPath(), def.Output)
+	}
+	sort.Strings(lines)
+	fmt.Fprintf(out, "registry\n%s\n", strings.Join(lines, "\n"))
+}
+
+// definitionsByTarget indexes the registry's definitions by target and
+// exact name.
+func (c *Collector) definitionsByTarget() map[TargetType]map[string]*Definition {
+	defs := make(map[TargetType]map[string]*Definition)
+	for _, def := range c.Registry.AllDefinitions() {
+		if defs[def.Target] == nil {
+			defs[def.Target] = make(map[string]*Definition)
+		}
+		defs[def.Target][def.Name] = def
+	}
+	return defs
+}
+
+// loadStored loads the markers collected for the given package by an
+// earlier run from the store, if any, matching them up with its syntax.
+func (c *Collector) loadStored(pkg *loader.Package) (map[ast.Node]MarkerValues, map[ast.Node]markerComments, bool) {
+	var stored storedMarkers
+	if c.Store == nil || !c.Store.Load(pkg, c.storeName(), &stored) {
+		return nil, nil, false
+	}
+
+	defs := c.definitionsByTarget()
+	index := indexPackage(pkg)
+	nodeMarkerValues := make(map[ast.Node]MarkerValues, len(stored.Nodes))
+	nodeMarkerComments := make(map[ast.Node]markerComments, len(stored.Nodes))
+	for _, storedNode := range stored.Nodes {
+		node, found := index.nodes[storedNodeKey{file: storedNode.File, target: storedNode.Target, offset: storedNode.Offset}]
+		if !found {
+			return nil, nil, false
+		}
+		markerVals := make(map[string][]interface{})
+		comments := make(markerComments)
+		for _, marker := range storedNode.Markers {
+			def := defs[storedNode.Target][marker.Name]
+			comment := index.comments[storedNodeKey{file: storedNode.File, offset: marker.Comment}]
+			if def == nil || comment == nil {
+				return nil, nil, false
+			}
+			val, err := decodeStoredValue(def, marker.Value)
+			if err != nil {
+				return nil, nil, false
+			}
+			markerVals[def.Name] = append(markerVals[def.Name], val)
+			comments[def.Name] = append(comments[def.Name], comment)
+		}
+		nodeMarkerValues[node] = markerVals
+		nodeMarkerComments[node] = comments
+	}
+	return nodeMarkerValues, nodeMarkerComments, true
+}
+
+// saveStored saves the markers collected for the given package to the
+// store.  Nothing is saved if any of the values wouldn't be loaded back
+// exactly as they are.
+func (c *Collector) saveStored(pkg *loader.Package, nodeMarkerValues map[ast.Node]MarkerValues, nodeMarkerComments map[ast.Node]markerComments) {
+	if c.Store == nil {
+		return
+	}
+
+	defs := c.definitionsByTarget()
+	index := indexPackage(pkg)
+	var stored storedMarkers
+	for node, markerVals := range nodeMarkerValues {
+		key, found := index.nodeKeys[node]
+		if !found {
+			return
+		}
+		storedNode := storedNode{File: key.file, Target: key.target, Offset: key.offset}
+
+		names := make([]string, 0, len(markerVals))
+		for name := range markerVals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			def := defs[key.target][name]
+			if def == nil {
+				return
+			}
+			for i, val := range markerVals[name] {
+				raw, err := json.Marshal(val)
+				if err != nil {
+					return
+				}
+				if decoded, err := decodeStoredValue(def, raw); err != nil || !reflect.DeepEqual(decoded, val) {
+					return
+				}
+				commentKey, found := index.commentKeys[nodeMarkerComments[node][name][i]]
+				if !found {
+					return
+				}
+				storedNode.Markers = append(storedNode.Markers, storedMarker{
+					Name:    name,
+					Value:   raw,
+					Comment: commentKey.offset,
+				})
+			}
+		}
+		stored.Nodes = append(stored.Nodes, storedNode)
+	}
+
+	// keep what's saved stable
+	sort.Slice(stored.Nodes, func(i, j int) bool {
+		a, b := stored.Nodes[i], stored.Nodes[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.Target < b.Target
+	})
+	c.Store.Save(pkg, c.storeName(), stored)
+}
+
+// packageIndex indexes the nodes in a package that markers can be
+// associated with, as well as all of its comments, by position.  Positions
+// are relative to each file's package clause, since the files are parsed
+// into a different file set each run.
+type packageIndex struct {
+	nodes       map[storedNodeKey]ast.Node
+	nodeKeys    map[ast.Node]storedNodeKey
+	comments    map[storedNodeKey]*ast.Comment
+	commentKeys map[*ast.Comment]storedNodeKey
+}
+
+func indexPackage(pkg *loader.Package) *packageIndex {
+	index := &packageIndex{
+		nodes:       make(map[storedNodeKey]ast.Node),
+		nodeKeys:    make(map[ast.Node]storedNodeKey),
+		comments:    make(map[storedNodeKey]*ast.Comment),
+		commentKeys: make(map[*ast.Comment]storedNodeKey),
+	}
+	for i, file := range pkg.Syntax {
+		fileName := filepath.Base(pkg.CompiledGoFiles[i])
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				key := storedNodeKey{file: fileName, offset: int(comment.Pos() - file.Pos())}
+				index.comments[key] = comment
+				index.commentKeys[comment] = key
+			}
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			key := storedNodeKey{file: fileName}
+			switch node.(type) {
+			case *ast.File:
+				key.target = DescribesPackage
+			case *ast.TypeSpec:
+				key.target = DescribesType
+				key.offset = int(node.Pos() - file.Pos())
+			case *ast.Field:
+				key.target = DescribesField
+				key.offset = int(node.Pos() - file.Pos())
+			default:
+				return true
+			}
+			index.nodes[key] = node
+			index.nodeKeys[node] = key
+			return true
+		})
+	}
+	return index
+}
+
+// decodeStoredValue decodes the given stored value of the given marker.
+// Numbers that the marker parser would have produced as ints (in fields
+// of any type) are decoded as ints, rather than float64s.
+func decodeStoredValue(def *Definition, raw json.RawMessage) (interface{}, error) {
+	out := reflect.New(def.Output)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(out.Interface()); err != nil {
+		return nil, err
+	}
+	fixNumbers(out.Elem())
+	return out.Elem().Interface(), nil
+}
+
+// fixNumbers replaces the json.Numbers in any interface{} values within
+// the given value with ints (if they're integers) or float64s.
+func fixNumbers(val reflect.Value) {
+	switch val.Kind() {
+	case reflect.Ptr:
+		if !val.IsNil() {
+			fixNumbers(val.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			if val.Field(i).CanSet() {
+				fixNumbers(val.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			fixNumbers(val.Index(i))
+		}
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			elem := reflect.New(val.Type().Elem()).Elem()
+			elem.Set(val.MapIndex(key))
+			fixNumbers(elem)
+			val.SetMapIndex(key, elem)
+		}
+	case reflect.Interface:
+		if val.IsNil() {
+			return
+		}
+		switch inner := val.Elem().Interface().(type) {
+		case json.Number:
+			if i, err := inner.Int64(); err == nil {
+				val.Set(reflect.ValueOf(int(i)))
+			} else if f, err := inner.Float64(); err == nil {
+				val.Set(reflect.ValueOf(f))
+			}
+		default:
+			// copy out the contents, which aren't addressable
+			elem := reflect.New(val.Elem().Type()).Elem()
+			elem.Set(val.Elem())
+			fixNumbers(elem)
+			val.Set(elem)
+		}
+	}
+}
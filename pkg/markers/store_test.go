@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pkgstest "golang.org/x/tools/go/packages/packagestest"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	testloader "sigs.k8s.io/controller-tools/pkg/loader/testutils"
+	. "sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// memoryStore is a PackageStore that keeps values in memory, regardless of
+// whether their packages change.
+type memoryStore struct {
+	values map[string][]byte
+	loads  int
+}
+
+func (s *memoryStore) Load(_ *loader.Package, name string, out interface{}) bool {
+	raw, stored := s.values[name]
+	if !stored {
+		return false
+	}
+	s.loads++
+	return json.Unmarshal(raw, out) == nil
+}
+
+func (s *memoryStore) Save(_ *loader.Package, name string, value interface{}) {
+	raw, err := json.Marshal(value)
+	Expect(err).NotTo(HaveOccurred())
+	s.values[name] = raw
+}
+
+type anyValue struct {
+	Value interface{}
+}
+
+var _ = Describe("Storing collected markers", func() {
+	var exportedDirs []*pkgstest.Exported
+
+	// loadPackage loads the test package afresh, as a new run would, with
+	// the given value for one of its markers.
+	loadPackage := func(anyValue string) *loader.Package {
+		modules := []pkgstest.Module{
+			{
+				Name: "sigs.k8s.io/controller-tools/pkg/markers/testdata/stored",
+				Files: map[string]interface{}{
+					"file.go": `
+						// +testing:pkglvl="package"
+						package stored
+
+						// +testing:typelvl="type"
+						type Foo struct {
+							// +testing:fieldlvl="field"
+							// +testing:anylvl=3
+							Bar string
+
+							// +testing:anylvl=` + anyValue + `
+							// +testing:fieldlvl="first"
+							// +testing:fieldlvl="second"
+							Baz int
+						}
+					`,
+				},
+			},
+		}
+		pkgs, exported, err := testloader.LoadFakeRoots(pkgstest.Modules, modules, "sigs.k8s.io/controller-tools/pkg/markers/testdata/stored")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+		exportedDirs = append(exportedDirs, exported)
+		return pkgs[0]
+	}
+
+	AfterEach(func() {
+		for _, exported := range exportedDirs {
+			exported.Cleanup()
+		}
+		exportedDirs = nil
+	})
+
+	newCollector := func(store PackageStore) *Collector {
+		reg := &Registry{}
+		mustDefine(reg, "testing:pkglvl", DescribesPackage, "")
+		mustDefine(reg, "testing:typelvl", DescribesType, "")
+		mustDefine(reg, "testing:fieldlvl", DescribesField, "")
+		def, err := MakeAnyTypeDefinition("testing:anylvl", DescribesField, anyValue{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reg.Register(def)).To(Succeed())
+		return &Collector{Registry: reg, Store: store}
+	}
+
+	// markersByName gathers the values and comments of markers on each
+	// named node.
+	markersByName := func(col *Collector, pkg *loader.Package) (map[string]MarkerValues, map[string][]string) {
+		byNode, err := col.MarkersInPackage(pkg)
+		Expect(err).NotTo(HaveOccurred())
+		values := make(map[string]MarkerValues)
+		comments := make(map[string][]string)
+		for node, markers := range byNode {
+			var name string
+			switch node := node.(type) {
+			case *ast.File:
+				name = "package"
+			case *ast.TypeSpec:
+				name = node.Name.Name
+			case *ast.Field:
+				name = node.Names[0].Name
+			}
+			values[name] = markers
+			for markerName := range markers {
+				for _, comment := range col.MarkerComments(pkg, node, markerName) {
+					comments[name] = append(comments[name], comment.Text)
+				}
+			}
+			sort.Strings(comments[name])
+		}
+		return values, comments
+	}
+
+	It("should load the same markers as were collected, for a freshly loaded package", func() {
+		store := &memoryStore{values: make(map[string][]byte)}
+		collected, collectedComments := markersByName(newCollector(store), loadPackage(`{a: 1.5, b: two}`))
+		Expect(store.values).To(HaveLen(1))
+		Expect(collected["Bar"]).To(HaveKeyWithValue("testing:anylvl", []interface{}{anyValue{Value: 3}}))
+
+		loaded, loadedComments := markersByName(newCollector(store), loadPackage(`{a: 1.5, b: two}`))
+		Expect(store.loads).To(Equal(1))
+		Expect(loaded).To(Equal(collected))
+		Expect(loadedComments).To(Equal(collectedComments))
+	})
+
+	It("should collect markers afresh when the registered markers change", func() {
+		store := &memoryStore{values: make(map[string][]byte)}
+		markersByName(newCollector(store), loadPackage(`"a"`))
+
+		col := newCollector(store)
+		mustDefine(col.Registry, "testing:other", DescribesField, "")
+		markersByName(col, loadPackage(`"a"`))
+		Expect(store.loads).To(Equal(0))
+		Expect(store.values).To(HaveLen(2))
+	})
+
+	It("should not store markers whose values wouldn't be loaded back as they are", func() {
+		store := &memoryStore{values: make(map[string][]byte)}
+		// typed slices would come back as []interface{}
+		markersByName(newCollector(store), loadPackage(`{1, 2}`))
+		Expect(store.values).To(BeEmpty())
+	})
+})
@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnknownMarkerCheck configures a Collector to report markers that look like
+// they were meant to be registered ones, but aren't: ones with a typo in
+// their name, and ones used on the wrong kind of node.
+//
+// Only markers whose first name segment (e.g. `kubebuilder` in
+// `kubebuilder:validation:Minimum`) is shared with a registered marker are
+// reported as unknown, since other markers most likely belong to other tools.
+type UnknownMarkerCheck struct {
+	// Known holds markers that are valid, but that aren't registered with
+	// the collector, like those used by generators that aren't being run.
+	Known *Registry
+	// Allow lists markers that belong to other tools, and so should never
+	// be reported.  Each entry matches markers with exactly that name, or
+	// names starting with it followed by a `:`.
+	Allow []string
+}
+
+// allTargets are all the kinds of nodes a marker can describe.
+var allTargets = []TargetType{DescribesPackage, DescribesType, DescribesField}
+
+// lookupKnown looks up the given marker in the collector's registry, falling
+// back to the markers known to the unknown marker check, if any.
+func (c *Collector) lookupKnown(markerText string, target TargetType) *Definition {
+	if def := c.Registry.Lookup(markerText, target); def != nil {
+		return def
+	}
+	if c.CheckUnknown != nil && c.CheckUnknown.Known != nil {
+		return c.CheckUnknown.Known.Lookup(markerText, target)
+	}
+	return nil
+}
+
+// checkUnknown returns an error if the given marker (which isn't registered
+// for the given target) should be reported by the unknown marker check.
+func (c *Collector) checkUnknown(markerText string, target TargetType) error {
+	check := c.CheckUnknown
+	if check == nil {
+		return nil
+	}
+	name, anonName, _ := splitMarker(markerText)
+	if check.allowed(anonName) {
+		return nil
+	}
+	if c.lookupKnown(markerText, target) != nil {
+		return nil
+	}
+
+	// a real marker, in the wrong place
+	var validTargets []string
+	var def *Definition
+	for _, other := range allTargets {
+		if otherDef := c.lookupKnown(markerText, other); otherDef != nil {
+			def = otherDef
+			validTargets = append(validTargets, other.String())
+		}
+	}
+	if def != nil {
+		return fmt.Errorf("marker %q can only be used on a %s, not a %s", def.Name, strings.Join(validTargets, " or "), target)
+	}
+
+	// a typo, or something else entirely
+	registries := []*Registry{c.Registry}
+	if check.Known != nil {
+		registries = append(registries, check.Known)
+	}
+	prefix := strings.SplitN(anonName, ":", 2)[0]
+	knownPrefix := false
+	names := make(map[string]struct{})
+	for _, reg := range registries {
+		for _, def := range reg.AllDefinitions() {
+			names[def.Name] = struct{}{}
+			if strings.SplitN(def.Name, ":", 2)[0] == prefix {
+				knownPrefix = true
+			}
+		}
+	}
+	if !knownPrefix {
+		return nil
+	}
+
+	suggestions := suggestNames(names, anonName, name)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("unknown marker %q", anonName)
+	}
+	quoted := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		quoted[i] = fmt.Sprintf("%q", suggestion)
+	}
+	return fmt.Errorf("unknown marker %q (did you mean %s?)", anonName, strings.Join(quoted, " or "))
+}
+
+// allowed checks if the given marker name is in the allow list.
+func (c *UnknownMarkerCheck) allowed(name string) bool {
+	for _, allowed := range c.Allow {
+		if name == allowed || strings.HasPrefix(name, allowed+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestNames returns the names closest to any of the given (misspelled)
+// names, as long as they're close enough to be plausible typos.
+func suggestNames(names map[string]struct{}, misspelled ...string) []string {
+	best := -1
+	var suggestions []string
+	for name := range names {
+		dist := -1
+		for _, typo := range misspelled {
+			// allow for roughly one typo every ten characters
+			typoDist := editDistance(typo, name)
+			if typoDist <= 1+len(typo)/10 && (dist == -1 || typoDist < dist) {
+				dist = typoDist
+			}
+		}
+		switch {
+		case dist == -1:
+			continue
+		case best == -1 || dist < best:
+			best = dist
+			suggestions = []string{name}
+		case dist == best:
+			suggestions = append(suggestions, name)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
+// editDistance returns the number of single-character insertions, deletions,
+// substitutions and transpositions of adjacent characters needed to turn one
+// string into another (the optimal string alignment distance).
+func editDistance(a, b string) int {
+	// rows i-2, i-1 and i of the usual dynamic programming table
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = minInt(cur[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, cur = prev, cur, prevPrev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
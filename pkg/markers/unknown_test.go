@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pkgstest "golang.org/x/tools/go/packages/packagestest"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	testloader "sigs.k8s.io/controller-tools/pkg/loader/testutils"
+	. "sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+var _ = Describe("Checking for unknown markers", func() {
+	var pkg *loader.Package
+	var exported *pkgstest.Exported
+
+	BeforeEach(func() {
+		modules := []pkgstest.Module{
+			{
+				Name: "sigs.k8s.io/controller-tools/pkg/markers/testdata/unknown",
+				Files: map[string]interface{}{
+					"file.go": `
+						package unknown
+
+						// +testing:pkglvl="ok"
+						// +testing:pkglvll="misspelled package-level"
+
+						// +testing:typelvl="ok"
+						// +testing:typlvl="misspelled"
+						// +testing:fieldlvl="wrong target"
+						// +testing:external:thing=1
+						// +other:typelvl="not ours"
+						type Foo struct {
+							// +testing:fieldlvl="ok"
+							// +testing:feildlvl="misspelled"
+							// +testing:typelvl="wrong target"
+							// +testing:other:flag=true
+							// +testing:completely-different
+							Bar string
+						}
+					`,
+				},
+			},
+		}
+		pkgs, exp, err := testloader.LoadFakeRoots(pkgstest.Modules, modules, "sigs.k8s.io/controller-tools/pkg/markers/testdata/unknown")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+		pkg, exported = pkgs[0], exp
+	})
+
+	AfterEach(func() {
+		exported.Cleanup()
+	})
+
+	newCollector := func(check *UnknownMarkerCheck) *Collector {
+		reg := &Registry{}
+		mustDefine(reg, "testing:pkglvl", DescribesPackage, "")
+		mustDefine(reg, "testing:typelvl", DescribesType, "")
+		mustDefine(reg, "testing:fieldlvl", DescribesField, "")
+		return &Collector{Registry: reg, CheckUnknown: check}
+	}
+
+	errorMessages := func(err error) []string {
+		Expect(err).To(BeAssignableToTypeOf(loader.ErrList{}))
+		var msgs []string
+		for _, err := range err.(loader.ErrList) {
+			msgs = append(msgs, err.Error())
+		}
+		return msgs
+	}
+
+	It("should ignore unregistered markers if not asked to check for them", func() {
+		_, err := newCollector(nil).MarkersInPackage(pkg)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should report misspelled and misplaced markers, skipping allowed and known ones", func() {
+		known := &Registry{}
+		mustDefine(known, "testing:other:flag", DescribesField, false)
+		col := newCollector(&UnknownMarkerCheck{
+			Known: known,
+			Allow: []string{"testing:external"},
+		})
+
+		_, err := col.MarkersInPackage(pkg)
+		Expect(errorMessages(err)).To(ConsistOf(
+			`unknown marker "testing:pkglvll" (did you mean "testing:pkglvl"?)`,
+			`unknown marker "testing:typlvl" (did you mean "testing:typelvl"?)`,
+			`marker "testing:fieldlvl" can only be used on a field, not a type`,
+			`unknown marker "testing:feildlvl" (did you mean "testing:fieldlvl"?)`,
+			`marker "testing:typelvl" can only be used on a type, not a field`,
+			`unknown marker "testing:completely-different"`,
+		))
+	})
+
+	It("should report markers that are only known for a different target", func() {
+		known := &Registry{}
+		mustDefine(known, "testing:other:flag", DescribesType, false)
+		col := newCollector(&UnknownMarkerCheck{Known: known, Allow: []string{"testing:external"}})
+
+		_, err := col.MarkersInPackage(pkg)
+		Expect(errorMessages(err)).To(ContainElement(`marker "testing:other:flag" can only be used on a type, not a field`))
+	})
+})
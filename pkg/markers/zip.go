@@ -28,6 +28,12 @@ import (
 // extractDoc extracts documentation from the given node, skipping markers
 // in the godoc and falling back to the decl if necessary (for single-line decls).
 func extractDoc(node ast.Node, decl *ast.GenDecl) string {
+	return JoinDocLines(extractDocLines(node, decl))
+}
+
+// extractDocLines extracts the lines of documentation from the given node, like
+// extractDoc, but without joining them together.
+func extractDocLines(node ast.Node, decl *ast.GenDecl) []string {
 	var docs *ast.CommentGroup
 	switch docced := node.(type) {
 	case *ast.Field:
@@ -46,7 +52,7 @@ func extractDoc(node ast.Node, decl *ast.GenDecl) string {
 	}
 
 	if docs == nil {
-		return ""
+		return nil
 	}
 
 	// filter out markers
@@ -59,16 +65,20 @@ func extractDoc(node ast.Node, decl *ast.GenDecl) string {
 		outGroup.List = append(outGroup.List, comment)
 	}
 
-	// split lines, and re-join together as a single
-	// paragraph, respecting double-newlines as
-	// paragraph markers.
 	outLines := strings.Split(outGroup.Text(), "\n")
 	if outLines[len(outLines)-1] == "" {
 		// chop off the extraneous last part
 		outLines = outLines[:len(outLines)-1]
 	}
+	return outLines
+}
 
-	for i, line := range outLines {
+// JoinDocLines joins lines of Godoc (like FieldInfo.DocLines) together as a
+// single paragraph, respecting double-newlines as paragraph markers.  This is
+// how the Doc of types and fields is produced.
+func JoinDocLines(lines []string) string {
+	outLines := make([]string, len(lines))
+	for i, line := range lines {
 		// Trim any extranous whitespace,
 		// for handling /*…*/-style comments,
 		// which have whitespace preserved in go/ast:
@@ -110,6 +120,9 @@ type FieldInfo struct {
 	// Doc is the Godoc of the field, pre-processed to remove markers and joine
 	// single newlines together.
 	Doc string
+	// DocLines are the lines of the Godoc of the field, with markers removed,
+	// but otherwise unprocessed.
+	DocLines []string
 	// Tag struct tag associated with this field (or "" if non existed).
 	Tag reflect.StructTag
 
@@ -127,6 +140,9 @@ type TypeInfo struct {
 	// Doc is the Godoc of the type, pre-processed to remove markers and joine
 	// single newlines together.
 	Doc string
+	// DocLines are the lines of the Godoc of the type, with markers removed,
+	// but otherwise unprocessed.
+	DocLines []string
 
 	// Markers are all registered markers associated with the type.
 	Markers MarkerValues
@@ -170,6 +186,7 @@ func EachType(col *Collector, pkg *loader.Package, cb TypeCallback) error {
 					fields = append(fields, FieldInfo{
 						Name:     name.Name,
 						Doc:      extractDoc(field, nil),
+						DocLines: extractDocLines(field, nil),
 						Tag:      loader.ParseAstTag(field.Tag),
 						Markers:  markers[field],
 						RawField: field,
@@ -178,6 +195,7 @@ func EachType(col *Collector, pkg *loader.Package, cb TypeCallback) error {
 				if field.Names == nil {
 					fields = append(fields, FieldInfo{
 						Doc:      extractDoc(field, nil),
+						DocLines: extractDocLines(field, nil),
 						Tag:      loader.ParseAstTag(field.Tag),
 						Markers:  markers[field],
 						RawField: field,
@@ -187,13 +205,14 @@ func EachType(col *Collector, pkg *loader.Package, cb TypeCallback) error {
 		}
 
 		cb(&TypeInfo{
-			Name:    spec.Name.Name,
-			Markers: markers[spec],
-			Doc:     extractDoc(spec, decl),
-			Fields:  fields,
-			RawDecl: decl,
-			RawSpec: spec,
-			RawFile: file,
+			Name:     spec.Name.Name,
+			Markers:  markers[spec],
+			Doc:      extractDoc(spec, decl),
+			DocLines: extractDocLines(spec, decl),
+			Fields:   fields,
+			RawDecl:  decl,
+			RawSpec:  spec,
+			RawFile:  file,
 		})
 	})
 
@@ -0,0 +1,29 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model contains a serializable model of what generators see when
+// they process a set of packages: the root packages and their group-versions,
+// their types (noting which are kinds) and fields (with their JSON names),
+// the documentation and markers on each, and (optionally) the OpenAPI schemas
+// that the CRD generator would produce for the types.
+//
+// The model is meant for tools that don't link against controller-tools,
+// such as generator plugins (see the plugin package), or tools reading the
+// JSON document written by this package's generator (`controller-gen model`),
+// so it only contains plain data.  The format is versioned (see Version).
+// Marker values are keyed by their argument names (as written in the marker),
+// rather than by the Go fields they're parsed into.
+package model
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// +controllertools:marker:generateHelp
+
+// Generator writes the model of the root packages as a JSON document.
+//
+// The model contains the packages (with their group-versions), types (noting
+// which are kinds), fields (with their JSON names), documentation and
+// collected marker values, for tools like linters and documentation
+// generators that don't link against controller-tools.  The document is
+// written to `model.json`, and its `version` field says what format it's in.
+type Generator struct {
+	// Schemas includes the OpenAPI schema of each type, as generated for
+	// CRDs.  Types that can't be described by a schema are reported as
+	// errors, so this is only suitable for API packages.
+	Schemas bool `marker:",optional"`
+}
+
+var _ genall.Generator = Generator{}
+
+func (Generator) CheckFilter() loader.NodeFilter {
+	return crd.Generator{}.CheckFilter()
+}
+
+func (Generator) RegisterMarkers(into *markers.Registry) error {
+	// the CRD markers are what group-versions (and schemas) come from
+	return crdmarkers.Register(into)
+}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	model := Build(ctx, Options{Schemas: g.Schemas})
+
+	out, err := ctx.Open(nil, "model.json")
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(model)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/model"
+)
+
+var _ = Describe("Model Generation", func() {
+	var (
+		pkgs []*loader.Package
+		cwd  string
+	)
+
+	BeforeEach(func() {
+		By("switching into testdata to appease go modules")
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+
+		By("loading the roots")
+		pkgs, err = loader.LoadRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+	})
+
+	generate := func(gen model.Generator) string {
+		By("setting up the context")
+		reg := &markers.Registry{}
+		Expect(gen.RegisterMarkers(reg)).To(Succeed())
+		out := &outputRule{files: make(map[string]*bytes.Buffer)}
+		ctx := &genall.GenerationContext{
+			Collector:  &markers.Collector{Registry: reg},
+			Roots:      pkgs,
+			Checker:    &loader.TypeChecker{NodeFilters: []loader.NodeFilter{gen.CheckFilter()}},
+			OutputRule: out,
+		}
+
+		By("calling Generate")
+		Expect(gen.Generate(ctx)).To(Succeed())
+		// type errors from partially-checked dependencies are fine, just like in controller-gen
+		Expect(loader.PrintErrors(pkgs, packages.TypeError)).To(BeFalse(), "packages should have no errors")
+		Expect(out.files).To(HaveKey("model.json"))
+		return out.files["model.json"].String()
+	}
+
+	It("should write the model of the roots", func() {
+		actual := generate(model.Generator{})
+
+		By("comparing to the golden file")
+		expected, err := ioutil.ReadFile("model.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(string(expected)), cmp.Diff(actual, string(expected)))
+	})
+
+	It("should include schemas if asked", func() {
+		var written model.Model
+		Expect(json.Unmarshal([]byte(generate(model.Generator{Schemas: true})), &written)).To(Succeed())
+
+		Expect(written.Version).To(Equal(model.Version))
+		for _, typ := range written.Packages[0].Types {
+			Expect(typ.Schema).NotTo(BeNil(), "type %s should have a schema", typ.Name)
+		}
+		gadget := written.Packages[0].Types[2]
+		Expect(gadget.Name).To(Equal("Gadget"))
+		Expect(gadget.Schema.Properties).To(HaveKey("spec"))
+	})
+})
+
+type outputRule struct {
+	files map[string]*bytes.Buffer
+}
+
+func (o *outputRule) Open(_ *loader.Package, itemPath string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	o.files[itemPath] = buf
+	return nopCloser{buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (n nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// Version is the version of the model's format.  Changes to the format
+// that existing consumers might not cope with get a new version.
+const Version = "v1alpha1"
+
+// Model describes a set of root packages.
+type Model struct {
+	// Version is the version of the model's format (see Version).
+	Version string `json:"version"`
+	// Packages are the root packages, in the order they were loaded.
+	Packages []Package `json:"packages"`
+}
+
+// Package describes a single root package.
+type Package struct {
+	// ID is the package's ID, as used by the Go tooling (usually the same
+	// as its import path).
+	ID string `json:"id"`
+	// Name is the package's name.
+	Name string `json:"name"`
+	// PkgPath is the package's import path.
+	PkgPath string `json:"pkgPath"`
+	// GroupVersion is the API group-version of the package, if it has one
+	// (from its `+groupName` and `+versionName` markers).
+	GroupVersion *GroupVersion `json:"groupVersion,omitempty"`
+	// Markers are the package-level markers.
+	Markers MarkerValues `json:"markers,omitempty"`
+	// Types are the types declared in the package, in source order.
+	Types []Type `json:"types,omitempty"`
+}
+
+// GroupVersion is an API group-version.
+type GroupVersion struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+}
+
+// Type describes a type declared in a root package.
+type Type struct {
+	// Name is the type's name.
+	Name string `json:"name"`
+	// Kind marks types that are Kubernetes objects (that embed both TypeMeta
+	// and ObjectMeta) in a package with a group-version.
+	Kind bool `json:"kind,omitempty"`
+	// Doc is the type's Godoc, with markers removed and single newlines
+	// joined together.
+	Doc string `json:"doc,omitempty"`
+	// Markers are the type-level markers.
+	Markers MarkerValues `json:"markers,omitempty"`
+	// Fields are the type's fields, if it's a struct.
+	Fields []Field `json:"fields,omitempty"`
+	// Schema is the OpenAPI schema for the type, if schemas were asked for.
+	// References to other types are of the form
+	// `#/definitions/<escaped package path>~0<type name>`.
+	Schema *apiext.JSONSchemaProps `json:"schema,omitempty"`
+}
+
+// Field describes a field of a struct type.
+type Field struct {
+	// Name is the field's name, or empty for embedded fields.
+	Name string `json:"name,omitempty"`
+	// Doc is the field's Godoc, with markers removed and single newlines
+	// joined together.
+	Doc string `json:"doc,omitempty"`
+	// Type is the field's type, as written in the source.
+	Type string `json:"type"`
+	// QualifiedType is the field's type with full package paths, like
+	// `k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta`.
+	QualifiedType string `json:"qualifiedType,omitempty"`
+	// Tag is the field's struct tag.
+	Tag string `json:"tag,omitempty"`
+	// JSONName is the name of the field when serialized, from its `json`
+	// tag.  It's empty for fields that aren't serialized, and for inline
+	// fields without a name.
+	JSONName string `json:"jsonName,omitempty"`
+	// Inline marks fields whose own fields are serialized in place of the
+	// field itself (embedded fields, and those marked `inline`).
+	Inline bool `json:"inline,omitempty"`
+	// OmitEmpty marks fields that are left out when serialized if they're
+	// empty.
+	OmitEmpty bool `json:"omitempty,omitempty"`
+	// Markers are the field-level markers.
+	Markers MarkerValues `json:"markers,omitempty"`
+}
+
+// MarkerValues are the values of the markers on a package, type or field,
+// by marker name.  Markers with named arguments have a value that's an
+// object of their arguments, keyed by argument name; other markers have
+// the value of their single argument.
+type MarkerValues map[string][]interface{}
+
+// Options configure what's included in a model.
+type Options struct {
+	// Schemas includes the OpenAPI schema for each type, as generated for
+	// CRDs.  This requires the CRD markers to be registered with the
+	// collector, and reports an error for any type that can't be described
+	// by a schema, so it's only suitable for API packages.
+	Schemas bool
+}
+
+// Build builds the model of the roots in the given context.  Problems with
+// individual packages are added to the packages, as with generators.
+//
+// Group-versions and kinds are found by the CRD parser, so they're only
+// included if the CRD markers are registered with the collector, and the
+// context must have a type checker (see genall.NeedsTypeChecking).
+func Build(ctx *genall.GenerationContext, opts Options) *Model {
+	parser := crd.SharedParserFor(ctx, crd.ParserSettings{})
+
+	res := &Model{Version: Version, Packages: []Package{}}
+	for _, root := range ctx.Roots {
+		pkg := Package{ID: root.ID, Name: root.Name, PkgPath: root.PkgPath}
+		root.NeedTypesInfo()
+
+		var kinds map[string]bool
+		pkg.GroupVersion, kinds = kindsIn(parser, root)
+
+		pkgMarkers, err := markers.PackageMarkers(ctx.Collector, root)
+		if err != nil {
+			root.AddError(err)
+		}
+		pkg.Markers = valuesFor(ctx.Collector.Registry, pkgMarkers, markers.DescribesPackage)
+
+		if err := markers.EachType(ctx.Collector, root, func(info *markers.TypeInfo) {
+			typ := Type{
+				Name:    info.Name,
+				Kind:    kinds[info.Name],
+				Doc:     info.Doc,
+				Markers: valuesFor(ctx.Collector.Registry, info.Markers, markers.DescribesType),
+			}
+			for _, field := range info.Fields {
+				modelField := Field{
+					Name:    field.Name,
+					Doc:     field.Doc,
+					Type:    types.ExprString(field.RawField.Type),
+					Tag:     string(field.Tag),
+					Markers: valuesFor(ctx.Collector.Registry, field.Markers, markers.DescribesField),
+				}
+				if fieldType := root.TypesInfo.TypeOf(field.RawField.Type); fieldType != nil {
+					modelField.QualifiedType = types.TypeString(fieldType, nil)
+				}
+				setJSONOptions(&modelField, field)
+				typ.Fields = append(typ.Fields, modelField)
+			}
+			if opts.Schemas {
+				typ.Schema = schemaFor(parser, root, info.Name)
+			}
+			pkg.Types = append(pkg.Types, typ)
+		}); err != nil {
+			root.AddError(err)
+		}
+
+		res.Packages = append(res.Packages, pkg)
+	}
+	return res
+}
+
+// setJSONOptions fills in how the given field is serialized, from its `json`
+// tag, the same way the CRD generator does.
+func setJSONOptions(modelField *Field, field markers.FieldInfo) {
+	jsonTag, hasTag := field.Tag.Lookup("json")
+	if !hasTag {
+		modelField.Inline = field.Name == ""
+		return
+	}
+	jsonOpts := strings.Split(jsonTag, ",")
+	if len(jsonOpts) == 1 && jsonOpts[0] == "-" {
+		// skipped fields have the tag "-" (note that "-," means the field is named "-")
+		return
+	}
+	for _, opt := range jsonOpts[1:] {
+		switch opt {
+		case "inline":
+			modelField.Inline = true
+		case "omitempty":
+			modelField.OmitEmpty = true
+		}
+	}
+	modelField.JSONName = jsonOpts[0]
+	modelField.Inline = modelField.Inline || modelField.JSONName == ""
+}
+
+// kindsIn returns the group-version of the given package, if it has one,
+// along with the names of the kinds it defines.
+func kindsIn(parser *crd.SharedParser, pkg *loader.Package) (*GroupVersion, map[string]bool) {
+	parser.Lock()
+	defer parser.Unlock()
+
+	kinds := make(map[string]bool)
+	gv, hasGV := parser.GroupVersions[pkg]
+	if !hasGV {
+		return nil, kinds
+	}
+	for _, groupKind := range parser.KubeKinds {
+		if groupKind.Group == gv.Group && parser.LookupType(pkg, groupKind.Kind) != nil {
+			kinds[groupKind.Kind] = true
+		}
+	}
+	return &GroupVersion{Group: gv.Group, Version: gv.Version}, kinds
+}
+
+// schemaFor returns a copy of the schema for the given type.
+func schemaFor(parser *crd.SharedParser, pkg *loader.Package, name string) *apiext.JSONSchemaProps {
+	parser.Lock()
+	defer parser.Unlock()
+
+	ident := crd.TypeIdent{Package: pkg, Name: name}
+	if parser.LookupType(pkg, name) == nil {
+		// skipped by the parser (e.g. via +kubebuilder:skip)
+		return nil
+	}
+	parser.NeedSchemaFor(ident)
+	schema := parser.Schemata[ident]
+	return schema.DeepCopy()
+}
+
+// valuesFor converts the given marker values into their serializable form.
+func valuesFor(reg *markers.Registry, values markers.MarkerValues, target markers.TargetType) MarkerValues {
+	if len(values) == 0 {
+		return nil
+	}
+	res := make(MarkerValues, len(values))
+	for name, vals := range values {
+		def := reg.Lookup("+"+name, target)
+		for _, val := range vals {
+			res[name] = append(res[name], ArgumentsOf(def, val))
+		}
+	}
+	return res
+}
+
+// ArgumentsOf converts the value of a marker with the given definition into
+// its serializable form: a map of argument names to values for markers with
+// named arguments (leaving out optional arguments that weren't given, or
+// that have their zero value, if they aren't pointers), or the value itself
+// otherwise.
+func ArgumentsOf(def *markers.Definition, val interface{}) interface{} {
+	structVal := reflect.ValueOf(val)
+	if def == nil || structVal.Kind() != reflect.Struct {
+		return val
+	}
+	if def.AnonymousField() {
+		if fieldName := def.FieldNames[""]; fieldName != "" {
+			// a struct with a single field that's used as the value
+			return structVal.FieldByName(fieldName).Interface()
+		}
+		return val
+	}
+	args := make(map[string]interface{}, len(def.FieldNames))
+	for argName, fieldName := range def.FieldNames {
+		field := structVal.FieldByName(fieldName)
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		} else if def.Fields[argName].Optional && field.IsZero() {
+			// we can't tell if it was given, but it'd mean the same thing anyway
+			continue
+		}
+		args[argName] = field.Interface()
+	}
+	return args
+}
@@ -0,0 +1,60 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//go:generate ../../../../.run-controller-gen.sh model paths=../... output:dir=..
+
+// +groupName=tools.example.com
+// +versionName=v1beta1
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GadgetSpec defines the desired state of a Gadget.
+type GadgetSpec struct {
+	// Size is how big the gadget is.
+	// +kubebuilder:validation:Minimum=1
+	Size int32 `json:"size"`
+
+	// Labels are extra labels,
+	// copied onto everything the gadget makes.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Parts are shared with other gadgets.
+	Parts `json:",inline"`
+
+	// cache is never serialized.
+	cache map[string]string `json:"-"`
+}
+
+// Parts are the parts of a gadget.
+type Parts struct {
+	// Wheels is the number of wheels.
+	// +kubebuilder:validation:Enum=2;4
+	Wheels int `json:"wheels,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// Gadget is a thing that does things.
+type Gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GadgetSpec `json:"spec,omitempty"`
+}
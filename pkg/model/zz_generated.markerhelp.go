@@ -0,0 +1,42 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by helpgen. DO NOT EDIT.
+
+package model
+
+import (
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+func (Generator) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "writes the model of the root packages as a JSON document. ",
+			Details: "The model contains the packages (with their group-versions), types (noting which are kinds), fields (with their JSON names), documentation and collected marker values, for tools like linters and documentation generators that don't link against controller-tools.  The document is written to `model.json`, and its `version` field says what format it's in.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Schemas": {
+				Summary: "includes the OpenAPI schema of each type, as generated for CRDs.  Types that can't be described by a schema are reported as errors, so this is only suitable for API packages.",
+				Details: "",
+			},
+		},
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plugin runs generators that live in separate executables, so that
+// projects can add their own generators without building their own copy of
+// controller-gen.
+//
+// A plugin named `foo` is an executable called `controller-gen-foo` (found on
+// the PATH), and is used by passing the `foo` option (with any of its
+// arguments) to controller-gen, just like a built-in generator.
+//
+// # Protocol
+//
+// Plugins are run with a single argument, saying what's wanted, and exchange
+// JSON documents over their standard input and output.  Anything written to
+// standard error is shown if the plugin fails (exits with a non-zero status).
+//
+// When run with `manifest`, a plugin writes its Manifest: the version of the
+// protocol it speaks (ProtocolVersion), help for the plugin, the options it
+// takes, and the markers it understands.  Options and marker arguments have
+// types written like Go types: `string`, `int`, `number`, `bool`, `any`,
+// `[]T` and `map[string]T`.
+//
+// When run with `generate`, a plugin reads a Request, containing the values
+// of its options, and the Model of the root packages (see the model package),
+// with the values of its markers.  It writes a Response, containing the files
+// to write (which go through controller-gen's usual output rules), and any
+// errors to report, optionally with their position in the source.
+package plugin
@@ -0,0 +1,182 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/model"
+)
+
+// Generator runs a plugin.
+type Generator struct {
+	// Plugin is the plugin to run.
+	Plugin *Plugin
+	// Options are the values of the plugin's options that were given, by
+	// name.
+	Options map[string]interface{}
+}
+
+func (Generator) CheckFilter() loader.NodeFilter {
+	return crd.Generator{}.CheckFilter()
+}
+
+func (g Generator) RegisterMarkers(into *markers.Registry) error {
+	// the CRD markers are what group-versions (and schemas) come from
+	if err := crdmarkers.Register(into); err != nil {
+		return err
+	}
+	for _, marker := range g.Plugin.Manifest.Markers {
+		target, knownTarget := targets[marker.Target]
+		if !knownTarget {
+			return fmt.Errorf("plugin %q: marker %q has unknown target %q", g.Plugin.Name, marker.Name, marker.Target)
+		}
+		def, help, err := definitionFor(marker.Name, target, marker.Arguments, marker.Summary, marker.Details)
+		if err != nil {
+			return fmt.Errorf("plugin %q: invalid marker %q: %w", g.Plugin.Name, marker.Name, err)
+		}
+		help.Category = g.Plugin.Name
+		if err := into.Register(def); err != nil {
+			return err
+		}
+		into.AddHelp(def, help)
+	}
+	return nil
+}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	req := Request{
+		ProtocolVersion: ProtocolVersion,
+		Options:         g.Options,
+		Model:           model.Build(ctx, model.Options{Schemas: g.Plugin.Manifest.Schemas}),
+	}
+	var resp Response
+	if err := g.Plugin.run("generate", req, &resp); err != nil {
+		return err
+	}
+
+	rootsByID := make(map[string]*loader.Package, len(ctx.Roots))
+	for _, root := range ctx.Roots {
+		rootsByID[root.ID] = root
+	}
+
+	var errs []error
+	for _, file := range resp.Files {
+		if !isRelativePath(file.Path) {
+			errs = append(errs, fmt.Errorf("plugin %q wrote %s outside of its output: paths must be relative, and can't start with ..", g.Plugin.Name, file.Path))
+			continue
+		}
+		var pkg *loader.Package
+		if file.Package != "" {
+			var known bool
+			if pkg, known = rootsByID[file.Package]; !known {
+				errs = append(errs, fmt.Errorf("plugin %q wrote %s for unknown package %q", g.Plugin.Name, file.Path, file.Package))
+				continue
+			}
+		}
+		if err := writeFile(ctx, pkg, file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, pluginErr := range resp.Errors {
+		root, known := rootsByID[pluginErr.Package]
+		if !known {
+			errs = append(errs, errors.New(pluginErr.Message))
+			continue
+		}
+		root.AddError(errorIn(root, pluginErr))
+	}
+
+	return loader.MaybeErrList(errs)
+}
+
+// isRelativePath checks that the given path from a plugin stays within
+// wherever the output rule puts it.
+func isRelativePath(path string) bool {
+	if filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	return path != ".." && !strings.HasPrefix(path, ".."+string(filepath.Separator))
+}
+
+// writeFile writes a file from the plugin through the output rule.
+func writeFile(ctx *genall.GenerationContext, pkg *loader.Package, file File) error {
+	out, err := ctx.Open(pkg, file.Path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = out.Write([]byte(file.Contents))
+	return err
+}
+
+// errorIn returns the given error from the plugin, positioned in the given
+// package if it has a known position.
+func errorIn(pkg *loader.Package, pluginErr Error) error {
+	err := errors.New(pluginErr.Message)
+	if pluginErr.File == "" {
+		return err
+	}
+	// type-checking gives us the file set
+	pkg.NeedTypesInfo()
+
+	line, col := pluginErr.Line, pluginErr.Column
+	if line < 1 {
+		line = 1
+	}
+	if col < 1 {
+		col = 1
+	}
+	for _, file := range pkg.Syntax {
+		tokFile := pkg.Fset.File(file.Pos())
+		if !sameFile(tokFile.Name(), pluginErr.File) || line > tokFile.LineCount() {
+			continue
+		}
+		pos := tokFile.LineStart(line) + token.Pos(col-1)
+		if int(pos) > tokFile.Base()+tokFile.Size() {
+			pos = tokFile.LineStart(line)
+		}
+		return loader.ErrFromNode(err, position(pos))
+	}
+	return fmt.Errorf("%s:%d:%d: %w", pluginErr.File, pluginErr.Line, pluginErr.Column, err)
+}
+
+// sameFile checks if the given paths refer to the same file, allowing the
+// second to be relative to the working directory.
+func sameFile(path, other string) bool {
+	absOther, err := filepath.Abs(other)
+	if err != nil {
+		return false
+	}
+	return filepath.Clean(path) == absOther
+}
+
+// position is a loader.Node at a fixed position.
+type position token.Pos
+
+func (p position) Pos() token.Pos { return token.Pos(p) }
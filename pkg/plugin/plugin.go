@@ -0,0 +1,217 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/model"
+)
+
+// ExecutablePrefix is the prefix of the names of plugin executables.
+const ExecutablePrefix = "controller-gen-"
+
+// Plugin is a generator in a separate executable.
+type Plugin struct {
+	// Name is the name of the plugin, as used in options.
+	Name string
+	// Path is the path to the plugin's executable.
+	Path string
+	// Manifest describes the plugin.
+	Manifest Manifest
+}
+
+// Find finds the plugin with the given name on the PATH, and loads its
+// manifest.  It returns nil (and no error) if there's no such plugin.
+func Find(name string) (*Plugin, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, nil
+	}
+	path, err := exec.LookPath(ExecutablePrefix + name)
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Load(name, path)
+}
+
+// Load loads the manifest of the plugin with the given name from the given
+// executable.
+func Load(name, path string) (*Plugin, error) {
+	plugin := &Plugin{Name: name, Path: path}
+	if err := plugin.run("manifest", nil, &plugin.Manifest); err != nil {
+		return nil, err
+	}
+	if plugin.Manifest.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("plugin %q speaks protocol version %q, but only %q is supported", name, plugin.Manifest.ProtocolVersion, ProtocolVersion)
+	}
+	return plugin, nil
+}
+
+// run runs the plugin with the given argument, sending it the given request
+// (if any) and decoding its output into the given response.
+func (p *Plugin) run(what string, req interface{}, resp interface{}) error {
+	cmd := exec.Command(p.Path, what)
+	if req != nil {
+		reqRaw, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = bytes.NewReader(reqRaw)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %q failed (%w): %s", p.Name, err, msg)
+		}
+		return fmt.Errorf("plugin %q failed: %w", p.Name, err)
+	}
+
+	if err := json.NewDecoder(&stdout).Decode(resp); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid %s from plugin %q: %w", what, p.Name, err)
+	}
+	return nil
+}
+
+// Register registers the options marker for the plugin in the given options
+// registry, so that it can be used like any other generator.
+func Register(optionsRegistry *markers.Registry, p *Plugin) error {
+	def, help, err := definitionFor(p.Name, markers.DescribesPackage, p.Manifest.Options, p.Manifest.Summary, p.Manifest.Details)
+	if err != nil {
+		return fmt.Errorf("invalid options for plugin %q: %w", p.Name, err)
+	}
+	if err := optionsRegistry.Register(def); err != nil {
+		return err
+	}
+	optionsRegistry.AddHelp(def, help)
+	genall.RegisterGeneratorFactory(def, func(options interface{}) genall.Generator {
+		return Generator{Plugin: p, Options: argumentsOf(def, options)}
+	})
+	return nil
+}
+
+// targets maps target names in manifests to target types.
+var targets = map[string]markers.TargetType{
+	"package": markers.DescribesPackage,
+	"type":    markers.DescribesType,
+	"field":   markers.DescribesField,
+}
+
+// definitionFor makes a marker definition (and help) with the given
+// arguments.  Markers with named arguments are parsed into structs built on
+// the fly.
+func definitionFor(name string, target markers.TargetType, args []Argument, summary, details string) (*markers.Definition, *markers.DefinitionHelp, error) {
+	help := &markers.DefinitionHelp{
+		DetailedHelp: markers.DetailedHelp{Summary: summary, Details: details},
+		FieldHelp:    make(map[string]markers.DetailedHelp, len(args)),
+	}
+
+	var output interface{}
+	switch {
+	case len(args) == 0:
+		output = struct{}{}
+	case len(args) == 1 && args[0].Name == "":
+		typ, err := typeFor(args[0].Type)
+		if err != nil {
+			return nil, nil, err
+		}
+		output = reflect.Zero(typ).Interface()
+	default:
+		fields := make([]reflect.StructField, len(args))
+		for i, arg := range args {
+			if arg.Name == "" {
+				return nil, nil, fmt.Errorf("only a single argument may be unnamed")
+			}
+			typ, err := typeFor(arg.Type)
+			if err != nil {
+				return nil, nil, fmt.Errorf("argument %q: %w", arg.Name, err)
+			}
+			tag := arg.Name
+			if arg.Optional {
+				// pointers let us tell which optional arguments were given
+				typ = reflect.PtrTo(typ)
+				tag += ",optional"
+			}
+			fields[i] = reflect.StructField{
+				Name: fmt.Sprintf("Arg%d", i),
+				Type: typ,
+				Tag:  reflect.StructTag(fmt.Sprintf("marker:%q", tag)),
+			}
+			help.FieldHelp[fields[i].Name] = markers.DetailedHelp{Summary: arg.Summary, Details: arg.Details}
+		}
+		output = reflect.Zero(reflect.StructOf(fields)).Interface()
+	}
+
+	def, err := markers.MakeDefinition(name, target, output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return def, help, nil
+}
+
+// typeFor returns the Go type for the given argument type.
+func typeFor(typ string) (reflect.Type, error) {
+	switch {
+	case typ == "string":
+		return reflect.TypeOf(""), nil
+	case typ == "int":
+		return reflect.TypeOf(0), nil
+	case typ == "number":
+		return reflect.TypeOf(float64(0)), nil
+	case typ == "bool":
+		return reflect.TypeOf(false), nil
+	case typ == "any":
+		return reflect.TypeOf((*interface{})(nil)).Elem(), nil
+	case strings.HasPrefix(typ, "[]"):
+		itemType, err := typeFor(typ[2:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(itemType), nil
+	case strings.HasPrefix(typ, "map[string]"):
+		itemType, err := typeFor(typ[len("map[string]"):])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(reflect.TypeOf(""), itemType), nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+// argumentsOf returns the arguments that were given in the parsed value of
+// a marker with the given definition, by name.
+func argumentsOf(def *markers.Definition, val interface{}) map[string]interface{} {
+	args := model.ArgumentsOf(def, val)
+	if named, isNamed := args.(map[string]interface{}); isNamed {
+		return named
+	}
+	return map[string]interface{}{"": args}
+}
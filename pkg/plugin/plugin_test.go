@@ -0,0 +1,275 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+func TestDefinitionFor(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	def, help, err := definitionFor("test:named", markers.DescribesType, []Argument{
+		{Name: "verbs", Type: "[]string", Summary: "are the verbs."},
+		{Name: "level", Type: "int", Optional: true},
+		{Name: "labels", Type: "map[string]string", Optional: true},
+	}, "is a test.", "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(help.Summary).To(gomega.Equal("is a test."))
+	g.Expect(help.FieldHelp).To(gomega.HaveKeyWithValue("Arg0", markers.DetailedHelp{Summary: "are the verbs."}))
+
+	val, err := def.Parse("+test:named:verbs={get,list},level=2")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(argumentsOf(def, val)).To(gomega.Equal(map[string]interface{}{
+		"verbs": []string{"get", "list"},
+		"level": 2,
+	}))
+
+	_, err = def.Parse("+test:named:level=2")
+	g.Expect(err).To(gomega.HaveOccurred(), "verbs are required")
+
+	def, _, err = definitionFor("test:value", markers.DescribesField, []Argument{{Type: "number"}}, "", "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	val, err = def.Parse("+test:value=1.5")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(argumentsOf(def, val)).To(gomega.Equal(map[string]interface{}{"": 1.5}))
+
+	_, _, err = definitionFor("test:bad", markers.DescribesField, []Argument{{Name: "a", Type: "complex"}}, "", "")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown type "complex"`)))
+	_, _, err = definitionFor("test:bad", markers.DescribesField, []Argument{{Name: "a", Type: "int"}, {Type: "int"}}, "", "")
+	g.Expect(err).To(gomega.HaveOccurred(), "only a single argument may be unnamed")
+}
+
+const fakeManifest = `{
+	"protocolVersion": "v1alpha1",
+	"summary": "generates policies.",
+	"options": [{"name": "bundle", "type": "string"}],
+	"markers": [{"name": "policy:allow", "target": "type", "arguments": [{"name": "verbs", "type": "[]string"}]}]
+}`
+
+const fakeResponse = `{
+	"files": [
+		{"path": "bundle.yaml", "contents": "bundle: all\n"},
+		{"package": "example.com/plugin/a", "path": "policy.txt", "contents": "A\n"}
+	],
+	"errors": [
+		{"package": "example.com/plugin/a", "file": "a/a.go", "line": 4, "column": 6, "message": "A allows too much"}
+	]
+}`
+
+// writeFakePlugin writes a fake plugin with the given name to the given
+// directory.  It records its request (in <name>.json, next to it), and gives
+// a canned response.
+func writeFakePlugin(g *gomega.WithT, binDir, name, manifest, response string) {
+	reqPath := filepath.Join(binDir, name+".json")
+	script := "#!/bin/sh\ncase \"$1\" in\n" +
+		"manifest) cat <<'EOF'\n" + manifest + "\nEOF\n;;\n" +
+		"generate) cat > '" + reqPath + "'; cat <<'EOF'\n" + response + "\nEOF\n;;\n" +
+		"esac\n"
+	g.Expect(os.WriteFile(filepath.Join(binDir, ExecutablePrefix+name), []byte(script), 0755)).To(gomega.Succeed())
+}
+
+func TestPlugin(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "a"), os.ModePerm)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/plugin\n\ngo 1.19\n"), 0644)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte(`package a
+
+// +policy:allow:verbs={get,list}
+type A struct {
+	B int
+}
+`), 0644)).To(gomega.Succeed())
+
+	binDir := t.TempDir()
+	reqPath := filepath.Join(binDir, "policy.json")
+	writeFakePlugin(g, binDir, "policy", fakeManifest, fakeResponse)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	missing, err := Find("missing")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(missing).To(gomega.BeNil())
+
+	p, err := Find("policy")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(p.Manifest.Summary).To(gomega.Equal("generates policies."))
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	optionsRegistry := &markers.Registry{}
+	g.Expect(Register(optionsRegistry, p)).To(gomega.Succeed())
+	g.Expect(optionsRegistry.Register(markers.Must(markers.MakeDefinition("output:policy:dir", markers.DescribesPackage, genall.OutputToDirectory(""))))).To(gomega.Succeed())
+	g.Expect(genall.RegisterOptionsMarkers(optionsRegistry)).To(gomega.Succeed())
+
+	outDir := t.TempDir()
+	rt, err := genall.FromOptions(optionsRegistry, []string{"policy:bundle=all", "paths=./a", "output:policy:dir=" + outDir})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var errOut bytes.Buffer
+	rt.ErrorWriter = &errOut
+	rt.DiagnosticsFormat = genall.DiagnosticsJSON
+	g.Expect(rt.Run()).To(gomega.BeTrue(), "the plugin reported an error")
+
+	var report struct {
+		Diagnostics []genall.Diagnostic `json:"diagnostics"`
+	}
+	g.Expect(json.Unmarshal(errOut.Bytes(), &report)).To(gomega.Succeed())
+	g.Expect(report.Diagnostics).To(gomega.ConsistOf(genall.Diagnostic{
+		File:      filepath.Join(dir, "a", "a.go"),
+		Line:      4,
+		Column:    6,
+		Severity:  genall.SeverityError,
+		Generator: "policy",
+		Message:   "A allows too much",
+	}))
+
+	// the plugin got its options and markers
+	reqRaw, err := os.ReadFile(reqPath)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var req struct {
+		Options map[string]interface{} `json:"options"`
+		Model   struct {
+			Packages []struct {
+				ID    string `json:"id"`
+				Types []struct {
+					Name    string                   `json:"name"`
+					Markers map[string][]interface{} `json:"markers"`
+				} `json:"types"`
+			} `json:"packages"`
+		} `json:"model"`
+	}
+	g.Expect(json.Unmarshal(reqRaw, &req)).To(gomega.Succeed())
+	g.Expect(req.Options).To(gomega.Equal(map[string]interface{}{"bundle": "all"}))
+	g.Expect(req.Model.Packages).To(gomega.HaveLen(1))
+	g.Expect(req.Model.Packages[0].ID).To(gomega.Equal("example.com/plugin/a"))
+	g.Expect(req.Model.Packages[0].Types).To(gomega.HaveLen(1))
+	g.Expect(req.Model.Packages[0].Types[0].Markers).To(gomega.Equal(map[string][]interface{}{
+		"policy:allow": {map[string]interface{}{"verbs": []interface{}{"get", "list"}}},
+	}))
+
+	for name, contents := range map[string]string{"bundle.yaml": "bundle: all\n", "policy.txt": "A\n"} {
+		written, err := os.ReadFile(filepath.Join(outDir, name))
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(string(written)).To(gomega.Equal(contents))
+	}
+}
+
+func TestPluginPathsOutsideOutput(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "a"), os.ModePerm)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/plugin\n\ngo 1.19\n"), 0644)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte("package a\n"), 0644)).To(gomega.Succeed())
+
+	outDir := filepath.Join(t.TempDir(), "out")
+	escaped := filepath.Join(filepath.Dir(outDir), "escaped.txt")
+	binDir := t.TempDir()
+	writeFakePlugin(g, binDir, "policy", `{"protocolVersion": "v1alpha1"}`, `{
+		"files": [
+			{"path": "../escaped.txt", "contents": "escaped\n"},
+			{"path": "nested/../../escaped.txt", "contents": "escaped\n"},
+			{"path": "`+escaped+`", "contents": "escaped\n"},
+			{"path": "nested/../kept.txt", "contents": "kept\n"}
+		]
+	}`)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	p, err := Find("policy")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	optionsRegistry := &markers.Registry{}
+	g.Expect(Register(optionsRegistry, p)).To(gomega.Succeed())
+	g.Expect(optionsRegistry.Register(markers.Must(markers.MakeDefinition("output:dir", markers.DescribesPackage, genall.OutputToDirectory(""))))).To(gomega.Succeed())
+	g.Expect(genall.RegisterOptionsMarkers(optionsRegistry)).To(gomega.Succeed())
+
+	rt, err := genall.FromOptions(optionsRegistry, []string{"policy", "paths=./a", "output:dir=" + outDir})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var errOut bytes.Buffer
+	rt.ErrorWriter = &errOut
+	g.Expect(rt.Run()).To(gomega.BeTrue(), "paths outside the output weren't rejected")
+	g.Expect(errOut.String()).To(gomega.ContainSubstring(`plugin "policy" wrote ../escaped.txt outside of its output`))
+	g.Expect(errOut.String()).To(gomega.ContainSubstring(`plugin "policy" wrote nested/../../escaped.txt outside of its output`))
+	g.Expect(errOut.String()).To(gomega.ContainSubstring(`plugin "policy" wrote ` + escaped + ` outside of its output`))
+
+	g.Expect(escaped).NotTo(gomega.BeAnExistingFile())
+	g.Expect(filepath.Join(outDir, "kept.txt")).To(gomega.BeAnExistingFile())
+}
+
+func TestPrunePlugins(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "a"), os.ModePerm)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/plugin\n\ngo 1.19\n"), 0644)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte("package a\n"), 0644)).To(gomega.Succeed())
+
+	binDir := t.TempDir()
+	writeFakePlugin(g, binDir, "policy", `{"protocolVersion": "v1alpha1"}`, `{"files": [{"path": "policy.yaml", "contents": "policy\n"}]}`)
+	writeFakePlugin(g, binDir, "docs", `{"protocolVersion": "v1alpha1"}`, `{"files": [{"path": "docs.md", "contents": "docs\n"}]}`)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cwd, err := os.Getwd()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(os.Chdir(dir)).To(gomega.Succeed())
+	defer func() { g.Expect(os.Chdir(cwd)).To(gomega.Succeed()) }()
+
+	outDir := t.TempDir()
+	run := func(plugins ...string) {
+		optionsRegistry := &markers.Registry{}
+		for _, name := range plugins {
+			p, err := Find(name)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(Register(optionsRegistry, p)).To(gomega.Succeed())
+		}
+		g.Expect(optionsRegistry.Register(markers.Must(markers.MakeDefinition("output:dir", markers.DescribesPackage, genall.OutputToDirectory(""))))).To(gomega.Succeed())
+		g.Expect(genall.RegisterOptionsMarkers(optionsRegistry)).To(gomega.Succeed())
+
+		rt, err := genall.FromOptions(optionsRegistry, append(plugins, "paths=./a", "output:dir="+outDir, "prune"))
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		var errOut bytes.Buffer
+		rt.ErrorWriter = &errOut
+		g.Expect(rt.Run()).To(gomega.BeFalse(), errOut.String())
+	}
+
+	run("policy", "docs")
+	g.Expect(filepath.Join(outDir, "policy.yaml")).To(gomega.BeAnExistingFile())
+	g.Expect(filepath.Join(outDir, "docs.md")).To(gomega.BeAnExistingFile())
+
+	// plugins that aren't run don't have their output pruned, even though
+	// all plugins are the same type of generator
+	run("policy")
+	g.Expect(filepath.Join(outDir, "policy.yaml")).To(gomega.BeAnExistingFile())
+	g.Expect(filepath.Join(outDir, "docs.md")).To(gomega.BeAnExistingFile())
+}
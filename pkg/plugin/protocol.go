@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"sigs.k8s.io/controller-tools/pkg/model"
+)
+
+// ProtocolVersion is the version of the plugin protocol described here.
+const ProtocolVersion = "v1alpha1"
+
+// Manifest describes a plugin.
+type Manifest struct {
+	// ProtocolVersion is the version of the protocol the plugin speaks,
+	// which must be ProtocolVersion.
+	ProtocolVersion string `json:"protocolVersion"`
+	// Summary is a one-line description of what the plugin generates.
+	Summary string `json:"summary,omitempty"`
+	// Details contains further information about the plugin.
+	Details string `json:"details,omitempty"`
+	// Options are the options the plugin takes.
+	Options []Argument `json:"options,omitempty"`
+	// Markers are the markers the plugin understands.
+	Markers []Marker `json:"markers,omitempty"`
+	// Schemas asks for the OpenAPI schema of each type to be included in the
+	// model.
+	Schemas bool `json:"schemas,omitempty"`
+}
+
+// Argument describes an option or marker argument.
+type Argument struct {
+	// Name is the name of the argument, or empty for the single argument of
+	// a marker that takes a value, like `+foo:bar=<value>`.
+	Name string `json:"name"`
+	// Type is the type of the argument, like `string` or `[]int`.
+	Type string `json:"type"`
+	// Optional marks the argument as optional.
+	Optional bool `json:"optional,omitempty"`
+	// Summary is a one-line description of the argument.
+	Summary string `json:"summary,omitempty"`
+	// Details contains further information about the argument.
+	Details string `json:"details,omitempty"`
+}
+
+// Marker describes a marker.
+type Marker struct {
+	// Name is the name of the marker, without the leading `+`.
+	Name string `json:"name"`
+	// Target is what the marker can be used on: `package`, `type` or
+	// `field`.
+	Target string `json:"target"`
+	// Arguments are the arguments of the marker.  Markers without arguments
+	// are flags, and markers with a single unnamed argument take a value.
+	Arguments []Argument `json:"arguments,omitempty"`
+	// Summary is a one-line description of the marker.
+	Summary string `json:"summary,omitempty"`
+	// Details contains further information about the marker.
+	Details string `json:"details,omitempty"`
+}
+
+// Request is sent to a plugin to have it generate files.
+type Request struct {
+	// ProtocolVersion is the version of the protocol used for the request.
+	ProtocolVersion string `json:"protocolVersion"`
+	// Options are the values of the plugin's options that were given, by
+	// name.
+	Options map[string]interface{} `json:"options"`
+	// Model describes the root packages.
+	Model *model.Model `json:"model"`
+}
+
+// Response is returned by a plugin after generating files.
+type Response struct {
+	// Files are the files to write.
+	Files []File `json:"files,omitempty"`
+	// Errors are the problems the plugin found.  If there are any, the
+	// run fails (though files are still written).
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// File is a file written by a plugin.
+type File struct {
+	// Package is the ID of the root package the file belongs with, for files
+	// like generated code, or empty for files like manifests.  Output rules
+	// use this to decide where the file goes.
+	Package string `json:"package,omitempty"`
+	// Path is the path of the file, relative to wherever the output rule
+	// puts it.  Absolute paths, and ones outside of that (starting with ..),
+	// are rejected.
+	Path string `json:"path"`
+	// Contents are the contents of the file.
+	Contents string `json:"contents"`
+}
+
+// Error is a problem found by a plugin.
+type Error struct {
+	// Package is the ID of the root package the problem is in, if any.
+	Package string `json:"package,omitempty"`
+	// File is the path of the file the problem is in, if any.  It's only
+	// used if Package is set.
+	File string `json:"file,omitempty"`
+	// Line and Column are the (one-based) position of the problem in the
+	// file, if known.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
@@ -0,0 +1,543 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+const (
+	clientPkgPath  = "sigs.k8s.io/controller-runtime/pkg/client"
+	builderPkgPath = "sigs.k8s.io/controller-runtime/pkg/builder"
+	sourcePkgPath  = "sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// clientMethod describes what a controller-runtime method needs.
+type clientMethod struct {
+	// arg is the index of the argument that's the object acted on.
+	arg int
+	// verbs are the verbs needed on the object's resource.
+	verbs []string
+	// cached is set for methods served from a manager's cache, which lists
+	// and watches objects across all namespaces.
+	cached bool
+}
+
+var (
+	// clientMethods are the methods of clients, by name.  Reads through a
+	// manager's client are served from a cache, which lists and watches the
+	// objects it holds.
+	clientMethods = map[string]clientMethod{
+		"Get":         {arg: 2, verbs: []string{"get", "list", "watch"}, cached: true},
+		"List":        {arg: 1, verbs: []string{"list", "watch"}, cached: true},
+		"Watch":       {arg: 1, verbs: []string{"watch"}, cached: true},
+		"Create":      {arg: 1, verbs: []string{"create"}},
+		"Update":      {arg: 1, verbs: []string{"update"}},
+		"Patch":       {arg: 1, verbs: []string{"patch"}},
+		"Delete":      {arg: 1, verbs: []string{"delete"}},
+		"DeleteAllOf": {arg: 1, verbs: []string{"deletecollection"}},
+	}
+
+	// subResourceMethods are the methods of subresource clients (like the
+	// one returned by Status()), by name.
+	subResourceMethods = map[string]clientMethod{
+		"Get":    {arg: 1, verbs: []string{"get"}},
+		"Create": {arg: 1, verbs: []string{"create"}},
+		"Update": {arg: 1, verbs: []string{"update"}},
+		"Patch":  {arg: 1, verbs: []string{"patch"}},
+	}
+
+	// subResourceClients are the names of the interfaces that act on a
+	// subresource.
+	subResourceClients = map[string]bool{
+		"StatusWriter":      true,
+		"SubResourceWriter": true,
+		"SubResourceReader": true,
+		"SubResourceClient": true,
+	}
+
+	// builderMethods are the methods of the controller builder that set up
+	// watches, by name.
+	builderMethods = map[string]clientMethod{
+		"For":     {arg: 0, verbs: []string{"list", "watch"}, cached: true},
+		"Owns":    {arg: 0, verbs: []string{"list", "watch"}, cached: true},
+		"Watches": {arg: 0, verbs: []string{"list", "watch"}, cached: true},
+	}
+
+	// sourceKind is what source.Kind needs.
+	sourceKind = clientMethod{verbs: []string{"list", "watch"}, cached: true}
+)
+
+// +controllertools:marker:generateHelp
+
+// InferGenerator checks that RBAC markers allow what controllers do with
+// controller-runtime clients.
+//
+// Calls to client methods (Get, List, Watch, Create, Update, Patch, Delete
+// and DeleteAllOf, along with those on Status() and SubResource(...)), and
+// watches set up with the controller builder or source.Kind, are matched up
+// with the group and resource of the objects they're passed.  Any rule that
+// no `+kubebuilder:rbac` marker allows is reported at the call that needs it.
+// Reads served from the manager's cache and watches list and watch objects
+// across all namespaces, so only cluster-wide markers (those without a
+// namespace) allow them; markers for any role or namespace allow other calls.
+//
+// Only objects whose types are known statically are checked: objects passed
+// as interfaces (like client.Object) or unstructured objects are skipped.
+type InferGenerator struct {
+	// Emit writes the inferred rules as a ClusterRole, to inferred_role.yaml,
+	// rather than checking that markers allow them.
+	Emit bool `marker:",optional"`
+
+	// RoleName sets the name of the ClusterRole written by emit.
+	// It must be set along with emit.
+	RoleName string `marker:",optional"`
+
+	// HeaderFile specifies the header text (e.g. license) to prepend to generated files.
+	HeaderFile string `marker:",optional"`
+
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
+}
+
+func (InferGenerator) RegisterMarkers(into *markers.Registry) error {
+	if err := into.Register(RuleDefinition); err != nil {
+		return err
+	}
+	into.AddHelp(RuleDefinition, Rule{}.Help())
+	// the CRD markers are what API groups and resource names come from
+	return crdmarkers.Register(into)
+}
+
+func (g InferGenerator) Generate(ctx *genall.GenerationContext) error {
+	if g.Emit && g.RoleName == "" {
+		return fmt.Errorf("roleName must be set along with emit")
+	}
+
+	inferrer := newRuleInferrer(ctx)
+	var rules []*Rule
+	for _, root := range ctx.Roots {
+		for _, inferred := range inferrer.inferRules(root) {
+			if g.Emit {
+				rules = append(rules, &Rule{
+					Groups:    []string{inferred.group},
+					Resources: []string{inferred.resource},
+					Verbs:     append([]string(nil), inferred.verbs...),
+				})
+				continue
+			}
+			if missing := inferrer.notAllowed(inferred); len(missing) > 0 {
+				root.AddError(loader.ErrFromNode(fmt.Errorf("missing RBAC rule for %s: add +kubebuilder:rbac:groups=%s,resources=%s,verbs=%s",
+					inferred.method, markerGroup(inferred.group), inferred.resource, strings.Join(missing, ";")), inferred.node))
+			}
+		}
+	}
+
+	if !g.Emit || len(rules) == 0 {
+		return nil
+	}
+
+	var headerText string
+	if g.HeaderFile != "" {
+		headerBytes, err := ctx.ReadFile(g.HeaderFile)
+		if err != nil {
+			return err
+		}
+		headerText = string(headerBytes)
+	}
+	headerText = strings.ReplaceAll(headerText, " YEAR", " "+g.Year)
+
+	return ctx.WriteYAML("inferred_role.yaml", headerText, []interface{}{rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterRole",
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: g.RoleName,
+		},
+		Rules: normalizeRules(rules),
+	}})
+}
+
+// markerGroup returns the group as written in RBAC markers.
+func markerGroup(group string) string {
+	if group == "" {
+		return "core"
+	}
+	return group
+}
+
+// inferredRule is a rule needed by a call.
+type inferredRule struct {
+	group    string
+	resource string
+	verbs    []string
+	// cached is set if the rule is needed across all namespaces, since the
+	// call is served from a cache.
+	cached bool
+
+	// method describes the call, like `List` or `Status().Update`.
+	method string
+	// node is the call the rule is needed by.
+	node ast.Node
+}
+
+// ruleInferrer infers the rules needed by the calls in packages.
+type ruleInferrer struct {
+	collector *markers.Collector
+	checker   *bodyChecker
+	// rules are the rules from the RBAC markers in the roots.
+	rules []Rule
+
+	// packages are the packages reachable from the roots, by import path.
+	packages map[string]*loader.Package
+	// resourcePaths are the resource names set with +kubebuilder:resource,
+	// by package and type name.
+	resourcePaths map[*loader.Package]map[string]string
+}
+
+func newRuleInferrer(ctx *genall.GenerationContext) *ruleInferrer {
+	inferrer := &ruleInferrer{
+		collector:     ctx.Collector,
+		packages:      make(map[string]*loader.Package),
+		resourcePaths: make(map[*loader.Package]map[string]string),
+	}
+	var visit func(pkg *loader.Package)
+	visit = func(pkg *loader.Package) {
+		if _, seen := inferrer.packages[pkg.PkgPath]; seen {
+			return
+		}
+		inferrer.packages[pkg.PkgPath] = pkg
+		for _, imported := range pkg.Imports() {
+			visit(imported)
+		}
+	}
+	for _, root := range ctx.Roots {
+		visit(root)
+		if inferrer.checker == nil {
+			// type-checking gives us the file set
+			root.NeedTypesInfo()
+			inferrer.checker = newBodyChecker(root.Fset)
+		}
+
+		markerSet, err := markers.PackageMarkers(ctx.Collector, root)
+		if err != nil {
+			root.AddError(err)
+			continue
+		}
+		for _, markerValue := range markerSet[RuleDefinition.Name] {
+			inferrer.rules = append(inferrer.rules, markerValue.(Rule))
+		}
+	}
+	return inferrer
+}
+
+// inferRules finds the rules needed by the calls in the given package, in
+// source order.
+func (i *ruleInferrer) inferRules(pkg *loader.Package) []inferredRule {
+	typesInfo := i.checker.check(pkg)
+
+	var rules []inferredRule
+	add := func(method string, node ast.Node, obj ast.Expr, subResource string, needs clientMethod) {
+		group, resource, known := i.resourceFor(typesInfo.TypeOf(obj))
+		if !known {
+			return
+		}
+		if subResource != "" {
+			resource += "/" + subResource
+		}
+		rules = append(rules, inferredRule{group: group, resource: resource, verbs: needs.verbs, cached: needs.cached, method: method, node: node})
+	}
+
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CompositeLit:
+				// source.Kind{Type: &Foo{}}
+				if !isNamed(typesInfo.TypeOf(node), sourcePkgPath, "Kind") {
+					return true
+				}
+				for _, elt := range node.Elts {
+					if field, isField := elt.(*ast.KeyValueExpr); isField {
+						if key, isIdent := field.Key.(*ast.Ident); isIdent && key.Name == "Type" {
+							add("source.Kind", node, field.Value, "", sourceKind)
+						}
+					}
+				}
+			case *ast.CallExpr:
+				sel, isSel := node.Fun.(*ast.SelectorExpr)
+				if !isSel {
+					return true
+				}
+				fn, isFunc := typesInfo.Uses[sel.Sel].(*types.Func)
+				if !isFunc || fn.Pkg() == nil {
+					return true
+				}
+				pkgPath := loader.NonVendorPath(fn.Pkg().Path())
+				recv := receiverName(fn)
+
+				switch {
+				case pkgPath == sourcePkgPath && recv == "" && fn.Name() == "Kind" && len(node.Args) == 2:
+					// source.Kind(cache, &Foo{})
+					add("source.Kind", node, node.Args[1], "", sourceKind)
+				case pkgPath == builderPkgPath && recv == "Builder":
+					if method, known := builderMethods[fn.Name()]; known && len(node.Args) > method.arg {
+						add(fn.Name(), node, node.Args[method.arg], "", method)
+					}
+				case pkgPath == clientPkgPath && subResourceClients[recv]:
+					method, known := subResourceMethods[fn.Name()]
+					if !known || len(node.Args) <= method.arg {
+						return true
+					}
+					subResource, call := subResourceOf(typesInfo, sel.X)
+					if subResource == "" {
+						return true
+					}
+					add(call+"."+fn.Name(), node, node.Args[method.arg], subResource, method)
+				case pkgPath == clientPkgPath && recv != "":
+					if method, known := clientMethods[fn.Name()]; known && len(node.Args) > method.arg {
+						add(fn.Name(), node, node.Args[method.arg], "", method)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return rules
+}
+
+// receiverName returns the name of the type the given function is a method
+// of, or "" for functions that aren't methods.
+func receiverName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return ""
+	}
+	typ := recv.Type()
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	if named, isNamed := typ.(*types.Named); isNamed {
+		return named.Obj().Name()
+	}
+	return ""
+}
+
+// subResourceOf returns the subresource that the client returned by the
+// given expression acts on (if known), along with a description of the call
+// that returned it.
+func subResourceOf(typesInfo *types.Info, expr ast.Expr) (string, string) {
+	call, isCall := expr.(*ast.CallExpr)
+	if !isCall {
+		return "", ""
+	}
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+	if !isSel {
+		return "", ""
+	}
+	switch {
+	case sel.Sel.Name == "Status" && len(call.Args) == 0:
+		return "status", "Status()"
+	case sel.Sel.Name == "SubResource" && len(call.Args) == 1:
+		val := typesInfo.Types[call.Args[0]].Value
+		if val == nil || val.Kind() != constant.String {
+			return "", ""
+		}
+		subResource := constant.StringVal(val)
+		return subResource, fmt.Sprintf("SubResource(%q)", subResource)
+	default:
+		return "", ""
+	}
+}
+
+// isNamed checks if the given type is the named type with the given package
+// path and name.
+func isNamed(typ types.Type, pkgPath, name string) bool {
+	named, isNamed := typ.(*types.Named)
+	if !isNamed || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Name() == name && loader.NonVendorPath(named.Obj().Pkg().Path()) == pkgPath
+}
+
+// resourceFor returns the API group and resource of objects of the given type
+// (or of the items of lists of the given type), if they're known.
+func (i *ruleInferrer) resourceFor(typ types.Type) (string, string, bool) {
+	named := objectType(typ)
+	if named == nil || named.Obj().Pkg() == nil {
+		return "", "", false
+	}
+	typesPkg := named.Obj().Pkg()
+	pkgPath := loader.NonVendorPath(typesPkg.Path())
+	if strings.HasPrefix(pkgPath, "k8s.io/apimachinery/") {
+		// generic objects (unstructured, partial object metadata, etc) can
+		// be of any kind
+		return "", "", false
+	}
+
+	pkg := i.packages[pkgPath]
+	group, hasGroup := groupFor(i.collector, typesPkg, pkg)
+	if !hasGroup {
+		return "", "", false
+	}
+
+	kind := named.Obj().Name()
+	resource := strings.ToLower(flect.Pluralize(kind))
+	if pkg != nil {
+		if path := i.resourcePathsIn(pkg)[kind]; path != "" {
+			resource = path
+		}
+	}
+	return group, resource, true
+}
+
+// resourcePathsIn returns the resource names set with +kubebuilder:resource
+// in the given package, by type name.
+func (i *ruleInferrer) resourcePathsIn(pkg *loader.Package) map[string]string {
+	if paths, known := i.resourcePaths[pkg]; known {
+		return paths
+	}
+	paths := make(map[string]string)
+	if err := markers.EachType(i.collector, pkg, func(info *markers.TypeInfo) {
+		if resource, isResource := info.Markers.Get("kubebuilder:resource").(crdmarkers.Resource); isResource && resource.Path != "" {
+			paths[info.Name] = resource.Path
+		}
+	}); err != nil {
+		pkg.AddError(err)
+	}
+	i.resourcePaths[pkg] = paths
+	return paths
+}
+
+// objectType returns the named type of objects of the given type: the type
+// itself (or what it points to), or the type of the items for lists.  It
+// returns nil if it's not a struct type, since interfaces can't say what
+// kind they are.
+func objectType(typ types.Type) *types.Named {
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	named, isNamed := typ.(*types.Named)
+	if !isNamed {
+		return nil
+	}
+	strct, isStruct := named.Underlying().(*types.Struct)
+	if !isStruct {
+		return nil
+	}
+
+	// lists are named after the items they contain
+	for fieldIdx := 0; fieldIdx < strct.NumFields(); fieldIdx++ {
+		field := strct.Field(fieldIdx)
+		if field.Name() != "Items" {
+			continue
+		}
+		if slice, isSlice := field.Type().(*types.Slice); isSlice {
+			return objectType(slice.Elem())
+		}
+	}
+	return named
+}
+
+// groupFor returns the API group of the given package, from its GroupName
+// constant (like the built-in API packages have), or its +groupName marker.
+// The loaded package may be nil if it's not reachable from the roots.
+func groupFor(collector *markers.Collector, typesPkg *types.Package, pkg *loader.Package) (string, bool) {
+	if groupName, isConst := typesPkg.Scope().Lookup("GroupName").(*types.Const); isConst && groupName.Val().Kind() == constant.String {
+		return constant.StringVal(groupName.Val()), true
+	}
+	if pkg == nil {
+		return "", false
+	}
+	pkgMarkers, err := markers.PackageMarkers(collector, pkg)
+	if err != nil {
+		pkg.AddError(err)
+		return "", false
+	}
+	groupName, hasGroup := pkgMarkers.Get("groupName").(string)
+	return groupName, hasGroup
+}
+
+// notAllowed returns the verbs of the given rule that no marker allows.
+// Rules needed by cached calls are only allowed by cluster-wide markers.
+func (i *ruleInferrer) notAllowed(inferred inferredRule) []string {
+	var missing []string
+	for _, verb := range inferred.verbs {
+		allowed := false
+		for _, rule := range i.rules {
+			if inferred.cached && rule.Namespace != "" {
+				continue
+			}
+			if rule.allows(inferred.group, inferred.resource, verb) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			missing = append(missing, verb)
+		}
+	}
+	return missing
+}
+
+// allows checks if the rule allows the given verb on the given resource (which
+// may include a subresource).  Rules limited to particular resource names are
+// assumed to cover the objects in question, since their names aren't known.
+func (r Rule) allows(group, resource, verb string) bool {
+	groupAllowed := false
+	for _, ruleGroup := range r.Groups {
+		if ruleGroup == "*" || ruleGroup == group || (ruleGroup == "core" && group == "") {
+			groupAllowed = true
+			break
+		}
+	}
+
+	subResourceWildcard := ""
+	if slash := strings.Index(resource, "/"); slash >= 0 {
+		subResourceWildcard = "*" + resource[slash:]
+	}
+	resourceAllowed := false
+	for _, ruleResource := range r.Resources {
+		if ruleResource == "*" || ruleResource == resource || ruleResource == subResourceWildcard {
+			resourceAllowed = true
+			break
+		}
+	}
+
+	verbAllowed := false
+	for _, ruleVerb := range r.Verbs {
+		if ruleVerb == "*" || ruleVerb == verb {
+			verbAllowed = true
+			break
+		}
+	}
+
+	return groupAllowed && resourceAllowed && verbAllowed
+}
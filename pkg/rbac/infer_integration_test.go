@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/rbac"
+)
+
+var _ = Describe("RBAC rules inferred from client usage", func() {
+	var (
+		pkgs []*loader.Package
+		cwd  string
+	)
+
+	BeforeEach(func() {
+		By("switching into testdata to appease go modules")
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/infer")).To(Succeed()) // go modules are directory-sensitive
+
+		By("loading the roots")
+		pkgs, err = loader.LoadRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(2))
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+	})
+
+	generate := func(gen rbac.InferGenerator) map[string]*bytes.Buffer {
+		By("setting up the context")
+		reg := &markers.Registry{}
+		Expect(gen.RegisterMarkers(reg)).To(Succeed())
+		out := &outputRule{files: make(map[string]*bytes.Buffer)}
+		ctx := &genall.GenerationContext{
+			Collector:  &markers.Collector{Registry: reg},
+			Roots:      pkgs,
+			OutputRule: out,
+		}
+
+		By("calling Generate")
+		Expect(gen.Generate(ctx)).To(Succeed())
+		return out.files
+	}
+
+	It("should report the rules that markers don't allow", func() {
+		Expect(generate(rbac.InferGenerator{})).To(BeEmpty())
+
+		var errs []string
+		packages.Visit([]*packages.Package{pkgs[0].Package, pkgs[1].Package}, nil, func(pkg *packages.Package) {
+			for _, err := range pkg.Errors {
+				// type errors from partially-checked dependencies are fine, just like in controller-gen
+				if err.Kind != packages.TypeError {
+					errs = append(errs, filepath.Base(err.Pos)+": "+err.Msg)
+				}
+			}
+		})
+		Expect(errs).To(ConsistOf(
+			"controller.go:48:12: missing RBAC rule for List: add +kubebuilder:rbac:groups=core,resources=secrets,verbs=list;watch",
+			"controller.go:69:24: missing RBAC rule for Status().Update: add +kubebuilder:rbac:groups=batch.example.com,resources=cronjobs/status,verbs=update",
+			// namespaced markers don't allow watching across all namespaces
+			"controller.go:76:12: missing RBAC rule for source.Kind: add +kubebuilder:rbac:groups=core,resources=configmaps,verbs=list;watch",
+		))
+	})
+
+	It("should emit the inferred rules", func() {
+		files := generate(rbac.InferGenerator{Emit: true, RoleName: "manager-role"})
+		Expect(files).To(HaveKey("inferred_role.yaml"))
+
+		expected, err := ioutil.ReadFile("inferred_role.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		actual := files["inferred_role.yaml"].String()
+		Expect(actual).To(Equal(string(expected)), "check pkg/rbac/testdata/infer/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(actual, string(expected)))
+	})
+
+	It("should require a role name to emit rules", func() {
+		ctx := &genall.GenerationContext{Roots: pkgs}
+		Expect(rbac.InferGenerator{Emit: true}.Generate(ctx)).NotTo(Succeed())
+	})
+})
+
+type outputRule struct {
+	files map[string]*bytes.Buffer
+}
+
+func (o *outputRule) Open(_ *loader.Package, itemPath string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	o.files[itemPath] = buf
+	return nopCloser{buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (n nopCloser) Close() error {
+	return nil
+}
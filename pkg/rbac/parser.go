@@ -27,6 +27,7 @@ import (
 	"sort"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -61,6 +62,18 @@ type Rule struct {
 	// If not set, the Rule belongs to the generated ClusterRole.
 	// If set, the Rule belongs to a Role, whose namespace is specified by this field.
 	Namespace string `marker:",optional"`
+	// Role specifies the name of the ClusterRole (or Role) the Rule belongs to.
+	// If not set, the Rule belongs to the role named by the generator's roleName.
+	//
+	// Rules with different roles (or namespaces) end up in different objects, so
+	// separate components can each get only the permissions they need.
+	Role string `marker:",optional"`
+}
+
+// roleKey identifies a generated ClusterRole (with no namespace) or Role.
+type roleKey struct {
+	Name      string
+	Namespace string
 }
 
 // ruleKey represents the resources and non-resources a Rule applies.
@@ -145,11 +158,24 @@ func (r *Rule) ToRule() rbacv1.PolicyRule {
 
 // +controllertools:marker:generateHelp
 
-// Generator generates ClusterRole objects.
+// Generator generates ClusterRole objects (and optionally a ServiceAccount bound to them).
 type Generator struct {
-	// RoleName sets the name of the generated ClusterRole.
+	// RoleName sets the name of the generated ClusterRole (and Roles) for rules
+	// that don't name their own role.
 	RoleName string
 
+	// ServiceAccount sets the name of a ServiceAccount to generate, along with
+	// a binding of each generated role to it.
+	//
+	// ClusterRoles get a ClusterRoleBinding, and Roles get a RoleBinding in
+	// their namespace.  Bindings are named after their roles, with a
+	// `-binding` suffix.
+	ServiceAccount string `marker:",optional"`
+
+	// ServiceAccountNamespace sets the namespace of the generated ServiceAccount.
+	// It must be set along with serviceAccount.
+	ServiceAccountNamespace string `marker:",optional"`
+
 	// HeaderFile specifies the header text (e.g. license) to prepend to generated files.
 	HeaderFile string `marker:",optional"`
 
@@ -157,6 +183,10 @@ type Generator struct {
 	Year string `marker:",optional"`
 }
 
+func (Generator) CacheScope() genall.CacheScope {
+	return genall.CachePerRun
+}
+
 func (Generator) RegisterMarkers(into *markers.Registry) error {
 	if err := into.Register(RuleDefinition); err != nil {
 		return err
@@ -165,79 +195,83 @@ func (Generator) RegisterMarkers(into *markers.Registry) error {
 	return nil
 }
 
+// normalizeRules merges Rules with the same ruleKey and sorts the Rules.
+func normalizeRules(rules []*Rule) []rbacv1.PolicyRule {
+	ruleMap := make(map[ruleKey]*Rule)
+	// all the Rules having the same ruleKey will be merged into the first Rule
+	for _, rule := range rules {
+		key := rule.key()
+		if _, ok := ruleMap[key]; !ok {
+			ruleMap[key] = rule
+			continue
+		}
+		ruleMap[key].addVerbs(rule.Verbs)
+	}
+
+	// sort the Rules in rules according to their ruleKeys
+	keys := make([]ruleKey, 0, len(ruleMap))
+	for key := range ruleMap {
+		keys = append(keys, key)
+	}
+	sort.Sort(ruleKeys(keys))
+
+	var policyRules []rbacv1.PolicyRule
+	for _, key := range keys {
+		policyRules = append(policyRules, ruleMap[key].ToRule())
+	}
+	return policyRules
+}
+
 // GenerateRoles generate a slice of objs representing either a ClusterRole or a Role object
-// The order of the objs in the returned slice is stable and determined by their namespaces.
+// The order of the objs in the returned slice is stable and determined by their namespaces
+// and names.  Rules without a role belong to the role with the given name.
 func GenerateRoles(ctx *genall.GenerationContext, roleName string) ([]interface{}, error) {
-	rulesByNS := make(map[string][]*Rule)
+	rulesByRole := make(map[roleKey][]*Rule)
 	for _, root := range ctx.Roots {
 		markerSet, err := markers.PackageMarkers(ctx.Collector, root)
 		if err != nil {
 			root.AddError(err)
 		}
 
-		// group RBAC markers by namespace
+		// group RBAC markers by role and namespace
 		for _, markerValue := range markerSet[RuleDefinition.Name] {
 			rule := markerValue.(Rule)
-			namespace := rule.Namespace
-			if _, ok := rulesByNS[namespace]; !ok {
-				rules := make([]*Rule, 0)
-				rulesByNS[namespace] = rules
-			}
-			rulesByNS[namespace] = append(rulesByNS[namespace], &rule)
-		}
-	}
-
-	// NormalizeRules merge Rule with the same ruleKey and sort the Rules
-	NormalizeRules := func(rules []*Rule) []rbacv1.PolicyRule {
-		ruleMap := make(map[ruleKey]*Rule)
-		// all the Rules having the same ruleKey will be merged into the first Rule
-		for _, rule := range rules {
-			key := rule.key()
-			if _, ok := ruleMap[key]; !ok {
-				ruleMap[key] = rule
-				continue
+			role := roleKey{Name: rule.Role, Namespace: rule.Namespace}
+			if role.Name == "" {
+				role.Name = roleName
 			}
-			ruleMap[key].addVerbs(rule.Verbs)
-		}
-
-		// sort the Rules in rules according to their ruleKeys
-		keys := make([]ruleKey, 0, len(ruleMap))
-		for key := range ruleMap {
-			keys = append(keys, key)
-		}
-		sort.Sort(ruleKeys(keys))
-
-		var policyRules []rbacv1.PolicyRule
-		for _, key := range keys {
-			policyRules = append(policyRules, ruleMap[key].ToRule())
-
+			rulesByRole[role] = append(rulesByRole[role], &rule)
 		}
-		return policyRules
 	}
 
-	// collect all the namespaces and sort them
-	var namespaces []string
-	for ns := range rulesByNS {
-		namespaces = append(namespaces, ns)
+	// collect all the roles and sort them by namespace, then name
+	var roles []roleKey
+	for role := range rulesByRole {
+		roles = append(roles, role)
 	}
-	sort.Strings(namespaces)
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Namespace != roles[j].Namespace {
+			return roles[i].Namespace < roles[j].Namespace
+		}
+		return roles[i].Name < roles[j].Name
+	})
 
-	// process the items in rulesByNS by the order specified in `namespaces` to make sure that the Role order is stable
+	// process the items in rulesByRole by the order specified in `roles` to make sure that the Role order is stable
 	var objs []interface{}
-	for _, ns := range namespaces {
-		rules := rulesByNS[ns]
-		policyRules := NormalizeRules(rules)
+	for _, role := range roles {
+		rules := rulesByRole[role]
+		policyRules := normalizeRules(rules)
 		if len(policyRules) == 0 {
 			continue
 		}
-		if ns == "" {
+		if role.Namespace == "" {
 			objs = append(objs, rbacv1.ClusterRole{
 				TypeMeta: metav1.TypeMeta{
 					Kind:       "ClusterRole",
 					APIVersion: rbacv1.SchemeGroupVersion.String(),
 				},
 				ObjectMeta: metav1.ObjectMeta{
-					Name: roleName,
+					Name: role.Name,
 				},
 				Rules: policyRules,
 			})
@@ -248,8 +282,8 @@ func GenerateRoles(ctx *genall.GenerationContext, roleName string) ([]interface{
 					APIVersion: rbacv1.SchemeGroupVersion.String(),
 				},
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      roleName,
-					Namespace: ns,
+					Name:      role.Name,
+					Namespace: role.Namespace,
 				},
 				Rules: policyRules,
 			})
@@ -259,12 +293,80 @@ func GenerateRoles(ctx *genall.GenerationContext, roleName string) ([]interface{
 	return objs, nil
 }
 
+// GenerateBindings generates a ServiceAccount with the given name and namespace,
+// followed by a binding of each of the given roles (as returned by GenerateRoles)
+// to it.
+func GenerateBindings(roles []interface{}, serviceAccount, namespace string) []interface{} {
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      serviceAccount,
+		Namespace: namespace,
+	}}
+
+	objs := []interface{}{corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceAccount,
+			Namespace: namespace,
+		},
+	}}
+	for _, role := range roles {
+		switch role := role.(type) {
+		case rbacv1.ClusterRole:
+			objs = append(objs, rbacv1.ClusterRoleBinding{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "ClusterRoleBinding",
+					APIVersion: rbacv1.SchemeGroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: role.Name + "-binding",
+				},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "ClusterRole",
+					Name:     role.Name,
+				},
+				Subjects: subjects,
+			})
+		case rbacv1.Role:
+			objs = append(objs, rbacv1.RoleBinding{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "RoleBinding",
+					APIVersion: rbacv1.SchemeGroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      role.Name + "-binding",
+					Namespace: role.Namespace,
+				},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "Role",
+					Name:     role.Name,
+				},
+				Subjects: subjects,
+			})
+		}
+	}
+	return objs
+}
+
 func (g Generator) Generate(ctx *genall.GenerationContext) error {
 	objs, err := GenerateRoles(ctx, g.RoleName)
 	if err != nil {
 		return err
 	}
 
+	if g.ServiceAccount != "" {
+		if g.ServiceAccountNamespace == "" {
+			return fmt.Errorf("serviceAccountNamespace must be set along with serviceAccount")
+		}
+		// the service account is still worth having without any rules
+		objs = append(objs, GenerateBindings(objs, g.ServiceAccount, g.ServiceAccountNamespace)...)
+	}
+
 	if len(objs) == 0 {
 		return nil
 	}
@@ -10,7 +10,9 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/controller-tools/pkg/genall"
 	"sigs.k8s.io/controller-tools/pkg/loader"
@@ -53,7 +55,9 @@ var _ = Describe("ClusterRole generated by the RBAC Generator", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			By("parsing the desired YAML")
-			for i, expectedRoleBytes := range bytes.Split(expectedFile, []byte("\n---\n"))[1:] {
+			expectedRoles := bytes.Split(expectedFile, []byte("\n---\n"))[1:]
+			Expect(objs).To(HaveLen(len(expectedRoles)))
+			for i, expectedRoleBytes := range expectedRoles {
 				By(fmt.Sprintf("comparing the generated Role and expected Role (Pair %d)", i))
 				obj := objs[i]
 				switch obj := obj.(type) {
@@ -71,3 +75,38 @@ var _ = Describe("ClusterRole generated by the RBAC Generator", func() {
 		})
 	}
 })
+
+var _ = Describe("Bindings generated by the RBAC Generator", func() {
+	It("should bind each role to the service account", func() {
+		roles := []interface{}{
+			rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "manager-role"}},
+			rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "leader-election", Namespace: "system"}},
+		}
+		subjects := []rbacv1.Subject{{Kind: "ServiceAccount", Name: "controller-manager", Namespace: "system"}}
+
+		objs := rbac.GenerateBindings(roles, "controller-manager", "system")
+		Expect(objs).To(HaveLen(3))
+
+		By("generating the ServiceAccount")
+		Expect(objs[0]).To(Equal(corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "controller-manager", Namespace: "system"},
+		}))
+
+		By("binding the ClusterRole with a ClusterRoleBinding")
+		Expect(objs[1]).To(Equal(rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{Kind: "ClusterRoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "manager-role-binding"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "manager-role"},
+			Subjects:   subjects,
+		}))
+
+		By("binding the Role with a RoleBinding in its namespace")
+		Expect(objs[2]).To(Equal(rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "leader-election-binding", Namespace: "system"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: "leader-election"},
+			Subjects:   subjects,
+		}))
+	})
+})
@@ -11,3 +11,7 @@ package controller
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=watch;watch
 // +kubebuilder:rbac:groups=art,resources=jobs,verbs=get,namespace=park
 // +kubebuilder:rbac:groups=batch.io,resources=cronjobs,resourceNames=foo;bar;baz,verbs=get;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;update,role=leader-election,namespace=system
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update,role=leader-election,namespace=system
+// +kubebuilder:rbac:urls=/metrics,verbs=get,role=metrics-reader
+// +kubebuilder:rbac:groups=art,resources=jobs,verbs=list,role=metrics-reader
@@ -0,0 +1,78 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	batchv1 "testdata.kubebuilder.io/rbacinfer/api/v1"
+)
+
+// +kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=*
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=list,role=other,namespace=system
+// +kubebuilder:rbac:groups=batch.example.com,resources=frobs,verbs=deletecollection,namespace=system
+
+type CronJobReconciler struct {
+	client.Client
+}
+
+func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cronJob batchv1.CronJob
+	if err := r.Get(ctx, req.NamespacedName, &cronJob); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Create(ctx, &appsv1.Deployment{}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.DeleteAllOf(ctx, &batchv1.Frobnicator{}, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// the kinds of these can't be known
+	var obj client.Object = &cronJob
+	if err := r.Update(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Update(ctx, &unstructured.Unstructured{}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, r.Status().Update(ctx, &cronJob)
+}
+
+func (r *CronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&batchv1.CronJob{}).
+		Owns(&appsv1.Deployment{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForObject{}).
+		Complete(r)
+}
@@ -0,0 +1,9 @@
+module testdata.kubebuilder.io/rbacinfer
+
+go 1.15
+
+require (
+	k8s.io/api v0.19.2
+	k8s.io/apimachinery v0.19.2
+	sigs.k8s.io/controller-runtime v0.7.0
+)
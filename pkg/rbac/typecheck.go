@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// bodyChecker type-checks packages including their function bodies, which
+// the loader skips (generators usually only care about declarations).
+//
+// The packages they import are checked (declarations only) into the
+// checker's own types.Packages, rather than the loader's, so that this
+// doesn't interfere with the partial type-checking of other generators.
+type bodyChecker struct {
+	fset *token.FileSet
+	// imported are the packages checked so far, for importing.
+	imported map[*loader.Package]*types.Package
+}
+
+func newBodyChecker(fset *token.FileSet) *bodyChecker {
+	return &bodyChecker{
+		fset:     fset,
+		imported: make(map[*loader.Package]*types.Package),
+	}
+}
+
+// check type-checks the given package, function bodies and all.
+func (c *bodyChecker) check(pkg *loader.Package) *types.Info {
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	c.typeCheck(pkg, info, false)
+	return info
+}
+
+// importPackage returns the types of the given package, checking its
+// declarations if it hasn't been already.
+func (c *bodyChecker) importPackage(pkg *loader.Package) *types.Package {
+	if typesPkg, checked := c.imported[pkg]; checked {
+		return typesPkg
+	}
+	typesPkg := c.typeCheck(pkg, nil, true)
+	c.imported[pkg] = typesPkg
+	return typesPkg
+}
+
+func (c *bodyChecker) typeCheck(pkg *loader.Package, info *types.Info, ignoreFuncBodies bool) *types.Package {
+	pkg.NeedSyntax()
+	config := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			// The imports map is keyed by import path.
+			importedPkg := pkg.Imports()[path]
+			if importedPkg == nil {
+				return nil, fmt.Errorf("package %q not found", path)
+			}
+			return c.importPackage(importedPkg), nil
+		}),
+		IgnoreFuncBodies: ignoreFuncBodies,
+
+		// the loader reports any real problems with the package, and we
+		// make do with whatever types we can figure out
+		Error: func(error) {},
+	}
+	typesPkg, _ := config.Check(pkg.PkgPath, c.fset, pkg.Syntax, info)
+	return typesPkg
+}
+
+// importerFunc is a types.Importer implemented as a function.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
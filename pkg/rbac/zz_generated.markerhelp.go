@@ -29,12 +29,48 @@ func (Generator) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "",
 		DetailedHelp: markers.DetailedHelp{
-			Summary: "generates ClusterRole objects.",
+			Summary: "generates ClusterRole objects (and optionally a ServiceAccount bound to them).",
 			Details: "",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{
 			"RoleName": {
-				Summary: "sets the name of the generated ClusterRole.",
+				Summary: "sets the name of the generated ClusterRole (and Roles) for rules that don't name their own role.",
+				Details: "",
+			},
+			"ServiceAccount": {
+				Summary: "sets the name of a ServiceAccount to generate, along with a binding of each generated role to it. ",
+				Details: "ClusterRoles get a ClusterRoleBinding, and Roles get a RoleBinding in their namespace.  Bindings are named after their roles, with a `-binding` suffix.",
+			},
+			"ServiceAccountNamespace": {
+				Summary: "sets the namespace of the generated ServiceAccount. It must be set along with serviceAccount.",
+				Details: "",
+			},
+			"HeaderFile": {
+				Summary: "specifies the header text (e.g. license) to prepend to generated files.",
+				Details: "",
+			},
+			"Year": {
+				Summary: "specifies the year to substitute for \" YEAR\" in the header file.",
+				Details: "",
+			},
+		},
+	}
+}
+
+func (InferGenerator) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "checks that RBAC markers allow what controllers do with controller-runtime clients. ",
+			Details: "Calls to client methods (Get, List, Watch, Create, Update, Patch, Delete and DeleteAllOf, along with those on Status() and SubResource(...)), and watches set up with the controller builder or source.Kind, are matched up with the group and resource of the objects they're passed.  Any rule that no `+kubebuilder:rbac` marker allows is reported at the call that needs it. Reads served from the manager's cache and watches list and watch objects across all namespaces, so only cluster-wide markers (those without a namespace) allow them; markers for any role or namespace allow other calls. \n Only objects whose types are known statically are checked: objects passed as interfaces (like client.Object) or unstructured objects are skipped.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Emit": {
+				Summary: "writes the inferred rules as a ClusterRole, to inferred_role.yaml, rather than checking that markers allow them.",
+				Details: "",
+			},
+			"RoleName": {
+				Summary: "sets the name of the ClusterRole written by emit. It must be set along with emit.",
 				Details: "",
 			},
 			"HeaderFile": {
@@ -81,6 +117,10 @@ func (Rule) Help() *markers.DefinitionHelp {
 				Summary: "specifies the scope of the Rule. If not set, the Rule belongs to the generated ClusterRole. If set, the Rule belongs to a Role, whose namespace is specified by this field.",
 				Details: "",
 			},
+			"Role": {
+				Summary: "specifies the name of the ClusterRole (or Role) the Rule belongs to. If not set, the Rule belongs to the role named by the generator's roleName. ",
+				Details: "Rules with different roles (or namespaces) end up in different objects, so separate components can each get only the permissions they need.",
+			},
 		},
 	}
 }
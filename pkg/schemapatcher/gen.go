@@ -73,6 +73,24 @@ type Generator struct {
 	// closest sentence boundary if it exceeds n characters.
 	MaxDescLen *int `marker:",optional"`
 
+	// DescriptionSeparator cuts descriptions off at the first line of Go
+	// documentation consisting only of this string (quote it on the command
+	// line, e.g. descriptionSeparator="---"), so that implementation notes can
+	// follow it without ending up in the schema.
+	DescriptionSeparator string `marker:",optional"`
+
+	// StripDescriptionTODOs removes lines starting with TODO or FIXME from descriptions.
+	//
+	// Left unspecified, the default is false.
+	StripDescriptionTODOs *bool `marker:",optional"`
+
+	// RewriteDescriptionLinks rewrites Go doc links in descriptions into plain
+	// text: `[pkg.Foo]` becomes `pkg.Foo`, and `[text]` with a matching
+	// `[text]: URL` definition becomes `text (URL)`.
+	//
+	// Left unspecified, the default is false.
+	RewriteDescriptionLinks *bool `marker:",optional"`
+
 	// GenerateEmbeddedObjectMeta specifies if any embedded ObjectMeta in the CRD should be generated
 	GenerateEmbeddedObjectMeta *bool `marker:",optional"`
 }
@@ -88,21 +106,19 @@ func (Generator) RegisterMarkers(into *markers.Registry) error {
 }
 
 func (g Generator) Generate(ctx *genall.GenerationContext) (result error) {
-	parser := &crdgen.Parser{
-		Collector: ctx.Collector,
-		Checker:   ctx.Checker,
+	shared := crdgen.SharedParserFor(ctx, crdgen.ParserSettings{
 		// Indicates the parser on whether to register the ObjectMeta type or not
 		GenerateEmbeddedObjectMeta: g.GenerateEmbeddedObjectMeta != nil && *g.GenerateEmbeddedObjectMeta == true,
-	}
-
-	crdgen.AddKnownTypes(parser)
-	for _, root := range ctx.Roots {
-		parser.NeedPackage(root)
-	}
-
-	metav1Pkg := crdgen.FindMetav1(ctx.Roots)
-	if metav1Pkg == nil {
-		// no objects in the roots, since nothing imported metav1
+		DescriptionRules: crdgen.DescriptionRules{
+			Separator:    g.DescriptionSeparator,
+			StripTODOs:   g.StripDescriptionTODOs != nil && *g.StripDescriptionTODOs == true,
+			RewriteLinks: g.RewriteDescriptionLinks != nil && *g.RewriteDescriptionLinks == true,
+		},
+	})
+	parser := shared.Parser
+
+	if len(shared.KubeKinds) == 0 {
+		// no objects in the roots (or nothing imported metav1)
 		return nil
 	}
 
@@ -113,7 +129,9 @@ func (g Generator) Generate(ctx *genall.GenerationContext) (result error) {
 	}
 
 	// generate schemata for the types we care about, and save them to be written later.
-	for _, groupKind := range crdgen.FindKubeKinds(parser, metav1Pkg) {
+	// The parser is only locked while generating them, since they're copied out.
+	shared.Lock()
+	for _, groupKind := range shared.KubeKinds {
 		existingSet, wanted := partialCRDSets[groupKind]
 		if !wanted {
 			continue
@@ -131,8 +149,8 @@ func (g Generator) Generate(ctx *genall.GenerationContext) (result error) {
 			parser.NeedFlattenedSchemaFor(typeIdent)
 
 			fullSchema := parser.FlattenedSchemata[typeIdent]
+			fullSchema = *fullSchema.DeepCopy() // don't mutate the (shared) cache
 			if g.MaxDescLen != nil {
-				fullSchema = *fullSchema.DeepCopy()
 				crdgen.TruncateDescription(&fullSchema, *g.MaxDescLen)
 			}
 
@@ -144,6 +162,7 @@ func (g Generator) Generate(ctx *genall.GenerationContext) (result error) {
 			existingSet.NewSchemata[gv.Version] = fullSchema
 		}
 	}
+	shared.Unlock()
 
 	// patch existing CRDs with new schemata
 	for _, existingSet := range partialCRDSets {
@@ -41,6 +41,18 @@ func (Generator) Help() *markers.DefinitionHelp {
 				Summary: "specifies the maximum description length for fields in CRD's OpenAPI schema. ",
 				Details: "0 indicates drop the description for all fields completely. n indicates limit the description to at most n characters and truncate the description to closest sentence boundary if it exceeds n characters.",
 			},
+			"DescriptionSeparator": {
+				Summary: "cuts descriptions off at the first line of Go documentation consisting only of this string (quote it on the command line, e.g. descriptionSeparator=\"---\"), so that implementation notes can follow it without ending up in the schema.",
+				Details: "",
+			},
+			"StripDescriptionTODOs": {
+				Summary: "removes lines starting with TODO or FIXME from descriptions. ",
+				Details: "Left unspecified, the default is false.",
+			},
+			"RewriteDescriptionLinks": {
+				Summary: "rewrites Go doc links in descriptions into plain text: `[pkg.Foo]` becomes `pkg.Foo`, and `[text]` with a matching `[text]: URL` definition becomes `text (URL)`. ",
+				Details: "Left unspecified, the default is false.",
+			},
 			"GenerateEmbeddedObjectMeta": {
 				Summary: "specifies if any embedded ObjectMeta in the CRD should be generated",
 				Details: "",
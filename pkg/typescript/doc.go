@@ -0,0 +1,29 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package typescript contains a generator for TypeScript type definitions
+// describing the Kubernetes kinds in a set of API packages.
+//
+// The generated definitions are derived from the same OpenAPI schemata that
+// the crd package produces (see crd.Parser.Schemata), so optionality, enums,
+// int-or-string values and maps follow the same rules as the corresponding
+// CustomResourceDefinitions.
+//
+// One module is written per group-version, named <group>_<version>.ts.
+// Types declared in another generated group-version are imported from that
+// module, while types from any other package (such as metav1.ObjectMeta) are
+// declared locally in each module that needs them.
+package typescript
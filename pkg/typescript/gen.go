@@ -0,0 +1,351 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package typescript
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// +controllertools:marker:generateHelp
+
+// Generator generates TypeScript type definitions for API kinds.
+//
+// One module is written per group-version, containing an interface for each
+// kind along with every type reachable from it (spec, status, and so on).
+type Generator struct {
+	// IgnoreUnexportedFields indicates that we should skip unexported fields.
+	//
+	// Left unspecified, the default is false.
+	IgnoreUnexportedFields *bool `marker:",optional"`
+
+	// AllowDangerousTypes allows types which are usually omitted from CRD generation
+	// because they are not recommended.
+	//
+	// Floats are emitted as number when this is true.
+	//
+	// Left unspecified, the default is false
+	AllowDangerousTypes *bool `marker:",optional"`
+
+	// HeaderFile specifies the header text (e.g. license) to prepend to generated files.
+	HeaderFile string `marker:",optional"`
+
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
+}
+
+var _ genall.Generator = &Generator{}
+
+func (Generator) CheckFilter() loader.NodeFilter {
+	return crd.Generator{}.CheckFilter()
+}
+
+func (Generator) CacheScope() genall.CacheScope {
+	return genall.CachePerRun
+}
+
+func (Generator) RegisterMarkers(into *markers.Registry) error {
+	return crdmarkers.Register(into)
+}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	parser := &crd.Parser{
+		Collector: ctx.Collector,
+		Checker:   ctx.Checker,
+		// Perform defaulting here to avoid ambiguity later
+		IgnoreUnexportedFields: g.IgnoreUnexportedFields != nil && *g.IgnoreUnexportedFields == true,
+		AllowDangerousTypes:    g.AllowDangerousTypes != nil && *g.AllowDangerousTypes == true,
+		// always describe the well-known ObjectMeta fields (name, labels, etc),
+		// since that's what consumers will actually want to look at
+		GenerateEmbeddedObjectMeta: true,
+	}
+
+	crd.AddKnownTypes(parser)
+	for _, root := range ctx.Roots {
+		parser.NeedPackage(root)
+	}
+
+	metav1Pkg := crd.FindMetav1(ctx.Roots)
+	if metav1Pkg == nil {
+		// no objects in the roots, since nothing imported metav1
+		return nil
+	}
+
+	kubeKinds := crd.FindKubeKinds(parser, metav1Pkg)
+	if len(kubeKinds) == 0 {
+		// no objects in the roots
+		return nil
+	}
+
+	var headerText string
+	if g.HeaderFile != "" {
+		headerBytes, err := ctx.ReadFile(g.HeaderFile)
+		if err != nil {
+			return err
+		}
+		headerText = string(headerBytes)
+	}
+	headerText = strings.ReplaceAll(headerText, " YEAR", " "+g.Year)
+
+	gen := &moduleSet{
+		parser:  parser,
+		modules: make(map[schema.GroupVersion]*module),
+		deps:    make(map[crd.TypeIdent][]crd.TypeIdent),
+	}
+	for _, root := range ctx.Roots {
+		if gv, hasGV := parser.GroupVersions[root]; hasGV {
+			gen.ownedPackages = append(gen.ownedPackages, root)
+			gen.moduleFor(gv)
+		}
+	}
+
+	for _, groupKind := range kubeKinds {
+		for _, pkg := range gen.ownedPackages {
+			gv := parser.GroupVersions[pkg]
+			if gv.Group != groupKind.Group {
+				continue
+			}
+			ident := crd.TypeIdent{Package: pkg, Name: groupKind.Kind}
+			if parser.Types[ident] == nil {
+				continue
+			}
+			gen.modules[gv].kinds[ident] = struct{}{}
+			gen.need(ident)
+		}
+	}
+
+	for _, mod := range gen.sortedModules() {
+		if len(mod.own) == 0 {
+			continue
+		}
+		gen.resolveNames(mod)
+		if err := gen.writeModule(ctx, mod, headerText); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moduleSet tracks all the modules being generated in a single run, along
+// with the types each of them declares.
+type moduleSet struct {
+	parser *crd.Parser
+
+	// ownedPackages are the root packages that have a group-version,
+	// and thus get a module of their own.
+	ownedPackages []*loader.Package
+	// modules are the modules being generated, by group-version.
+	modules map[schema.GroupVersion]*module
+	// deps contains the types directly referenced by each known type.
+	deps map[crd.TypeIdent][]crd.TypeIdent
+}
+
+// module is a single generated TypeScript module, covering one group-version.
+type module struct {
+	gv schema.GroupVersion
+
+	// kinds are the kinds declared in this module.
+	kinds map[crd.TypeIdent]struct{}
+	// own are the types declared in this module that come from this
+	// module's group-version.
+	own map[crd.TypeIdent]struct{}
+
+	// names maps every type used in this module to the name it's referred
+	// to by, locally.
+	names map[crd.TypeIdent]string
+	// usedNames tracks the names in names, to avoid collisions.
+	usedNames map[string]struct{}
+	// imports are the types imported from other modules.
+	imports []crd.TypeIdent
+	// decls are all the types declared in this module, in order.
+	decls []crd.TypeIdent
+}
+
+// fileName returns the name of the file this module is written to.
+func (m *module) fileName() string {
+	return m.importPath()[len("./"):] + ".ts"
+}
+
+// importPath returns the path used by other modules to import this one.
+func (m *module) importPath() string {
+	if m.gv.Group == "" {
+		return "./" + m.gv.Version
+	}
+	return fmt.Sprintf("./%s_%s", m.gv.Group, m.gv.Version)
+}
+
+// moduleFor returns the module for the given group-version, creating it if needed.
+func (s *moduleSet) moduleFor(gv schema.GroupVersion) *module {
+	if mod, exists := s.modules[gv]; exists {
+		return mod
+	}
+	mod := &module{
+		gv:        gv,
+		kinds:     make(map[crd.TypeIdent]struct{}),
+		own:       make(map[crd.TypeIdent]struct{}),
+		names:     make(map[crd.TypeIdent]string),
+		usedNames: make(map[string]struct{}),
+	}
+	s.modules[gv] = mod
+	return mod
+}
+
+// owner returns the module that declares types from the given package,
+// or nil if types from that package are declared locally wherever they're used.
+func (s *moduleSet) owner(pkg *loader.Package) *module {
+	for _, owned := range s.ownedPackages {
+		if owned == pkg {
+			return s.modules[s.parser.GroupVersions[pkg]]
+		}
+	}
+	return nil
+}
+
+// need indicates that the given type (and everything it references) needs
+// to be declared somewhere.
+func (s *moduleSet) need(ident crd.TypeIdent) {
+	if _, known := s.deps[ident]; known {
+		return
+	}
+	if mod := s.owner(ident.Package); mod != nil {
+		mod.own[ident] = struct{}{}
+	}
+
+	s.parser.NeedSchemaFor(ident)
+	typeSchema := s.parser.Schemata[ident]
+	// mark ourselves as seen before recursing, to deal with recursive types
+	s.deps[ident] = nil
+	refs := schemaRefs(&typeSchema, ident.Package)
+	s.deps[ident] = refs
+	for _, ref := range refs {
+		s.need(ref)
+	}
+}
+
+// sortedModules returns all modules, sorted by file name.
+func (s *moduleSet) sortedModules() []*module {
+	mods := make([]*module, 0, len(s.modules))
+	for _, mod := range s.modules {
+		mods = append(mods, mod)
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		return mods[i].fileName() < mods[j].fileName()
+	})
+	return mods
+}
+
+// resolveNames figures out which types the given module declares and
+// imports, and what they're called.  Types from the module's own
+// group-version get to keep their names, while imported and locally
+// declared types get prefixed with their package name on collision.
+func (s *moduleSet) resolveNames(mod *module) {
+	own := sortedIdents(mod.own)
+	var imported []crd.TypeIdent
+	var local []crd.TypeIdent
+	seen := make(map[crd.TypeIdent]struct{})
+	for _, ident := range own {
+		seen[ident] = struct{}{}
+	}
+
+	// walk out from our own types, stopping at anything another module declares
+	queue := append([]crd.TypeIdent(nil), own...)
+	for len(queue) > 0 {
+		ident := queue[0]
+		queue = queue[1:]
+		_, isKind := mod.kinds[ident]
+		for _, dep := range s.deps[ident] {
+			if _, isSeen := seen[dep]; isSeen {
+				continue
+			}
+			if isKind && isTypeMeta(dep) {
+				// kinds get literal apiVersion and kind fields instead
+				continue
+			}
+			seen[dep] = struct{}{}
+			if owner := s.owner(dep.Package); owner != nil && owner != mod {
+				imported = append(imported, dep)
+				continue
+			}
+			local = append(local, dep)
+			queue = append(queue, dep)
+		}
+	}
+
+	// sort everything for determinism before naming things
+	sortIdentList(imported)
+	sortIdentList(local)
+
+	for _, ident := range own {
+		mod.nameType(ident)
+	}
+	for _, ident := range imported {
+		mod.nameType(ident)
+	}
+	for _, ident := range local {
+		mod.nameType(ident)
+	}
+
+	mod.imports = imported
+	mod.decls = append(own, local...)
+}
+
+// nameType allocates a unique local name for the given type.
+func (m *module) nameType(ident crd.TypeIdent) {
+	name := ident.Name
+	if _, taken := m.usedNames[name]; taken {
+		name = strings.ToUpper(ident.Package.Name[:1]) + ident.Package.Name[1:] + ident.Name
+	}
+	baseName := name
+	for i := 2; ; i++ {
+		if _, taken := m.usedNames[name]; !taken {
+			break
+		}
+		name = fmt.Sprintf("%s%d", baseName, i)
+	}
+	m.usedNames[name] = struct{}{}
+	m.names[ident] = name
+}
+
+// sortedIdents returns the given set of types, sorted.
+func sortedIdents(set map[crd.TypeIdent]struct{}) []crd.TypeIdent {
+	res := make([]crd.TypeIdent, 0, len(set))
+	for ident := range set {
+		res = append(res, ident)
+	}
+	sortIdentList(res)
+	return res
+}
+
+// sortIdentList sorts the given types by package path, then name.
+func sortIdentList(idents []crd.TypeIdent) {
+	sort.Slice(idents, func(i, j int) bool {
+		if idents[i].Package.PkgPath != idents[j].Package.PkgPath {
+			return idents[i].Package.PkgPath < idents[j].Package.PkgPath
+		}
+		return idents[i].Name < idents[j].Name
+	})
+}
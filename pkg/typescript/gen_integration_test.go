@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package typescript_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/typescript"
+)
+
+var _ = Describe("TypeScript Generation", func() {
+	It("should generate one module per group-version", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(2))
+
+		By("setting up the context")
+		gen := typescript.Generator{}
+		reg := &markers.Registry{}
+		Expect(gen.RegisterMarkers(reg)).To(Succeed())
+		out := &outputRule{files: make(map[string]*bytes.Buffer)}
+		ctx := &genall.GenerationContext{
+			Collector:  &markers.Collector{Registry: reg},
+			Roots:      pkgs,
+			Checker:    &loader.TypeChecker{NodeFilters: []loader.NodeFilter{gen.CheckFilter()}},
+			OutputRule: out,
+		}
+
+		By("calling Generate")
+		Expect(gen.Generate(ctx)).To(Succeed())
+		// type errors from partially-checked dependencies are fine, just like in controller-gen
+		Expect(loader.PrintErrors(pkgs, packages.TypeError)).To(BeFalse(), "packages should have no errors")
+		Expect(out.files).To(HaveLen(2))
+
+		for _, fileName := range []string{"ui.example.com_v1.ts", "tools.example.com_v2.ts"} {
+			By("comparing " + fileName)
+			Expect(out.files).To(HaveKey(fileName))
+			expected, err := ioutil.ReadFile(fileName)
+			Expect(err).NotTo(HaveOccurred())
+			actual := out.files[fileName].String()
+			Expect(actual).To(Equal(string(expected)), cmp.Diff(actual, string(expected)))
+		}
+	})
+})
+
+type outputRule struct {
+	files map[string]*bytes.Buffer
+}
+
+func (o *outputRule) Open(_ *loader.Package, itemPath string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	o.files[itemPath] = buf
+	return nopCloser{buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (n nopCloser) Close() error {
+	return nil
+}
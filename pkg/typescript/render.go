@@ -0,0 +1,373 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package typescript
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+const metav1Path = "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// plainPropName matches property names that don't need quoting in TypeScript.
+var plainPropName = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// identFromRef converts the given schema ref from the given package back
+// into the TypeIdent that it represents.
+func identFromRef(ref string, contextPkg *loader.Package) (crd.TypeIdent, error) {
+	typ, pkgName, err := crd.RefParts(ref)
+	if err != nil {
+		return crd.TypeIdent{}, err
+	}
+	if pkgName == "" {
+		// a local reference
+		return crd.TypeIdent{Name: typ, Package: contextPkg}, nil
+	}
+	pkg := contextPkg.Imports()[pkgName]
+	if pkg == nil {
+		return crd.TypeIdent{}, fmt.Errorf("reference to type %q in unimported package %q", typ, pkgName)
+	}
+	return crd.TypeIdent{Name: typ, Package: pkg}, nil
+}
+
+// refCollector collects the types referenced in a schema.
+type refCollector struct {
+	pkg  *loader.Package
+	seen map[crd.TypeIdent]struct{}
+	refs []crd.TypeIdent
+}
+
+func (c *refCollector) Visit(schema *apiext.JSONSchemaProps) crd.SchemaVisitor {
+	if schema == nil || schema.Ref == nil {
+		return c
+	}
+	ident, err := identFromRef(*schema.Ref, c.pkg)
+	if err != nil {
+		c.pkg.AddError(err)
+		return nil
+	}
+	if _, seen := c.seen[ident]; !seen {
+		c.seen[ident] = struct{}{}
+		c.refs = append(c.refs, ident)
+	}
+	return nil
+}
+
+// schemaRefs returns the types directly referenced by the given (unflattened) schema.
+func schemaRefs(schema *apiext.JSONSchemaProps, pkg *loader.Package) []crd.TypeIdent {
+	col := &refCollector{pkg: pkg, seen: make(map[crd.TypeIdent]struct{})}
+	crd.EditSchema(schema.DeepCopy(), col)
+	return col.refs
+}
+
+// writeModule renders the given module, and writes it out.
+func (s *moduleSet) writeModule(ctx *genall.GenerationContext, mod *module, headerText string) error {
+	out := &bytes.Buffer{}
+	if headerText != "" {
+		out.WriteString(headerText)
+		if !strings.HasSuffix(headerText, "\n") {
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("// Code generated by controller-gen. DO NOT EDIT.\n")
+
+	s.writeImports(out, mod)
+
+	for _, ident := range mod.decls {
+		out.WriteString("\n")
+		s.writeDecl(out, mod, ident)
+	}
+
+	outWriter, err := ctx.Open(nil, mod.fileName())
+	if err != nil {
+		return err
+	}
+	defer outWriter.Close()
+
+	n, err := outWriter.Write(out.Bytes())
+	if err != nil {
+		return err
+	}
+	if n < out.Len() {
+		return fmt.Errorf("failed to write TypeScript module %s: short write", mod.fileName())
+	}
+	return nil
+}
+
+// writeImports writes the import statements for types from other modules.
+func (s *moduleSet) writeImports(out *bytes.Buffer, mod *module) {
+	byPath := make(map[string][]string)
+	for _, ident := range mod.imports {
+		owner := s.owner(ident.Package)
+		spec := ident.Name
+		if localName := mod.names[ident]; localName != ident.Name {
+			spec = fmt.Sprintf("%s as %s", ident.Name, localName)
+		}
+		byPath[owner.importPath()] = append(byPath[owner.importPath()], spec)
+	}
+	if len(byPath) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	out.WriteString("\n")
+	for _, path := range paths {
+		specs := byPath[path]
+		sort.Strings(specs)
+		fmt.Fprintf(out, "import { %s } from %q;\n", strings.Join(specs, ", "), path)
+	}
+}
+
+// writeDecl writes the declaration for the given type, as an interface if
+// it's a struct, and as a type alias otherwise.
+func (s *moduleSet) writeDecl(out *bytes.Buffer, mod *module, ident crd.TypeIdent) {
+	typeSchema := s.parser.Schemata[ident]
+	name := mod.names[ident]
+	_, isKind := mod.kinds[ident]
+
+	writeDoc(out, "", typeSchema.Description)
+	if !isInterface(&typeSchema) {
+		fmt.Fprintf(out, "export type %s = %s;\n", name, mod.typeOf(&typeSchema, ident.Package))
+		return
+	}
+
+	var extends []string
+	for i := range typeSchema.AllOf {
+		embedded, err := identFromRef(*typeSchema.AllOf[i].Ref, ident.Package)
+		if err != nil {
+			ident.Package.AddError(err)
+			continue
+		}
+		if isKind && isTypeMeta(embedded) {
+			// replaced by literal apiVersion and kind fields below
+			continue
+		}
+		extends = append(extends, mod.names[embedded])
+	}
+
+	fmt.Fprintf(out, "export interface %s ", name)
+	if len(extends) > 0 {
+		fmt.Fprintf(out, "extends %s ", strings.Join(extends, ", "))
+	}
+	out.WriteString("{\n")
+
+	if isKind {
+		writeDoc(out, "  ", "APIVersion defines the versioned schema of this representation of an object.")
+		fmt.Fprintf(out, "  apiVersion?: %q;\n", mod.gv.String())
+		writeDoc(out, "  ", "Kind is a string value representing the REST resource this object represents.")
+		fmt.Fprintf(out, "  kind?: %q;\n", ident.Name)
+	}
+
+	required := make(map[string]struct{}, len(typeSchema.Required))
+	for _, propName := range typeSchema.Required {
+		required[propName] = struct{}{}
+	}
+	propNames := make([]string, 0, len(typeSchema.Properties))
+	for propName := range typeSchema.Properties {
+		if isKind && (propName == "apiVersion" || propName == "kind") {
+			continue
+		}
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+
+	for _, propName := range propNames {
+		prop := typeSchema.Properties[propName]
+		writeDoc(out, "  ", prop.Description)
+		optional := "?"
+		if _, isRequired := required[propName]; isRequired {
+			optional = ""
+		}
+		fmt.Fprintf(out, "  %s%s: %s;\n", quotePropName(propName), optional, mod.typeOf(&prop, ident.Package))
+	}
+	out.WriteString("}\n")
+}
+
+// isInterface checks if the given type schema describes a struct that can be
+// written as an interface (that is, an object with known properties whose
+// embedded fields are all references to other types).
+func isInterface(schema *apiext.JSONSchemaProps) bool {
+	if schema.Type != "object" || schema.AdditionalProperties != nil || schema.Nullable {
+		return false
+	}
+	if schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields {
+		return false
+	}
+	for _, embedded := range schema.AllOf {
+		if embedded.Ref == nil || embedded.Nullable {
+			return false
+		}
+	}
+	return true
+}
+
+// isTypeMeta checks if the given type is metav1.TypeMeta.
+func isTypeMeta(ident crd.TypeIdent) bool {
+	return ident.Name == "TypeMeta" && loader.NonVendorPath(ident.Package.PkgPath) == metav1Path
+}
+
+// typeOf returns the TypeScript type expression for the given schema,
+// interpreting any references relative to the given package.
+func (m *module) typeOf(schema *apiext.JSONSchemaProps, pkg *loader.Package) string {
+	typ := m.baseTypeOf(schema, pkg)
+	if schema.Nullable {
+		return typ + " | null"
+	}
+	return typ
+}
+
+func (m *module) baseTypeOf(schema *apiext.JSONSchemaProps, pkg *loader.Package) string {
+	switch {
+	case schema.XIntOrString:
+		return "number | string"
+	case len(schema.Enum) > 0:
+		// enum values are already JSON literals, which are valid TypeScript literal types
+		literals := make([]string, len(schema.Enum))
+		for i, val := range schema.Enum {
+			literals[i] = string(val.Raw)
+		}
+		return strings.Join(literals, " | ")
+	case schema.Ref != nil:
+		ident, err := identFromRef(*schema.Ref, pkg)
+		if err != nil {
+			pkg.AddError(err)
+			return "unknown"
+		}
+		return m.names[ident]
+	case len(schema.AnyOf) > 0:
+		members := make([]string, len(schema.AnyOf))
+		for i := range schema.AnyOf {
+			members[i] = parenthesize(m.typeOf(&schema.AnyOf[i], pkg))
+		}
+		return strings.Join(members, " | ")
+	case len(schema.AllOf) > 0:
+		var members []string
+		rest := *schema
+		rest.AllOf = nil
+		rest.Nullable = false
+		if rest.Type != "" {
+			members = append(members, parenthesize(m.typeOf(&rest, pkg)))
+		}
+		for i := range schema.AllOf {
+			members = append(members, parenthesize(m.typeOf(&schema.AllOf[i], pkg)))
+		}
+		return strings.Join(members, " & ")
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		if schema.Items == nil || schema.Items.Schema == nil {
+			return "unknown[]"
+		}
+		return parenthesize(m.typeOf(schema.Items.Schema, pkg)) + "[]"
+	case "object":
+		if len(schema.Properties) > 0 {
+			return m.inlineObjectOf(schema, pkg)
+		}
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			return fmt.Sprintf("{ [key: string]: %s }", m.typeOf(schema.AdditionalProperties.Schema, pkg))
+		}
+		return "{ [key: string]: unknown }"
+	default:
+		// schemaless or preserve-unknown-fields
+		return "unknown"
+	}
+}
+
+// inlineObjectOf writes an object with properties as an inline object literal type.
+func (m *module) inlineObjectOf(schema *apiext.JSONSchemaProps, pkg *loader.Package) string {
+	required := make(map[string]struct{}, len(schema.Required))
+	for _, propName := range schema.Required {
+		required[propName] = struct{}{}
+	}
+	propNames := make([]string, 0, len(schema.Properties))
+	for propName := range schema.Properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+
+	props := make([]string, len(propNames))
+	for i, propName := range propNames {
+		prop := schema.Properties[propName]
+		optional := "?"
+		if _, isRequired := required[propName]; isRequired {
+			optional = ""
+		}
+		props[i] = fmt.Sprintf("%s%s: %s", quotePropName(propName), optional, m.typeOf(&prop, pkg))
+	}
+	return "{ " + strings.Join(props, "; ") + " }"
+}
+
+// parenthesize wraps union and intersection types in parentheses, so that
+// they can be safely combined with other type operators.
+func parenthesize(typ string) string {
+	if strings.Contains(typ, " | ") || strings.Contains(typ, " & ") {
+		return "(" + typ + ")"
+	}
+	return typ
+}
+
+// quotePropName quotes property names that aren't valid identifiers.
+func quotePropName(name string) string {
+	if plainPropName.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// writeDoc writes the given description as a JSDoc comment.
+func writeDoc(out *bytes.Buffer, indent, desc string) {
+	desc = strings.TrimSpace(desc)
+	if desc == "" {
+		return
+	}
+	// don't let the description terminate the comment early
+	desc = strings.ReplaceAll(desc, "*/", "*\\/")
+
+	fmt.Fprintf(out, "%s/**\n", indent)
+	for _, line := range strings.Split(desc, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			fmt.Fprintf(out, "%s *\n", indent)
+			continue
+		}
+		fmt.Fprintf(out, "%s * %s\n", indent, line)
+	}
+	fmt.Fprintf(out, "%s */\n", indent)
+}
@@ -0,0 +1,8 @@
+module testdata.kubebuilder.io/typescript
+
+go 1.15
+
+require (
+	k8s.io/api v0.19.2
+	k8s.io/apimachinery v0.19.2
+)
@@ -0,0 +1,54 @@
+// Code generated by controller-gen. DO NOT EDIT.
+
+import { WidgetReference } from "./ui.example.com_v1";
+
+/**
+ * Gadget is a gadget that uses widgets.
+ */
+export interface Gadget {
+  /**
+   * APIVersion defines the versioned schema of this representation of an object.
+   */
+  apiVersion?: "tools.example.com/v2";
+  /**
+   * Kind is a string value representing the REST resource this object represents.
+   */
+  kind?: "Gadget";
+  metadata?: ObjectMeta;
+  spec?: GadgetSpec;
+}
+
+/**
+ * GadgetSpec defines the desired state of a Gadget.
+ */
+export interface GadgetSpec {
+  /**
+   * At is our own time type.
+   */
+  at?: Time;
+  /**
+   * Since is a Kubernetes timestamp.
+   */
+  since?: V1Time;
+  /**
+   * Widget is a reference to a widget from another group-version.
+   */
+  widget: WidgetReference;
+}
+
+/**
+ * Time collides with metav1.Time.
+ */
+export interface Time {
+  hour: number;
+}
+
+export interface ObjectMeta {
+  annotations?: { [key: string]: string };
+  finalizers?: string[];
+  labels?: { [key: string]: string };
+  name?: string;
+  namespace?: string;
+}
+
+export type V1Time = string;
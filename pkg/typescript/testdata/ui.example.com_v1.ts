@@ -0,0 +1,112 @@
+// Code generated by controller-gen. DO NOT EDIT.
+
+/**
+ * Labels is a set of extra labels.
+ */
+export type Labels = { [key: string]: string };
+
+/**
+ * Phase is the lifecycle phase of a Widget.
+ */
+export type Phase = "Pending" | "Ready" | "Failed";
+
+/**
+ * Widget is a widget shown in the dashboard.
+ */
+export interface Widget {
+  /**
+   * APIVersion defines the versioned schema of this representation of an object.
+   */
+  apiVersion?: "ui.example.com/v1";
+  /**
+   * Kind is a string value representing the REST resource this object represents.
+   */
+  kind?: "Widget";
+  metadata?: ObjectMeta;
+  spec?: WidgetSpec;
+  status?: WidgetStatus;
+}
+
+/**
+ * WidgetReference refers to a Widget by name.
+ */
+export interface WidgetReference {
+  /**
+   * Name of the referent.
+   */
+  name: string;
+}
+
+/**
+ * WidgetSpec defines the desired state of a Widget.
+ */
+export interface WidgetSpec {
+  /**
+   * Weird names need quoting.
+   */
+  "dashed-name"?: string;
+  /**
+   * ExtraLabels are additional labels.
+   */
+  extraLabels?: Labels;
+  /**
+   * Memory is how much memory each widget gets.
+   */
+  memory?: Quantity;
+  /**
+   * Parent optionally refers to another widget.
+   */
+  parent?: WidgetReference | null;
+  /**
+   * Port may be a port number or a named port.
+   */
+  port?: IntOrString;
+  /**
+   * Replicas is the number of widgets to run.
+   */
+  replicas: number;
+  /**
+   * Size is the widget size.
+   */
+  size?: "small" | "medium" | "large";
+  /**
+   * Tags are free-form tags.
+   */
+  tags?: string[];
+  template?: WidgetTemplate;
+}
+
+/**
+ * WidgetStatus defines the observed state of a Widget.
+ */
+export interface WidgetStatus {
+  /**
+   * LastUpdated is when we last looked at this.
+   */
+  lastUpdated?: Time;
+  phase?: Phase;
+}
+
+/**
+ * WidgetTemplate is embedded to test extends.
+ */
+export interface WidgetTemplate extends WidgetReference {
+  /**
+   * Colors are the template colors, which contains a *\/ sequence.
+   */
+  colors?: { [key: string]: number[] };
+}
+
+export type Quantity = number | string;
+
+export interface ObjectMeta {
+  annotations?: { [key: string]: string };
+  finalizers?: string[];
+  labels?: { [key: string]: string };
+  name?: string;
+  namespace?: string;
+}
+
+export type Time = string;
+
+export type IntOrString = number | string;
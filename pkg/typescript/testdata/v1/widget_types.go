@@ -0,0 +1,101 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//go:generate ../../../../.run-controller-gen.sh typescript paths=../... output:dir=..
+
+// +groupName=ui.example.com
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Phase is the lifecycle phase of a Widget.
+// +kubebuilder:validation:Enum=Pending;Ready;Failed
+type Phase string
+
+// Labels is a set of extra labels.
+type Labels map[string]string
+
+// WidgetSpec defines the desired state of a Widget.
+type WidgetSpec struct {
+	// Replicas is the number of widgets to run.
+	Replicas int32 `json:"replicas"`
+
+	// Port may be a port number or a named port.
+	Port intstr.IntOrString `json:"port,omitempty"`
+
+	// Memory is how much memory each widget gets.
+	Memory resource.Quantity `json:"memory,omitempty"`
+
+	// Size is the widget size.
+	// +kubebuilder:validation:Enum=small;medium;large
+	Size string `json:"size,omitempty"`
+
+	// Tags are free-form tags.
+	Tags []string `json:"tags,omitempty"`
+
+	// ExtraLabels are additional labels.
+	ExtraLabels Labels `json:"extraLabels,omitempty"`
+
+	// Parent optionally refers to another widget.
+	// +nullable
+	Parent *WidgetReference `json:"parent,omitempty"`
+
+	// Weird names need quoting.
+	Dashed string `json:"dashed-name,omitempty"`
+
+	Template WidgetTemplate `json:"template,omitempty"`
+}
+
+// WidgetReference refers to a Widget by name.
+type WidgetReference struct {
+	// Name of the referent.
+	Name string `json:"name"`
+}
+
+// WidgetTemplate is embedded to test extends.
+type WidgetTemplate struct {
+	WidgetReference `json:",inline"`
+
+	// Colors are the template colors, which contains a */ sequence.
+	Colors map[string][]int32 `json:"colors,omitempty"`
+}
+
+// WidgetStatus defines the observed state of a Widget.
+type WidgetStatus struct {
+	Phase Phase `json:"phase,omitempty"`
+
+	// LastUpdated is when we last looked at this.
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// Widget is a widget shown in the dashboard.
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WidgetSpec   `json:"spec,omitempty"`
+	Status WidgetStatus `json:"status,omitempty"`
+}
+
+// WidgetList contains a list of Widget.
+type WidgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Widget `json:"items"`
+}
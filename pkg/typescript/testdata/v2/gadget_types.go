@@ -0,0 +1,49 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=tools.example.com
+// +versionName=v2
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "testdata.kubebuilder.io/typescript/v1"
+)
+
+// Time collides with metav1.Time.
+type Time struct {
+	Hour int32 `json:"hour"`
+}
+
+// GadgetSpec defines the desired state of a Gadget.
+type GadgetSpec struct {
+	// Widget is a reference to a widget from another group-version.
+	Widget v1.WidgetReference `json:"widget"`
+
+	// Since is a Kubernetes timestamp.
+	Since *metav1.Time `json:"since,omitempty"`
+
+	// At is our own time type.
+	At Time `json:"at,omitempty"`
+}
+
+// Gadget is a gadget that uses widgets.
+type Gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GadgetSpec `json:"spec,omitempty"`
+}
@@ -312,6 +312,10 @@ type Generator struct {
 	Year string `marker:",optional"`
 }
 
+func (Generator) CacheScope() genall.CacheScope {
+	return genall.CachePerRun
+}
+
 func (Generator) RegisterMarkers(into *markers.Registry) error {
 	if err := into.Register(ConfigDefinition); err != nil {
 		return err
@@ -52,6 +52,24 @@ type Generator struct {
 	// closest sentence boundary if it exceeds n characters.
 	MaxDescLen *int `marker:",optional"`
 
+	// DescriptionSeparator cuts descriptions off at the first line of Go
+	// documentation consisting only of this string (quote it on the command
+	// line, e.g. descriptionSeparator="---"), so that implementation notes can
+	// follow it without ending up in the schema.
+	DescriptionSeparator string `marker:",optional"`
+
+	// StripDescriptionTODOs removes lines starting with TODO or FIXME from descriptions.
+	//
+	// Left unspecified, the default is false.
+	StripDescriptionTODOs *bool `marker:",optional"`
+
+	// RewriteDescriptionLinks rewrites Go doc links in descriptions into plain
+	// text: `[pkg.Foo]` becomes `pkg.Foo`, and `[text]` with a matching
+	// `[text]: URL` definition becomes `text (URL)`.
+	//
+	// Left unspecified, the default is false.
+	RewriteDescriptionLinks *bool `marker:",optional"`
+
 	// XRDVersions specifies the target API versions of the CRD type itself to
 	// generate. Defaults to v1.
 	//
@@ -72,11 +90,31 @@ type Generator struct {
 
 	// Year specifies the year to substitute for " YEAR" in the header file.
 	Year string `marker:",optional"`
+
+	// FileNameTemplate specifies a Go template for the path of each generated file,
+	// relative to the output directory.  Since templates contain braces, quote the
+	// value on the command line, e.g. fileNameTemplate="{{.Group}}/{{.Plural}}.yaml".
+	//
+	// The template may refer to .Group, .Kind, .Plural, .Singular and .CRDVersion
+	// (the API version of the XRD itself).  Left unspecified, files are named
+	// "<group>_<plural>.yaml".
+	FileNameTemplate string `marker:",optional"`
+
+	// Kustomization indicates that a kustomization.yaml listing every generated
+	// file should be written as well.
+	//
+	// Left unspecified, the default is false.
+	Kustomization *bool `marker:",optional"`
 }
 
 func (Generator) CheckFilter() loader.NodeFilter {
 	return filterTypesForCRDs
 }
+
+func (Generator) CacheScope() genall.CacheScope {
+	return genall.CachePerRun
+}
+
 func (Generator) RegisterMarkers(into *markers.Registry) error {
 	err := crdmarkers.Register(into)
 	if err != nil {
@@ -92,36 +130,22 @@ func transformRemoveCRDStatus(obj map[string]interface{}) error {
 }
 
 func (g Generator) Generate(ctx *genall.GenerationContext) error {
-	parser := &Parser{
-		Parser: &crd.Parser{
-			Collector:                  ctx.Collector,
-			Checker:                    ctx.Checker,
-			IgnoreUnexportedFields:     true,
-			AllowDangerousTypes:        false,
-			GenerateEmbeddedObjectMeta: false,
+	shared := crd.SharedParserFor(ctx, crd.ParserSettings{
+		IgnoreUnexportedFields:     g.IgnoreUnexportedFields != nil && *g.IgnoreUnexportedFields == true,
+		AllowDangerousTypes:        g.AllowDangerousTypes != nil && *g.AllowDangerousTypes == true,
+		GenerateEmbeddedObjectMeta: g.GenerateEmbeddedObjectMeta != nil && *g.GenerateEmbeddedObjectMeta == true,
+		DescriptionRules: crd.DescriptionRules{
+			Separator:    g.DescriptionSeparator,
+			StripTODOs:   g.StripDescriptionTODOs != nil && *g.StripDescriptionTODOs == true,
+			RewriteLinks: g.RewriteDescriptionLinks != nil && *g.RewriteDescriptionLinks == true,
 		},
-	}
-
-	parser.Collector = ctx.Collector
-	parser.Checker = ctx.Checker
-	parser.IgnoreUnexportedFields = g.IgnoreUnexportedFields != nil && *g.IgnoreUnexportedFields == true
-	parser.AllowDangerousTypes = g.AllowDangerousTypes != nil && *g.AllowDangerousTypes == true
-	parser.GenerateEmbeddedObjectMeta = g.GenerateEmbeddedObjectMeta != nil && *g.GenerateEmbeddedObjectMeta == true
-	crd.AddKnownTypes(parser.Parser)
-	for _, root := range ctx.Roots {
-		parser.NeedPackage(root)
-	}
-
-	metav1Pkg := crd.FindMetav1(ctx.Roots)
-	if metav1Pkg == nil {
-		// no objects in the roots, since nothing imported metav1
-		return nil
-	}
+	})
+	parser := &Parser{Parser: shared.Parser}
 
 	// TODO: allow selecting a specific object
-	kubeKinds := FindKubeKinds(parser.Parser, metav1Pkg)
+	kubeKinds := shared.KubeKinds
 	if len(kubeKinds) == 0 {
-		// no objects in the roots
+		// no objects in the roots (or nothing imported metav1)
 		return nil
 	}
 
@@ -142,8 +166,18 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 	}
 	headerText = strings.ReplaceAll(headerText, " YEAR", " "+g.Year)
 
+	fileNames, err := crd.NewFileNamer(g.FileNameTemplate)
+	if err != nil {
+		return err
+	}
+
 	for _, groupKind := range kubeKinds {
+		// XRDs are kept by this parser, not the shared one, and their
+		// schemata are copied out of its caches, so it's only locked while
+		// building them.
+		shared.Lock()
 		parser.NeedXRDFor(groupKind, g.MaxDescLen)
+		shared.Unlock()
 		xrdRaw := parser.XRDefinitons[groupKind]
 
 		//addAttribution(&crdRaw)
@@ -154,12 +188,25 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 			removeXRDStatusProps(version.Schema.OpenAPIV3Schema)
 		}
 
-		fileName := fmt.Sprintf("%s_%s.yaml", xrdRaw.Spec.Group, xrdRaw.Spec.Names.Plural)
+		fileName, err := fileNames.FileName(crd.FileNameData{
+			Group:      xrdRaw.Spec.Group,
+			Kind:       xrdRaw.Spec.Names.Kind,
+			Plural:     xrdRaw.Spec.Names.Plural,
+			Singular:   xrdRaw.Spec.Names.Singular,
+			CRDVersion: xrdVersions[0],
+		}, fmt.Sprintf("%s_%s.yaml", xrdRaw.Spec.Group, xrdRaw.Spec.Names.Plural))
+		if err != nil {
+			return err
+		}
 		if err := ctx.WriteYAML(fileName, headerText, []interface{}{xrdRaw}, genall.WithTransform(transformRemoveXRDStatus)); err != nil {
 			return err
 		}
 	}
 
+	if g.Kustomization != nil && *g.Kustomization {
+		return ctx.WriteKustomization(headerText, fileNames.FileNames())
+	}
+
 	return nil
 }
 
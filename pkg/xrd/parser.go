@@ -58,7 +58,7 @@ func (p *Parser) init() {
 
 func (p *Parser) NeedXRDFor(groupKind schema.GroupKind, maxDescLen *int) {
 	p.init()
-	if _, exists := p.CustomResourceDefinitions[groupKind]; exists {
+	if _, exists := p.XRDefinitons[groupKind]; exists {
 		return
 	}
 	var packages []*loader.Package